@@ -0,0 +1,65 @@
+// cmd/geodude/main.go
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+	"github.com/sebastiaanwouters/geodude/internal/server"
+)
+
+func main() {
+	pbfPath := flag.String("pbf", "data/andorra-latest.osm.pbf", "OSM PBF file to index")
+	addr := flag.String("addr", ":8080", "address to listen on")
+	timeZone := flag.String("timezone", "UTC", "time zone of opening hours where neither the POI nor its boundaries tag one")
+	flag.Parse()
+
+	loc, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		log.Fatalf("invalid time zone %q: %v", *timeZone, err)
+	}
+
+	file, err := os.Open(*pbfPath)
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", *pbfPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("failed to stat %s: %v", *pbfPath, err)
+	}
+	nodes, err := osm.NewNodeStore(info.Size(), "")
+	if err != nil {
+		log.Fatalf("failed to create node store: %v", err)
+	}
+
+	start := time.Now()
+	builder := geo.NewGeoBuilderWithNodeStore(nodes)
+	builder.SetTimeZone(loc)
+	// Two passes deliver only the tagged nodes and those that ways and
+	// relations use, and let the builder keep only the ways relations use
+	if err := osm.StreamProcessTwoPass(file, builder, osm.MustParseFilter("n/*")); err != nil {
+		log.Fatalf("failed to process %s: %v", *pbfPath, err)
+	}
+	index := builder.GetIndex()
+	diagnostics := builder.Diagnostics()
+	for _, d := range diagnostics {
+		log.Print(d)
+	}
+	if len(diagnostics) > 0 {
+		log.Printf("found %d issues in areas", len(diagnostics))
+	}
+	if err := builder.ClearNodeCache(); err != nil {
+		log.Printf("failed to release node store: %v", err)
+	}
+	log.Printf("indexed %d addresses in %v", len(index.Addresses), time.Since(start))
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server.New(index)))
+}
@@ -2,7 +2,10 @@ module github.com/sebastiaanwouters/geodude
 
 go 1.23.4
 
-require github.com/paulmach/osm v0.8.0
+require (
+	github.com/paulmach/osm v0.8.0
+	golang.org/x/text v0.21.0
+)
 
 require (
 	github.com/datadog/czlib v0.0.0-20160811164712-4bc9a24e37f2 // indirect
@@ -0,0 +1,115 @@
+// internal/address/housenumber.go
+package address
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HouseNumber is a house number split into parts that can be compared, so
+// that "12 A" equals "12a" and "14" is found in "12-16"
+type HouseNumber struct {
+	Number int    // Base number, such as 12 in "12A"; 0 for S/N
+	Suffix string // Lowercase letter or word after the number, such as "a" or "bis"
+	To     int    // Last number of a range such as "12-14", 0 otherwise
+	Unit   string // Lowercase part after a slash, such as "3" in "12/3"
+	None   bool   // Whether the address has no number, written "S/N"
+}
+
+// houseNumberPattern matches "12", "12a", "12 bis", "12-14", "12a-14",
+// "12/3" and "12/3b"
+var houseNumberPattern = regexp.MustCompile(`^(\d+)\s*([a-z]|bis|ter|quater)?(?:\s*([-/])\s*(\d+)\s*([a-z])?)?$`)
+
+// ParseHouseNumber parses a house number as tagged or typed. Lists such as
+// "12;14" are parsed by their first number. It reports false for text that
+// is not a house number.
+func ParseHouseNumber(s string) (HouseNumber, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if first, _, ok := strings.Cut(s, ";"); ok {
+		s = strings.TrimSpace(first)
+	}
+	if noNumberPattern.MatchString(s) || s == "s/n." {
+		return HouseNumber{None: true}, true
+	}
+	m := houseNumberPattern.FindStringSubmatch(s)
+	if m == nil {
+		return HouseNumber{}, false
+	}
+	number, err := strconv.Atoi(m[1])
+	if err != nil {
+		return HouseNumber{}, false
+	}
+	h := HouseNumber{Number: number, Suffix: m[2]}
+	switch m[3] {
+	case "-":
+		to, err := strconv.Atoi(m[4])
+		if err != nil {
+			return HouseNumber{}, false
+		}
+		if to > number && m[5] == "" {
+			h.To = to
+		} else {
+			// "12-3" numbers a unit of 12 rather than a range
+			h.Unit = m[4] + m[5]
+		}
+	case "/":
+		h.Unit = m[4] + m[5]
+	}
+	return h, true
+}
+
+// String returns the canonical form of the house number, such as "12a",
+// "12 bis", "12-14", "12/3" or "s/n"
+func (h HouseNumber) String() string {
+	if h.None {
+		return "s/n"
+	}
+	s := strconv.Itoa(h.Number)
+	if len(h.Suffix) == 1 {
+		s += h.Suffix
+	} else if h.Suffix != "" {
+		s += " " + h.Suffix
+	}
+	if h.To > 0 {
+		s += "-" + strconv.Itoa(h.To)
+	}
+	if h.Unit != "" {
+		s += "/" + h.Unit
+	}
+	return s
+}
+
+// Covers reports whether n is one of the numbers of the house number. A
+// range covers the numbers on its side of the street: "12-16" covers 14
+// but not 13.
+func (h HouseNumber) Covers(n int) bool {
+	if h.None {
+		return false
+	}
+	if h.To == 0 {
+		return n == h.Number
+	}
+	if n < h.Number || n > h.To {
+		return false
+	}
+	return (h.To-h.Number)%2 != 0 || (n-h.Number)%2 == 0
+}
+
+// Distance returns how many numbers apart two house numbers are, 0 if they
+// share a base number or overlap, such as "12a" and "12b" or "14" and
+// "12-16". It reports false if only one of them has no number.
+func (h HouseNumber) Distance(other HouseNumber) (int, bool) {
+	if h.None || other.None {
+		return 0, h.None && other.None
+	}
+	lo, hi := h.Number, max(h.Number, h.To)
+	otherLo, otherHi := other.Number, max(other.Number, other.To)
+	switch {
+	case hi < otherLo:
+		return otherLo - hi, true
+	case otherHi < lo:
+		return lo - otherHi, true
+	}
+	return 0, true
+}
@@ -0,0 +1,89 @@
+// internal/address/housenumber_test.go
+package address
+
+import "testing"
+
+func TestParseHouseNumber(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      HouseNumber
+		canonical string
+	}{
+		{"12", HouseNumber{Number: 12}, "12"},
+		{"12A", HouseNumber{Number: 12, Suffix: "a"}, "12a"},
+		{"12 a", HouseNumber{Number: 12, Suffix: "a"}, "12a"},
+		{"12 bis", HouseNumber{Number: 12, Suffix: "bis"}, "12 bis"},
+		{"12bis", HouseNumber{Number: 12, Suffix: "bis"}, "12 bis"},
+		{"12-14", HouseNumber{Number: 12, To: 14}, "12-14"},
+		{"12 - 14", HouseNumber{Number: 12, To: 14}, "12-14"},
+		{"12-3", HouseNumber{Number: 12, Unit: "3"}, "12/3"},
+		{"12/3", HouseNumber{Number: 12, Unit: "3"}, "12/3"},
+		{"12/3b", HouseNumber{Number: 12, Unit: "3b"}, "12/3b"},
+		{"12;14", HouseNumber{Number: 12}, "12"},
+		{"S/N", HouseNumber{None: true}, "s/n"},
+		{"sn", HouseNumber{None: true}, "s/n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseHouseNumber(tt.input)
+			if !ok || got != tt.want {
+				t.Fatalf("ParseHouseNumber() = %+v, %v, want %+v", got, ok, tt.want)
+			}
+			if got.String() != tt.canonical {
+				t.Errorf("String() = %q, want %q", got.String(), tt.canonical)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "abc", "a12", "12abc", "-3"} {
+		if got, ok := ParseHouseNumber(input); ok {
+			t.Errorf("ParseHouseNumber(%q) = %+v, want failure", input, got)
+		}
+	}
+}
+
+func TestHouseNumber_Compare(t *testing.T) {
+	parse := func(s string) HouseNumber {
+		h, ok := ParseHouseNumber(s)
+		if !ok {
+			t.Fatalf("Cannot parse %q", s)
+		}
+		return h
+	}
+
+	covers := []struct {
+		number string
+		n      int
+		want   bool
+	}{
+		{"12", 12, true},
+		{"12a", 12, true},
+		{"12-16", 14, true},
+		{"12-16", 13, false},
+		{"12-13", 13, true},
+		{"S/N", 0, false},
+	}
+	for _, tt := range covers {
+		if got := parse(tt.number).Covers(tt.n); got != tt.want {
+			t.Errorf("%s.Covers(%d) = %v, want %v", tt.number, tt.n, got, tt.want)
+		}
+	}
+
+	distances := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"12a", "12b", 0, true},
+		{"14", "12-16", 0, true},
+		{"10", "12-16", 2, true},
+		{"20", "12-16", 4, true},
+		{"S/N", "s/n", 0, true},
+		{"S/N", "12", 0, false},
+	}
+	for _, tt := range distances {
+		if got, ok := parse(tt.a).Distance(parse(tt.b)); got != tt.want || ok != tt.ok {
+			t.Errorf("Distance(%s, %s) = %d, %v, want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// internal/address/parser.go
+package address
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Components are the parts of a free-form address. Empty fields were not found.
+type Components struct {
+	HouseNumber string
+	Street      string
+	Unit        string
+	PostCode    string
+	City        string
+	Country     string // ISO 3166-1 alpha-2 code, set only if the last part names a known country
+}
+
+// Options tune the parser
+type Options struct {
+	// Country is the expected country code, used to pick the postcode format
+	Country string
+	// Locality recognises city names. It returns the canonical name if text
+	// names a known locality and lets the parser find a city that is not
+	// separated from the street by a comma.
+	Locality func(text string) (string, bool)
+}
+
+var (
+	// A house number, range or fraction: "12", "12b", "12-14", "12/3"
+	numberPattern = regexp.MustCompile(`(?i)^\d+[a-z]?([-/]\d+[a-z]?)?$`)
+	// Suffixes written as a separate word: "12 b", "12 bis"
+	suffixPattern = regexp.MustCompile(`(?i)^([a-z]|bis|ter|quater)$`)
+	// Addresses without a number: "s/n" (sin número / sense número)
+	noNumberPattern = regexp.MustCompile(`(?i)^s/?n$`)
+	// Markers written before the number: "nº 12", "no. 12", "num 12"
+	numberMarkerPattern = regexp.MustCompile(`(?i)^(n[º°o]?\.?|num\.?|número|numero|#)$`)
+	// Units: apartments, floors and doors
+	unitPattern = regexp.MustCompile(`(?i)(^|\s)((apt|apartment|unit|suite|ste|flat|room|piso|pis|porta|puerta|planta|bloc|bloque|escala|escalera)\.?\s*#?\s*[\w-]+|#\s*\w+)`)
+)
+
+// Parse splits a free-form address into its components
+func Parse(s string) Components {
+	return ParseWithOptions(s, Options{})
+}
+
+// ParseWithOptions splits a free-form address into its components. It
+// handles both the number-before-street convention ("221B Baker Street") and
+// the street-before-number convention ("Carrer Major 12"), comma separated
+// or not.
+func ParseWithOptions(s string, opts Options) Components {
+	var c Components
+
+	var parts []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return c
+	}
+
+	// Country is only recognised as its own trailing part
+	if len(parts) > 1 {
+		last := parts[len(parts)-1]
+		if code, ok := CountryCode(last); ok {
+			c.Country = code
+			parts = parts[:len(parts)-1]
+		}
+	}
+	country := opts.Country
+	if c.Country != "" {
+		country = c.Country
+	}
+
+	// Units may appear in any part
+	for i, p := range parts {
+		if m := unitPattern.FindStringSubmatchIndex(p); m != nil && c.Unit == "" {
+			c.Unit = strings.TrimSpace(p[m[4]:m[5]])
+			parts[i] = strings.TrimSpace(p[:m[4]] + " " + p[m[5]:])
+		}
+	}
+
+	tokens := make([][]string, len(parts))
+	for i, p := range parts {
+		tokens[i] = strings.Fields(p)
+	}
+
+	c.PostCode, tokens = extractPostcode(tokens, country)
+
+	// Parts after an unnumbered street that only hold a house number
+	// ("Carrer Major, 12")
+	if _, _, ok := extractNumber(tokens[0]); !ok {
+		for i := 1; i < len(tokens) && c.HouseNumber == ""; i++ {
+			if number, rest, ok := trailingNumber(tokens[i]); ok && len(rest) == 0 {
+				c.HouseNumber = number
+				tokens[i] = nil
+			}
+		}
+	}
+
+	street := tokens[0]
+	var others []string
+	for _, t := range tokens[1:] {
+		if len(t) > 0 {
+			others = append(others, strings.Join(t, " "))
+		}
+	}
+
+	// The first part after the street is the city; later ones (states,
+	// provinces) are ignored
+	if len(others) > 0 {
+		c.City = others[0]
+		if opts.Locality != nil {
+			if name, ok := opts.Locality(c.City); ok {
+				c.City = name
+			}
+		}
+	} else if opts.Locality != nil {
+		street, c.City = splitTrailingLocality(street, opts.Locality)
+	}
+
+	if number, rest, ok := extractNumber(street); ok && c.HouseNumber == "" {
+		c.HouseNumber, street = number, rest
+	}
+	c.Street = strings.Trim(strings.Join(street, " "), " .;")
+	return c
+}
+
+// extractPostcode finds a postcode, preferring later parts, and returns it
+// with the remaining tokens. Tokens following a postcode inside the street
+// part are moved into a part of their own since they usually name the city.
+func extractPostcode(tokens [][]string, country string) (string, [][]string) {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		part := tokens[i]
+		for j := len(part) - 1; j >= 0; j-- {
+			// Two-token formats such as "SW1A 1AA" and "1012 AB" first
+			for n := 2; n >= 1; n-- {
+				if j+n > len(part) {
+					continue
+				}
+				candidate := strings.Join(part[j:j+n], " ")
+				if !IsPostcode(candidate, country) {
+					continue
+				}
+				// In the street part a bare number at either end is a house number
+				if i == 0 && isNumeric(candidate) && (j == 0 || j+n == len(part)) {
+					continue
+				}
+
+				rest := append(append([]string(nil), part[:j]...), part[j+n:]...)
+				result := append([][]string(nil), tokens...)
+				result[i] = rest
+				if i == 0 && j+n < len(part) {
+					result[0] = part[:j]
+					result = append([][]string{result[0], part[j+n:]}, result[1:]...)
+				}
+				return candidate, result
+			}
+		}
+	}
+	return "", tokens
+}
+
+// splitTrailingLocality looks for a known locality in the last words of the
+// street part, as in "Carrer Major 12 Andorra la Vella"
+func splitTrailingLocality(street []string, locality func(string) (string, bool)) ([]string, string) {
+	// A part that is entirely a locality name has no street
+	if name, ok := locality(strings.Join(street, " ")); ok {
+		if _, _, numbered := trailingNumber(street); !numbered {
+			return nil, name
+		}
+	}
+	for n := min(4, len(street)-1); n > 0; n-- {
+		tail := street[len(street)-n:]
+		if isNumeric(tail[0]) || numberPattern.MatchString(tail[0]) {
+			continue
+		}
+		if name, ok := locality(strings.Join(tail, " ")); ok {
+			return street[:len(street)-n], name
+		}
+	}
+	return street, ""
+}
+
+// extractNumber removes the house number from the end or start of the
+// street tokens
+func extractNumber(street []string) (string, []string, bool) {
+	if number, rest, ok := trailingNumber(street); ok {
+		return number, rest, true
+	}
+	return leadingNumber(street)
+}
+
+// leadingNumber matches number-before-street: "12 Main Street", "12b Main
+// Street", "12-14 Main Street"
+func leadingNumber(tokens []string) (string, []string, bool) {
+	if len(tokens) < 2 {
+		return "", tokens, false
+	}
+	if noNumberPattern.MatchString(tokens[0]) {
+		return "S/N", tokens[1:], true
+	}
+	if !numberPattern.MatchString(tokens[0]) {
+		return "", tokens, false
+	}
+	number, rest := tokens[0], tokens[1:]
+	// "12 - 14 Main Street"
+	if len(rest) > 2 && rest[0] == "-" && numberPattern.MatchString(rest[1]) {
+		return number + "-" + rest[1], rest[2:], true
+	}
+	// "12 bis Rue de Rivoli", but not "12 B Street"-like single letters
+	// followed by a single word
+	if len(rest) > 2 && suffixPattern.MatchString(rest[0]) {
+		return joinSuffix(number, rest[0]), rest[1:], true
+	}
+	return number, rest, true
+}
+
+// trailingNumber matches street-before-number: "Carrer Major 12", "Carrer
+// Major 12 b", "Carrer Major nº 12", "Carrer Major s/n"
+func trailingNumber(tokens []string) (string, []string, bool) {
+	n := len(tokens)
+	if n == 0 {
+		return "", tokens, false
+	}
+
+	var number string
+	rest := tokens
+	switch {
+	case noNumberPattern.MatchString(tokens[n-1]):
+		number, rest = "S/N", tokens[:n-1]
+	case numberPattern.MatchString(tokens[n-1]):
+		number, rest = tokens[n-1], tokens[:n-1]
+		// "12 - 14"
+		if len(rest) >= 2 && rest[len(rest)-1] == "-" && numberPattern.MatchString(rest[len(rest)-2]) {
+			number, rest = rest[len(rest)-2]+"-"+number, rest[:len(rest)-2]
+		}
+	case n >= 2 && suffixPattern.MatchString(tokens[n-1]) && numberPattern.MatchString(tokens[n-2]):
+		number, rest = joinSuffix(tokens[n-2], tokens[n-1]), tokens[:n-2]
+	default:
+		return "", tokens, false
+	}
+
+	if len(rest) > 0 && numberMarkerPattern.MatchString(rest[len(rest)-1]) {
+		rest = rest[:len(rest)-1]
+	}
+	return number, rest, true
+}
+
+// joinSuffix attaches a letter suffix directly ("12b") and a word suffix with
+// a space ("12 bis")
+func joinSuffix(number, suffix string) string {
+	if len(suffix) == 1 {
+		return number + suffix
+	}
+	return number + " " + suffix
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
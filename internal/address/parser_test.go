@@ -0,0 +1,90 @@
+// internal/address/parser_test.go
+package address
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Components
+	}{
+		{"221B Baker Street, London, NW1 6XE, United Kingdom", Components{HouseNumber: "221B", Street: "Baker Street", City: "London", PostCode: "NW1 6XE", Country: "GB"}},
+		{"1600 Pennsylvania Ave NW, Washington, DC 20500, USA", Components{HouseNumber: "1600", Street: "Pennsylvania Ave NW", City: "Washington", PostCode: "20500", Country: "US"}},
+		{"Carrer Major 12, AD500 Andorra la Vella", Components{HouseNumber: "12", Street: "Carrer Major", City: "Andorra la Vella", PostCode: "AD500"}},
+		{"Hauptstraße 12-14, 10115 Berlin", Components{HouseNumber: "12-14", Street: "Hauptstraße", City: "Berlin", PostCode: "10115"}},
+		{"Calle Mayor 12 b, 28013 Madrid, España", Components{HouseNumber: "12b", Street: "Calle Mayor", City: "Madrid", PostCode: "28013", Country: "ES"}},
+		{"12 bis Rue de Rivoli, 75001 Paris", Components{HouseNumber: "12 bis", Street: "Rue de Rivoli", City: "Paris", PostCode: "75001"}},
+		{"Carrer de la Unió, nº 7, Ordino", Components{HouseNumber: "7", Street: "Carrer de la Unió", City: "Ordino"}},
+		{"Avinguda Meritxell s/n, Andorra la Vella", Components{HouseNumber: "S/N", Street: "Avinguda Meritxell", City: "Andorra la Vella"}},
+		{"Damrak 1 Apt 4, 1012 LG Amsterdam", Components{HouseNumber: "1", Street: "Damrak", Unit: "Apt 4", City: "Amsterdam", PostCode: "1012 LG"}},
+		{"350 5th Ave #3201, New York", Components{HouseNumber: "350", Street: "5th Ave", Unit: "#3201", City: "New York"}},
+		{"Rua Augusta 24, 1100-053 Lisboa", Components{HouseNumber: "24", Street: "Rua Augusta", City: "Lisboa", PostCode: "1100-053"}},
+		{"Carrer Major 12 08001 Barcelona", Components{HouseNumber: "12", Street: "Carrer Major", City: "Barcelona", PostCode: "08001"}},
+		{"Baker Street", Components{Street: "Baker Street"}},
+		{"", Components{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Parse(tt.input); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWithOptions(t *testing.T) {
+	localities := map[string]string{
+		"andorra la vella": "Andorra la Vella",
+		"ordino":           "Ordino",
+	}
+	opts := Options{
+		Country: "AD",
+		Locality: func(text string) (string, bool) {
+			name, ok := localities[strings.ToLower(text)]
+			return name, ok
+		},
+	}
+
+	tests := []struct {
+		input string
+		want  Components
+	}{
+		{"Carrer Major 12 Andorra la Vella", Components{HouseNumber: "12", Street: "Carrer Major", City: "Andorra la Vella"}},
+		{"12 Carrer Major ordino", Components{HouseNumber: "12", Street: "Carrer Major", City: "Ordino"}},
+		{"Carrer Major 3, AD300", Components{HouseNumber: "3", Street: "Carrer Major", PostCode: "AD300"}},
+		{"Ordino", Components{City: "Ordino"}},
+		{"Carrer Major, 3, Ordino", Components{HouseNumber: "3", Street: "Carrer Major", City: "Ordino"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseWithOptions(tt.input, opts); got != tt.want {
+				t.Errorf("ParseWithOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPostcode(t *testing.T) {
+	tests := []struct {
+		s       string
+		country string
+		want    bool
+	}{
+		{"AD500", "AD", true},
+		{"AD-500", "", true},
+		{"SW1A 1AA", "GB", true},
+		{"1012 AB", "", true},
+		{"28013", "ES", true},
+		{"28013", "AD", false},
+		{"12", "", false},
+		{"12b", "", false},
+	}
+	for _, tt := range tests {
+		if got := IsPostcode(tt.s, tt.country); got != tt.want {
+			t.Errorf("IsPostcode(%q, %q) = %v, want %v", tt.s, tt.country, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// internal/address/postcode.go
+package address
+
+import (
+	"regexp"
+	"strings"
+)
+
+// postcodePatterns are the postcode formats of supported countries, keyed by
+// ISO 3166-1 alpha-2 code
+var postcodePatterns = map[string]*regexp.Regexp{
+	"AD": regexp.MustCompile(`(?i)^AD-?\d{3}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`(?i)^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`(?i)^\d{4} ?[A-Z]{2}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// postcodeOrder is the order in which patterns are tried when the country is
+// unknown; distinctive formats first so they are not mistaken for others
+var postcodeOrder = []string{"GB", "NL", "PT", "AD", "US", "ES"}
+
+// countryNames maps lowercase country names and codes to ISO 3166-1 alpha-2
+var countryNames = map[string]string{
+	"ad": "AD", "andorra": "AD",
+	"de": "DE", "germany": "DE", "deutschland": "DE", "alemanya": "DE",
+	"es": "ES", "spain": "ES", "españa": "ES", "espanya": "ES", "espagne": "ES",
+	"fr": "FR", "france": "FR", "frança": "FR", "francia": "FR",
+	"gb": "GB", "uk": "GB", "united kingdom": "GB", "great britain": "GB", "england": "GB",
+	"it": "IT", "italy": "IT", "italia": "IT",
+	"nl": "NL", "netherlands": "NL", "the netherlands": "NL", "nederland": "NL",
+	"pt": "PT", "portugal": "PT",
+	"us": "US", "usa": "US", "united states": "US", "united states of america": "US",
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code for a country name or code
+func CountryCode(name string) (string, bool) {
+	code, ok := countryNames[strings.ToLower(strings.TrimSpace(strings.Trim(name, ".")))]
+	return code, ok
+}
+
+// IsPostcode reports whether s is a valid postcode for the country, or for
+// any supported country if country is empty
+func IsPostcode(s, country string) bool {
+	if country != "" {
+		if pattern, ok := postcodePatterns[strings.ToUpper(country)]; ok {
+			return pattern.MatchString(s)
+		}
+	}
+	for _, c := range postcodeOrder {
+		if postcodePatterns[c].MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
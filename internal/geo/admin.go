@@ -0,0 +1,163 @@
+// internal/geo/admin.go
+package geo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// AdminArea is an administrative boundary such as a country (admin_level 2),
+// region (4), municipality (8) or neighbourhood (10)
+type AdminArea struct {
+	ID          osm.ID
+	Name        string
+	Names       Names
+	Level       int
+	CountryCode string // ISO 3166-1 alpha-2, only set on country boundaries
+	TimeZone    string // Value of the timezone tag, such as "Europe/Andorra"
+	Geometry    MultiPolygon
+	Wikidata    string // Value of the wikidata tag, such as "Q1863"
+	Wikipedia   string // Value of the wikipedia tag, such as "ca:Andorra la Vella"
+	Place       *Place // Place node or area describing the same locality, if any
+}
+
+// Importance ranks areas between 0 and 1: countries above regions, regions
+// above towns, and notable areas above others
+func (a *AdminArea) Importance() float64 {
+	importance := 1 - float64(a.Level)/12
+	if a.Wikidata != "" || a.Wikipedia != "" {
+		importance += wikiImportance
+	}
+	return math.Max(0, math.Min(1, importance))
+}
+
+// adminAreaResult describes an area as a geocoding result located at a point
+// inside it
+func (idx *GeoIndex) adminAreaResult(area *AdminArea) GeocodeResult {
+	center := area.Geometry.PointOnSurface()
+	extent := area.Geometry.Bounds()
+	result := GeocodeResult{
+		Address:    Address{Lat: center.Lat, Lon: center.Lon},
+		Name:       area.Name,
+		Names:      area.Names,
+		Layer:      LayerAdmin,
+		Importance: area.Importance(),
+		Extent:     &extent,
+	}
+	if area.Level >= localityMinLevel {
+		result.Layer = LayerLocality
+	}
+	idx.fillFromAdminAreas(&result.Address)
+	return result
+}
+
+// cityAdminLevels are the admin levels, in order of preference, whose name is
+// used as the city of an address without addr:city. Municipalities are level
+// 8 in most countries; Andorra's parishes are level 7.
+var cityAdminLevels = []int{8, 7}
+
+// AdminAreasAt returns the administrative areas containing the coordinate,
+// ordered from the broadest (lowest admin_level) to the most specific
+func (idx *GeoIndex) AdminAreasAt(lat, lon float64) []*AdminArea {
+	if idx.AdminAreas == nil {
+		return nil
+	}
+
+	var areas []*AdminArea
+	for _, e := range idx.AdminAreas.Containing(Coord{Lat: lat, Lon: lon}) {
+		areas = append(areas, e.Data.(*AdminArea))
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		if areas[i].Level != areas[j].Level {
+			return areas[i].Level < areas[j].Level
+		}
+		return areas[i].ID < areas[j].ID
+	})
+	return areas
+}
+
+// fillFromAdminAreas sets the city and country of an address from the
+// boundaries containing it, leaving tagged values untouched. Where no
+// boundary names the city, the settlement the address belongs to does.
+func (idx *GeoIndex) fillFromAdminAreas(addr *Address) {
+	if addr.City != "" && addr.Country != "" {
+		return
+	}
+	areas := idx.AdminAreasAt(addr.Lat, addr.Lon)
+	if addr.Country == "" {
+		for _, area := range areas {
+			if area.Level == 2 {
+				addr.Country = area.CountryCode
+				if addr.Country == "" {
+					addr.Country = area.Name
+				}
+				break
+			}
+		}
+	}
+	if addr.City == "" {
+		addr.City = idx.cityAt(Coord{Lat: addr.Lat, Lon: addr.Lon}, areas)
+	}
+}
+
+// cityAt returns the city of a coordinate given the areas containing it: the
+// municipality if there is a boundary for it, otherwise the settlement
+func (idx *GeoIndex) cityAt(c Coord, areas []*AdminArea) string {
+	if city := cityFromAreas(areas); city != "" {
+		return city
+	}
+	if p := idx.settlementAt(c); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+func cityFromAreas(areas []*AdminArea) string {
+	for _, level := range cityAdminLevels {
+		for _, area := range areas {
+			if area.Level == level {
+				return area.Name
+			}
+		}
+	}
+	return ""
+}
+
+// isAdminBoundary reports whether a relation describes an administrative area
+func isAdminBoundary(tags osm.Tags) bool {
+	return tags.Get("boundary") == "administrative" &&
+		(tags.Get("type") == "boundary" || tags.Get("type") == "multipolygon")
+}
+
+// buildAdminArea assembles the boundary polygons of an administrative
+// relation from its member ways, returning the issues repaired on the way
+func (b *GeoBuilder) buildAdminArea(relation *osm.Relation) (*AdminArea, []AssemblyIssue, error) {
+	level, err := strconv.Atoi(relation.Tags.Get("admin_level"))
+	if err != nil {
+		return nil, nil, &AssemblyError{ObjectType: "relation", ID: relation.ID, Issues: []AssemblyIssue{{
+			Kind:    IssueInvalidTag,
+			Message: fmt.Sprintf("invalid admin_level %q", relation.Tags.Get("admin_level")),
+		}}}
+	}
+
+	geometry, issues, err := b.assembler().AssembleRelation(relation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &AdminArea{
+		ID:          relation.ID,
+		Name:        relation.Tags.Get("name"),
+		Names:       namesFromTags(relation.Tags, "name"),
+		Level:       level,
+		CountryCode: relation.Tags.Get("ISO3166-1:alpha2"),
+		TimeZone:    relation.Tags.Get("timezone"),
+		Geometry:    geometry,
+		Wikidata:    relation.Tags.Get("wikidata"),
+		Wikipedia:   relation.Tags.Get("wikipedia"),
+	}, issues, nil
+}
@@ -0,0 +1,169 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// addSquareNodes adds the four corner nodes of a square starting at id
+func addSquareNodes(t *testing.T, b *GeoBuilder, id osm.ID, minLat, minLon, size float64) {
+	t.Helper()
+	corners := []osm.Node{
+		{ID: id, Lat: minLat, Lon: minLon},
+		{ID: id + 1, Lat: minLat, Lon: minLon + size},
+		{ID: id + 2, Lat: minLat + size, Lon: minLon + size},
+		{ID: id + 3, Lat: minLat + size, Lon: minLon},
+	}
+	for i := range corners {
+		if err := b.ProcessNode(&corners[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAdminAreasAt(t *testing.T) {
+	b := NewGeoBuilder()
+	addSquareNodes(t, b, 100, 42, 1, 1)
+	addSquareNodes(t, b, 200, 42.2, 1.2, 0.2)
+
+	address := &osm.Node{ID: 1, Lat: 42.3, Lon: 1.3, Tags: osm.Tags{
+		{Key: "addr:housenumber", Value: "3"},
+		{Key: "addr:street", Value: "Carrer Major"},
+	}}
+	if err := b.ProcessNode(address); err != nil {
+		t.Fatal(err)
+	}
+
+	// The country boundary is split over two ways given in opposite directions
+	ways := []*osm.Way{
+		{ID: 10, Nodes: []osm.ID{100, 101, 102}},
+		{ID: 11, Nodes: []osm.ID{100, 103, 102}},
+		{ID: 20, Nodes: []osm.ID{200, 201, 202, 203, 200}},
+	}
+	for _, w := range ways {
+		if err := b.ProcessWay(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	relations := []*osm.Relation{
+		{
+			ID: 1,
+			Tags: osm.Tags{
+				{Key: "type", Value: "boundary"},
+				{Key: "boundary", Value: "administrative"},
+				{Key: "admin_level", Value: "2"},
+				{Key: "name", Value: "Andorra"},
+				{Key: "ISO3166-1:alpha2", Value: "AD"},
+			},
+			Members: []osm.Member{
+				{Type: "way", Ref: 10, Role: "outer"},
+				{Type: "way", Ref: 11, Role: "outer"},
+			},
+		},
+		{
+			ID: 2,
+			Tags: osm.Tags{
+				{Key: "type", Value: "boundary"},
+				{Key: "boundary", Value: "administrative"},
+				{Key: "admin_level", Value: "7"},
+				{Key: "name", Value: "Andorra la Vella"},
+			},
+			Members: []osm.Member{{Type: "way", Ref: 20, Role: "outer"}},
+		},
+		{
+			// References a way outside the extract and must be skipped
+			ID: 3,
+			Tags: osm.Tags{
+				{Key: "type", Value: "boundary"},
+				{Key: "boundary", Value: "administrative"},
+				{Key: "admin_level", Value: "4"},
+			},
+			Members: []osm.Member{{Type: "way", Ref: 99, Role: "outer"}},
+		},
+	}
+	for _, r := range relations {
+		if err := b.ProcessRelation(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := b.GetIndex()
+	areas := idx.AdminAreasAt(42.3, 1.3)
+	if len(areas) != 2 {
+		t.Fatalf("Expected 2 areas, got %d", len(areas))
+	}
+	if areas[0].Name != "Andorra" || areas[1].Name != "Andorra la Vella" {
+		t.Errorf("Unexpected hierarchy %s > %s", areas[0].Name, areas[1].Name)
+	}
+
+	if areas := idx.AdminAreasAt(42.9, 1.1); len(areas) != 1 {
+		t.Errorf("Expected only the country, got %d areas", len(areas))
+	}
+
+	addr := idx.Addresses[makeAddressKey("Carrer Major", "3", "")]
+	if addr.City != "Andorra la Vella" || addr.Country != "AD" {
+		t.Errorf("Expected city and country from boundaries, got %q, %q", addr.City, addr.Country)
+	}
+}
+
+func TestGeoBuilder_Diagnostics(t *testing.T) {
+	b := NewGeoBuilder()
+	addSquareNodes(t, b, 100, 42, 1, 1)
+	// Only three sides of the boundary are in the data
+	if err := b.ProcessWay(&osm.Way{ID: 10, Nodes: []osm.ID{100, 101, 102, 103}}); err != nil {
+		t.Fatal(err)
+	}
+	boundary := func(id osm.ID, level string) *osm.Relation {
+		return &osm.Relation{
+			ID: id,
+			Tags: osm.Tags{
+				{Key: "type", Value: "boundary"},
+				{Key: "boundary", Value: "administrative"},
+				{Key: "admin_level", Value: level},
+				{Key: "name", Value: "Andorra"},
+			},
+			Members: []osm.Member{{Type: "way", Ref: 10, Role: "outer"}},
+		}
+	}
+	for _, r := range []*osm.Relation{boundary(1, "2"), boundary(2, "country")} {
+		if err := b.ProcessRelation(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diagnostics := b.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.ObjectType != "relation" || d.ID != 1 || d.Issue.Kind != IssueUnclosedRing || !d.Dropped {
+		t.Errorf("Expected the unclosed boundary to be dropped, got %v", d)
+	}
+	if d := diagnostics[1]; d.ID != 2 || d.Issue.Kind != IssueInvalidTag || !d.Dropped {
+		t.Errorf("Expected the invalid admin_level to be reported, got %v", d)
+	}
+	if areas := b.GetIndex().AdminAreasAt(42.5, 1.5); len(areas) != 0 {
+		t.Errorf("Expected no boundaries, got %v", areas)
+	}
+}
+
+func TestAdminAreasAt_Andorra(t *testing.T) {
+	b := NewGeoBuilder()
+	if err := osm.ParsePBF("../../data/andorra-latest.osm.pbf", nil, b); err != nil {
+		t.Fatal(err)
+	}
+	idx := b.GetIndex()
+
+	// Plaça del Poble in Andorra la Vella
+	areas := idx.AdminAreasAt(42.5063, 1.5218)
+	if len(areas) < 2 {
+		t.Fatalf("Expected at least country and parish, got %d areas", len(areas))
+	}
+	if areas[0].Level != 2 || areas[0].CountryCode != "AD" {
+		t.Errorf("Expected Andorra as the broadest area, got %+v", areas[0].Name)
+	}
+	if city := cityFromAreas(areas); city != "Andorra la Vella" {
+		t.Errorf("Expected city Andorra la Vella, got %q", city)
+	}
+}
@@ -0,0 +1,311 @@
+// internal/geo/autocomplete.go
+package geo
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Prefixes up to this many characters are answered from precomputed
+	// lists, since they match too many names to rank on every keystroke
+	shortPrefixLength = 3
+	shortPrefixSize   = 64
+
+	// maxPrefixScan bounds the number of keys ranked for longer prefixes
+	maxPrefixScan = 5000
+
+	// With a focus, short prefixes also suggest up to this many features
+	// within this distance of it, which the precomputed lists may not hold
+	nearbyPrefixSize     = 64
+	nearbyPrefixDistance = 5 * focusScale
+)
+
+// AutocompleteOptions tune an autocomplete query
+type AutocompleteOptions struct {
+	Limit    int     // Maximum number of suggestions, 10 if zero
+	Focus    *Coord  // Suggestions near this point rank higher
+	Viewport *Bounds // Suggestions inside this area rank higher
+	Language string  // Language to return names in where known, such as "es"
+}
+
+// prefixKey is a searchable form of a completion's name, starting at one of
+// its words so that "major" completes "Carrer Major"
+type prefixKey struct {
+	key   string
+	entry int32
+	start bool // Whether the key starts at the first word of the name
+}
+
+// prefixIndex answers prefix queries by binary search over sorted keys
+type prefixIndex struct {
+	entries []GeocodeResult // Features that can be suggested
+	keys    []prefixKey
+	short   map[string][]int32 // Short prefix to key positions, best first
+	version uint64             // Index version when built, to detect changes
+
+	entryKeys [][]int32 // Key positions of each entry
+	locations *RTree    // Entries by location
+}
+
+// Autocomplete suggests addresses, streets, POIs, places and areas whose
+// names start with the typed prefix, or contain a word starting with it.
+// Suggestions are ranked by how much of the name the prefix covers, the
+// importance of the feature and, if a focus point or viewport is given, its
+// distance to them.
+func (idx *GeoIndex) Autocomplete(prefix string, opts AutocompleteOptions) []GeocodeResult {
+	queries := prefixVariants(prefix)
+	if len(queries) == 0 {
+		return nil
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	p := idx.prefixIndex()
+
+	// Short prefixes are answered from lists ranked without a focus, so add
+	// the features matching near it
+	var near *Coord
+	switch {
+	case opts.Focus != nil:
+		near = opts.Focus
+	case opts.Viewport != nil:
+		lat, lon := opts.Viewport.Center()
+		near = &Coord{Lat: lat, Lon: lon}
+	}
+
+	// Score each entry by its best matching key
+	best := make(map[int32]float64)
+	for _, query := range queries {
+		positions := p.lookup(query)
+		if near != nil && utf8.RuneCountInString(query) <= shortPrefixLength {
+			positions = append(positions, p.lookupNear(query, *near)...)
+		}
+		for _, pos := range positions {
+			k := p.keys[pos]
+			if match := p.match(query, k); match > best[k.entry] {
+				best[k.entry] = match
+			}
+		}
+	}
+
+	results := make([]GeocodeResult, 0, len(best))
+	for entry, match := range best {
+		result := p.entries[entry]
+		result.Score = match
+		results = append(results, result)
+	}
+	results = rank(results, Bias{Focus: opts.Focus, Viewport: opts.Viewport}, limit)
+	for i := range results {
+		results[i] = idx.Localize(results[i], opts.Language)
+	}
+	return results
+}
+
+// lookup returns the positions of the keys starting with query
+func (p *prefixIndex) lookup(query string) []int32 {
+	if utf8.RuneCountInString(query) <= shortPrefixLength {
+		return p.short[query]
+	}
+	var positions []int32
+	lo := sort.Search(len(p.keys), func(i int) bool { return p.keys[i].key >= query })
+	for i := lo; i < len(p.keys) && i-lo < maxPrefixScan && strings.HasPrefix(p.keys[i].key, query); i++ {
+		positions = append(positions, int32(i))
+	}
+	return positions
+}
+
+// lookupNear returns the positions of the keys starting with query of the
+// features nearest to c
+func (p *prefixIndex) lookupNear(query string, c Coord) []int32 {
+	matching := func(entry int32) []int32 {
+		var positions []int32
+		for _, pos := range p.entryKeys[entry] {
+			if strings.HasPrefix(p.keys[pos].key, query) {
+				positions = append(positions, pos)
+			}
+		}
+		return positions
+	}
+	var positions []int32
+	nearest := p.locations.Nearest(c, nearbyPrefixSize, nearbyPrefixDistance, func(e RTreeEntry) bool {
+		return len(matching(e.Data.(int32))) > 0
+	})
+	for _, n := range nearest {
+		positions = append(positions, matching(n.Data.(int32))...)
+	}
+	return positions
+}
+
+// prefixVariants returns the folded prefix followed by its forms with
+// abbreviations expanded in each language, since names are indexed as
+// written. The last word is only expanded once followed by a separator, as it
+// may still be being typed.
+func prefixVariants(prefix string) []string {
+	words := foldWords(prefix)
+	if len(words) == 0 {
+		return nil
+	}
+	complete := len(words) - 1
+	if last, _ := utf8.DecodeLastRuneInString(prefix); !unicode.IsLetter(last) && !unicode.IsNumber(last) {
+		complete = len(words)
+	}
+
+	variants := []string{strings.Join(words, " ")}
+	seen := map[string]bool{variants[0]: true}
+	for _, lang := range languages {
+		expanded := append([]string(nil), words...)
+		for i := 0; i < complete; i++ {
+			// The name continues after the prefix, so no word is its last
+			if full, ok := expandWord(words[i], i, len(words)+1, lang); ok {
+				expanded[i] = full
+			}
+		}
+		if v := strings.Join(expanded, " "); !seen[v] {
+			seen[v] = true
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// match scores how much of a key the query covers between 0 and 1, keys
+// starting within the name counting less
+func (p *prefixIndex) match(query string, k prefixKey) float64 {
+	match := float64(len(query)) / float64(len(k.key))
+	if !k.start {
+		match *= 0.8
+	}
+	return match
+}
+
+// prefixIndex returns the autocomplete index, building it on first use or
+// when the features changed since it was built
+func (idx *GeoIndex) prefixIndex() *prefixIndex {
+	idx.prefixMu.Lock()
+	defer idx.prefixMu.Unlock()
+
+	if idx.prefixes == nil || idx.prefixes.version != idx.version {
+		idx.prefixes = idx.buildPrefixIndex()
+		idx.prefixes.version = idx.version
+	}
+	return idx.prefixes
+}
+
+func (idx *GeoIndex) buildPrefixIndex() *prefixIndex {
+	p := &prefixIndex{short: make(map[string][]int32)}
+	// Every variant of a name completes to the same entry
+	add := func(names []string, result GeocodeResult) {
+		entry := int32(len(p.entries))
+		p.entries = append(p.entries, result)
+		seen := make(map[string]bool)
+		for _, name := range names {
+			words := foldWords(name)
+			for i := range words {
+				key := strings.Join(words[i:], " ")
+				if !seen[key] {
+					seen[key] = true
+					p.keys = append(p.keys, prefixKey{key: key, entry: entry, start: i == 0})
+				}
+			}
+		}
+	}
+
+	for _, addr := range idx.Addresses {
+		if addr.Street == "" {
+			continue
+		}
+		var names []string
+		for _, street := range withVariants(addr.Street, addr.StreetNames) {
+			names = append(names, street+" "+addr.HouseNumber)
+		}
+		add(names, addressResult(addr))
+	}
+	for _, streets := range idx.Streets {
+		for _, s := range streets {
+			add(withVariants(s.Name, s.Names), s.result())
+		}
+	}
+	if idx.POIs != nil {
+		for _, e := range idx.POIs.All() {
+			if poi := e.Data.(*POI); poi.Name != "" {
+				add(withVariants(poi.Name, poi.Names), poi.result())
+			}
+		}
+	}
+	if idx.Places != nil {
+		for _, e := range idx.Places.All() {
+			p := e.Data.(*Place)
+			add(withVariants(p.Name, p.Names), idx.placeResult(p))
+		}
+	}
+	if idx.AdminAreas != nil {
+		for _, e := range idx.AdminAreas.All() {
+			area := e.Data.(*AdminArea)
+			if area.Place != nil {
+				continue
+			}
+			add(withVariants(area.Name, area.Names), idx.adminAreaResult(area))
+		}
+	}
+
+	sort.Slice(p.keys, func(i, j int) bool { return p.keys[i].key < p.keys[j].key })
+	p.buildShortPrefixes()
+
+	p.entryKeys = make([][]int32, len(p.entries))
+	for pos, k := range p.keys {
+		p.entryKeys[k.entry] = append(p.entryKeys[k.entry], int32(pos))
+	}
+	locations := make([]RTreeEntry, len(p.entries))
+	for i := range p.entries {
+		locations[i] = RTreeEntry{Geometry: p.entries[i].Location(), Data: int32(i)}
+	}
+	p.locations = BuildRTree(locations, 16)
+	return p
+}
+
+// buildShortPrefixes keeps, for every prefix of up to shortPrefixLength
+// characters, the keys that rank best without a focus point
+func (p *prefixIndex) buildShortPrefixes() {
+	rank := func(pos int32) float64 {
+		k := p.keys[pos]
+		// Shorter names are covered better by a short prefix
+		match := 1 / math.Sqrt(float64(len(k.key)))
+		if !k.start {
+			match *= 0.8
+		}
+		return matchWeight*match + importanceWeight*p.entries[k.entry].Importance
+	}
+	trim := func(prefix string) {
+		list := p.short[prefix]
+		sort.SliceStable(list, func(i, j int) bool { return rank(list[i]) > rank(list[j]) })
+		if len(list) > shortPrefixSize {
+			p.short[prefix] = list[:shortPrefixSize]
+		}
+	}
+
+	for i, k := range p.keys {
+		prefix := ""
+		n := 0
+		for _, r := range k.key {
+			if n == shortPrefixLength {
+				break
+			}
+			prefix += string(r)
+			n++
+			p.short[prefix] = append(p.short[prefix], int32(i))
+			// Trim as lists grow to bound memory on large extracts
+			if len(p.short[prefix]) >= 4*shortPrefixSize {
+				trim(prefix)
+			}
+		}
+	}
+	for prefix := range p.short {
+		trim(prefix)
+	}
+}
@@ -0,0 +1,131 @@
+// internal/geo/autocomplete_test.go
+package geo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestAutocomplete(t *testing.T) {
+	idx := newSearchTestIndex(t)
+
+	t.Run("Street prefix", func(t *testing.T) {
+		results := idx.Autocomplete("Carrer Ma", AutocompleteOptions{})
+		if len(results) != 5 {
+			t.Fatalf("Expected 2 streets and 3 addresses, got %d", len(results))
+		}
+		if results[0].Layer != LayerStreet || results[1].Layer != LayerStreet {
+			t.Errorf("Expected the streets to rank above their addresses, got %s and %s", results[0].Layer, results[1].Layer)
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].Score > results[i-1].Score {
+				t.Errorf("Results not sorted by score at %d", i)
+			}
+		}
+	})
+
+	t.Run("Address prefix", func(t *testing.T) {
+		results := idx.Autocomplete("carrer major 1", AutocompleteOptions{})
+		if len(results) != 2 {
+			t.Fatalf("Expected both number 12 addresses, got %+v", results)
+		}
+		for _, r := range results {
+			if r.Layer != LayerAddress || r.HouseNumber != "12" {
+				t.Errorf("Unexpected result %+v", r)
+			}
+		}
+	})
+
+	t.Run("Focus point", func(t *testing.T) {
+		results := idx.Autocomplete("carrer major 1", AutocompleteOptions{Focus: &Coord{Lat: 42.556, Lon: 1.533}})
+		if len(results) == 0 || results[0].City != "Ordino" {
+			t.Errorf("Expected the address in Ordino first, got %+v", results)
+		}
+	})
+
+	t.Run("Short prefix of a later word", func(t *testing.T) {
+		results := idx.Autocomplete("maj", AutocompleteOptions{Limit: 1})
+		if len(results) != 1 || results[0].Name != "Carrer Major" {
+			t.Errorf("Expected Carrer Major, got %+v", results)
+		}
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		if results := idx.Autocomplete("zz", AutocompleteOptions{}); len(results) != 0 {
+			t.Errorf("Expected no results, got %+v", results)
+		}
+		if results := idx.Autocomplete(" ,", AutocompleteOptions{}); results != nil {
+			t.Errorf("Expected nil for an empty prefix, got %+v", results)
+		}
+	})
+}
+
+func TestAutocomplete_ShortPrefixLimit(t *testing.T) {
+	idx := &GeoIndex{Addresses: make(map[string]*Address)}
+	for i := 0; i < 1000; i++ {
+		street := fmt.Sprintf("Street %d", i)
+		idx.Addresses[makeAddressKey(street, "1", "")] = &Address{Street: street, HouseNumber: "1"}
+	}
+
+	results := idx.Autocomplete("st", AutocompleteOptions{Limit: 20})
+	if len(results) != 20 {
+		t.Fatalf("Expected 20 suggestions, got %d", len(results))
+	}
+	if n := len(idx.prefixIndex().short["st"]); n > shortPrefixSize {
+		t.Errorf("Expected at most %d keys for a short prefix, got %d", shortPrefixSize, n)
+	}
+
+	// Features added after the first query are suggested too
+	idx.Addresses[makeAddressKey("Stadionweg", "1", "")] = &Address{Street: "Stadionweg", HouseNumber: "1"}
+	idx.changed()
+	if results := idx.Autocomplete("stadion", AutocompleteOptions{}); len(results) != 1 {
+		t.Errorf("Expected the new address, got %+v", results)
+	}
+}
+
+func TestAutocomplete_ShortPrefixFocus(t *testing.T) {
+	idx := &GeoIndex{Addresses: make(map[string]*Address)}
+	for i := 0; i < 200; i++ {
+		street := fmt.Sprintf("Street %d", i)
+		idx.Addresses[makeAddressKey(street, "1", "")] = &Address{Street: street, HouseNumber: "1", Lat: 10, Lon: 10}
+	}
+	// Its longer name keeps it out of the best keys for "st" overall
+	idx.Addresses[makeAddressKey("Stadium Lane", "1", "")] = &Address{Street: "Stadium Lane", HouseNumber: "1", Lat: 42.5, Lon: 1.5}
+
+	for _, r := range idx.Autocomplete("st", AutocompleteOptions{Limit: 20}) {
+		if r.Street == "Stadium Lane" {
+			t.Fatal("Expected the nearby address outside the best keys without a focus")
+		}
+	}
+	results := idx.Autocomplete("st", AutocompleteOptions{Limit: 5, Focus: &Coord{Lat: 42.51, Lon: 1.51}})
+	if len(results) == 0 || results[0].Street != "Stadium Lane" {
+		t.Errorf("Expected the address near the focus first, got %+v", results)
+	}
+	viewport := &Bounds{MinLat: 42.4, MinLon: 1.4, MaxLat: 42.6, MaxLon: 1.6}
+	results = idx.Autocomplete("st", AutocompleteOptions{Limit: 5, Viewport: viewport})
+	if len(results) == 0 || results[0].Street != "Stadium Lane" {
+		t.Errorf("Expected the address in the viewport first, got %+v", results)
+	}
+}
+
+func TestGeoBuilder_BuildsTextIndexes(t *testing.T) {
+	b := NewGeoBuilder()
+	b.ProcessNode(&osm.Node{ID: 1, Lat: 42.5, Lon: 1.5, Tags: osm.Tags{
+		{Key: "addr:housenumber", Value: "2"},
+		{Key: "addr:street", Value: "Carrer Major"},
+	}})
+
+	// Queries find the text indexes current rather than building them
+	idx := b.GetIndex()
+	if idx.text == nil || idx.text.version != idx.version {
+		t.Error("GetIndex() left the name index to the first query")
+	}
+	if idx.prefixes == nil || idx.prefixes.version != idx.version {
+		t.Error("GetIndex() left the autocomplete index to the first query")
+	}
+	if results := idx.Autocomplete("carrer m", AutocompleteOptions{}); len(results) != 1 {
+		t.Errorf("Expected the address, got %+v", results)
+	}
+}
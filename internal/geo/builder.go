@@ -2,10 +2,13 @@
 package geo
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sebastiaanwouters/geodude/internal/address"
 	"github.com/sebastiaanwouters/geodude/internal/osm"
 )
 
@@ -14,15 +17,57 @@ type GeoBuilder struct {
 	bounds     Bounds
 	maxPoints  int
 	streetTags map[string]bool
-	nodes      map[osm.ID]*osm.Node
+	nodes      osm.NodeStore
+	nodeTags   map[osm.ID]osm.Tags // Tags of address nodes, which interpolation ways end on
+	ways       map[osm.ID][]osm.ID
+	wayFilter  func(id osm.ID) bool // Ways whose nodes are kept, nil for all
+	unresolved []*Address           // Addresses missing a city or country
+	streets    []streetWay          // Street ways waiting to be merged per locality
+
+	places      []*Place              // Places waiting to be linked to boundaries
+	placeLabels map[string]*AdminArea // Boundaries by the ID of their label member
+
+	timeZone  *time.Location            // Zone of opening hours without a tagged zone
+	timeZones map[string]*time.Location // Zones loaded by name, nil if unknown
+	unzoned   []*POI                    // POIs whose opening hours await a zone
+
+	diagnostics []Diagnostic // Issues found in areas
+}
+
+// Diagnostic is an issue found in an area while building the index
+type Diagnostic struct {
+	ObjectType string // "way" or "relation"
+	ID         osm.ID
+	Issue      AssemblyIssue
+	Dropped    bool // Whether the area was left out of the index
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s %d: %s", d.ObjectType, d.ID, d.Issue)
+	if d.Dropped {
+		s += " (dropped)"
+	}
+	return s
 }
 
 func NewGeoBuilder() *GeoBuilder {
+	return NewGeoBuilderWithNodeStore(osm.NewSparseNodeStore())
+}
+
+// NewGeoBuilderWithNodeStore creates a builder keeping node locations in the
+// given store, such as one chosen by osm.NewNodeStore for the input size
+func NewGeoBuilderWithNodeStore(nodes osm.NodeStore) *GeoBuilder {
 	return &GeoBuilder{
 		index: &GeoIndex{
 			Addresses:     make(map[string]*Address),
-			AddressRanges: make(map[string]*AddressRange),
+			AddressRanges: make(map[string][]*AddressRange),
 			StreetIndex:   NewQuadTree(Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}, 50),
+			AdminAreas:    NewRTree(16),
+			Buildings:     NewRTree(16),
+			Streets:       make(map[string][]*Street),
+			StreetLines:   NewRTree(16),
+			POIs:          NewRTree(16),
+			Places:        NewRTree(16),
 		},
 		streetTags: map[string]bool{
 			"highway":       true,
@@ -30,166 +75,374 @@ func NewGeoBuilder() *GeoBuilder {
 			"service":       true,
 			"living_street": true,
 		},
-		nodes: make(map[osm.ID]*osm.Node),
+		nodes:       nodes,
+		nodeTags:    make(map[osm.ID]osm.Tags),
+		ways:        make(map[osm.ID][]osm.ID),
+		placeLabels: make(map[string]*AdminArea),
+		timeZone:    time.UTC,
+		timeZones:   make(map[string]*time.Location),
 	}
 }
 
 func (b *GeoBuilder) ProcessNode(node *osm.Node) error {
-	b.nodes[node.ID] = node
+	if err := b.nodes.Set(node.ID, node.Lat, node.Lon); err != nil {
+		return err
+	}
 
-	if houseNumber := node.Tags.Get("addr:housenumber"); houseNumber != "" {
-		addr := &Address{
-			HouseNumber: houseNumber,
-			Street:      node.Tags.Get("addr:street"),
-			City:        node.Tags.Get("addr:city"),
-			PostCode:    node.Tags.Get("addr:postcode"),
-			Country:     node.Tags.Get("addr:country"),
-			Lat:         node.Lat,
-			Lon:         node.Lon,
+	if node.Tags.Get("addr:housenumber") != "" {
+		b.nodeTags[node.ID] = node.Tags
+		b.addAddress(addressFromTags(node.Tags, Coord{Lat: node.Lat, Lon: node.Lon}))
+	}
+	if len(node.Tags) > 0 {
+		location := Coord{Lat: node.Lat, Lon: node.Lon}
+		if poi := poiFromTags(elementID('n', node.ID), node.Tags, location); poi != nil {
+			b.addPOI(poi)
+		}
+		if place := placeFromTags(elementID('n', node.ID), node.Tags); place != nil {
+			place.Location = location
+			b.places = append(b.places, place)
 		}
+	}
+	return nil
+}
 
-		key := makeAddressKey(addr.Street, addr.HouseNumber, addr.PostCode)
-		b.index.Addresses[key] = addr
+// addressFromTags creates an address from the addr:* tags of a feature
+func addressFromTags(tags osm.Tags, location Coord) *Address {
+	return &Address{
+		HouseNumber: tags.Get("addr:housenumber"),
+		Street:      tags.Get("addr:street"),
+		StreetNames: namesFromTags(tags, "addr:street"),
+		City:        tags.Get("addr:city"),
+		PostCode:    tags.Get("addr:postcode"),
+		Country:     tags.Get("addr:country"),
+		Lat:         location.Lat,
+		Lon:         location.Lon,
+	}
+}
 
-		b.index.StreetIndex.Insert(Point{
-			Lat:  node.Lat,
-			Lon:  node.Lon,
-			Data: addr,
-		})
+// addAddress stores an address in the lookup table and the spatial index, and
+// its footprint in the building index if it has one
+func (b *GeoBuilder) addAddress(addr *Address) {
+	key := makeAddressKey(addr.Street, addr.HouseNumber, addr.PostCode)
+	b.index.Addresses[key] = addr
+	b.index.changed()
+	b.trackUnresolved(addr)
+
+	b.index.StreetIndex.Insert(Point{
+		Lat:  addr.Lat,
+		Lon:  addr.Lon,
+		Data: addr,
+	})
+	if addr.Footprint != nil {
+		b.index.Buildings.Insert(RTreeEntry{Geometry: addr.Footprint, Data: addr})
 	}
-	return nil
+}
+
+// processAddressArea indexes an address tagged on a building or other area at
+// a point inside its footprint
+func (b *GeoBuilder) processAddressArea(tags osm.Tags, footprint MultiPolygon) {
+	addr := addressFromTags(tags, footprint.PointOnSurface())
+	addr.Footprint = footprint
+	b.addAddress(addr)
+}
+
+// SetRelationWays implements osm.RelationWayProcessor, keeping the node lists
+// of only the ways that relations refer to
+func (b *GeoBuilder) SetRelationWays(isMember func(id osm.ID) bool) {
+	b.wayFilter = isMember
 }
 
 func (b *GeoBuilder) ProcessWay(way *osm.Way) error {
+	// Relations refer to ways by ID and arrive after them, so keep the node
+	// lists of the ways they use, or of every way if those are not known
+	if b.wayFilter == nil || b.wayFilter(way.ID) {
+		b.ways[way.ID] = way.Nodes
+	}
+
 	if interpolationType := way.Tags.Get("addr:interpolation"); interpolationType != "" {
 		return b.processInterpolation(way, interpolationType)
 	}
 
+	if len(way.Nodes) > 3 && way.Nodes[0] == way.Nodes[len(way.Nodes)-1] {
+		_, isPOI := classify(way.Tags)
+		isPlace := way.Tags.Get("place") != ""
+		if isPOI || isPlace || way.Tags.Get("addr:housenumber") != "" {
+			// Broken footprints are skipped rather than failing the whole
+			// import, and reported in the diagnostics
+			footprint, issues, err := b.assembler().AssembleWay(way)
+			b.recordIssues("way", way.ID, issues, err)
+			if err == nil {
+				if isPOI {
+					b.processPOIArea(elementID('w', way.ID), way.Tags, footprint)
+				}
+				if isPlace {
+					b.processPlaceArea(elementID('w', way.ID), way.Tags, footprint)
+				}
+				if way.Tags.Get("addr:housenumber") != "" {
+					b.processAddressArea(way.Tags, footprint)
+				}
+			}
+		}
+	}
+
 	if b.isStreet(way.Tags) {
 		if street := way.Tags.Get("name"); street != "" {
-			centerLat, centerLon := b.calculateWayCentroid(way)
-			b.index.StreetIndex.Insert(Point{
-				Lat:  centerLat,
-				Lon:  centerLon,
-				Data: street,
-			})
+			b.processStreet(way, street)
 		}
 	}
 
 	return nil
 }
 
+// processInterpolation adds the house numbers of an addr:interpolation way.
+// Tagged nodes along the way, not only its ends, split it into ranges, and
+// numbers are spread evenly by length along each range. Ways that cannot be
+// interpolated are skipped rather than failing the import.
 func (b *GeoBuilder) processInterpolation(way *osm.Way, interpolationType string) error {
-	if len(way.Nodes) < 2 {
-		return nil
+	step, parity := 1, ""
+	switch interpolationType {
+	case "even", "odd":
+		step, parity = 2, interpolationType
+	case "all":
+	default:
+		s, err := strconv.Atoi(interpolationType)
+		if err != nil || s <= 0 {
+			// Such as "alphabetic", which has no numbers to interpolate
+			return nil
+		}
+		step = s
 	}
 
-	startNode, exists := b.nodes[way.Nodes[0]]
-	if !exists {
-		return fmt.Errorf("start node not found")
+	var start *osm.Node
+	var line LineString
+	for _, id := range way.Nodes {
+		node, exists := b.node(id)
+		if !exists {
+			return nil
+		}
+		line = append(line, Coord{Lat: node.Lat, Lon: node.Lon})
+		if node.Tags.Get("addr:housenumber") == "" {
+			continue
+		}
+		if start != nil {
+			b.addAddressRange(way.Tags, start, node, line, step, parity)
+		}
+		start, line = node, LineString{line[len(line)-1]}
 	}
+	return nil
+}
 
-	endNode, exists := b.nodes[way.Nodes[len(way.Nodes)-1]]
-	if !exists {
-		return fmt.Errorf("end node not found")
+// addAddressRange adds the range between two tagged nodes of an
+// interpolation way and the house numbers strictly between them
+func (b *GeoBuilder) addAddressRange(tags osm.Tags, startNode, endNode *osm.Node, line LineString, step int, parity string) {
+	start, ok := address.ParseHouseNumber(startNode.Tags.Get("addr:housenumber"))
+	if !ok || start.None {
+		return
 	}
-
-	startNum, err := strconv.Atoi(startNode.Tags.Get("addr:housenumber"))
-	if err != nil {
-		return fmt.Errorf("invalid start house number: %w", err)
+	end, ok := address.ParseHouseNumber(endNode.Tags.Get("addr:housenumber"))
+	if !ok || end.None {
+		return
 	}
-
-	endNum, err := strconv.Atoi(endNode.Tags.Get("addr:housenumber"))
-	if err != nil {
-		return fmt.Errorf("invalid end house number: %w", err)
+	// Ranges such as "2-4" at the ends are interpolated from their inner number
+	startNum, endNum := max(start.Number, start.To), end.Number
+	if startNum > endNum {
+		startNum, endNum = start.Number, max(end.Number, end.To)
 	}
-
-	step := 1
-	switch interpolationType {
-	case "even":
-		step = 2
-	case "odd":
-		step = 2
-	case "all":
-		step = 1
-	default:
-		if s, err := strconv.Atoi(interpolationType); err == nil {
-			step = s
+	if startNum == endNum {
+		return
+	}
+	if startNum > endNum {
+		// Numbered against the direction of the way
+		startNum, endNum = endNum, startNum
+		startNode, endNode = endNode, startNode
+		line = append(LineString(nil), line...)
+		for i, j := 0, len(line)-1; i < j; i, j = i+1, j-1 {
+			line[i], line[j] = line[j], line[i]
 		}
 	}
+	// Both ends of an even or odd range must have its parity
+	if parity != "" && (startNum%2 != endNum%2 || (startNum%2 == 0) != (parity == "even")) {
+		return
+	}
 
+	// The way usually only carries addr:interpolation; the rest of the
+	// address is on its end nodes
+	get := func(key string) string {
+		if v := tags.Get(key); v != "" {
+			return v
+		}
+		return startNode.Tags.Get(key)
+	}
 	addressRange := &AddressRange{
 		StartNumber: startNum,
 		EndNumber:   endNum,
 		Step:        step,
-		Street:      way.Tags.Get("addr:street"),
-		City:        way.Tags.Get("addr:city"),
-		PostCode:    way.Tags.Get("addr:postcode"),
-		Country:     way.Tags.Get("addr:country"),
+		Parity:      parity,
+		Street:      get("addr:street"),
+		City:        get("addr:city"),
+		PostCode:    get("addr:postcode"),
+		Country:     get("addr:country"),
 		StartLat:    startNode.Lat,
 		StartLon:    startNode.Lon,
 		EndLat:      endNode.Lat,
 		EndLon:      endNode.Lon,
+		Line:        line,
+	}
+	if addressRange.Street == "" {
+		return
 	}
 
 	key := makeStreetKey(addressRange.Street, addressRange.PostCode)
-	b.index.AddressRanges[key] = addressRange
-
-	for num := startNum; num <= endNum; num += step {
-		ratio := float64(num-startNum) / float64(endNum-startNum)
-		lat := startNode.Lat + (endNode.Lat-startNode.Lat)*ratio
-		lon := startNode.Lon + (endNode.Lon-startNode.Lon)*ratio
-
-		addr := &Address{
-			HouseNumber: strconv.Itoa(num),
-			Street:      addressRange.Street,
-			City:        addressRange.City,
-			PostCode:    addressRange.PostCode,
-			Country:     addressRange.Country,
-			Lat:         lat,
-			Lon:         lon,
-		}
+	b.index.AddressRanges[key] = append(b.index.AddressRanges[key], addressRange)
+	b.index.changed()
 
+	for num := startNum + step; num < endNum; num += step {
+		addr := addressRange.Interpolate(strconv.Itoa(num))
 		key := makeAddressKey(addr.Street, addr.HouseNumber, addr.PostCode)
+		// Mapped addresses are more precise than interpolated ones
+		if _, exists := b.index.Addresses[key]; exists {
+			continue
+		}
 		b.index.Addresses[key] = addr
+		b.trackUnresolved(addr)
 	}
-
-	return nil
 }
 
 func (b *GeoBuilder) ProcessRelation(relation *osm.Relation) error {
+	if isAdminBoundary(relation.Tags) {
+		area, issues, err := b.buildAdminArea(relation)
+		b.recordIssues("relation", relation.ID, issues, err)
+		if err != nil {
+			// Boundaries crossing the extract edge are incomplete; they are
+			// skipped and reported in the diagnostics
+			return nil
+		}
+		b.index.AdminAreas.Insert(RTreeEntry{Geometry: area.Geometry, Data: area})
+		b.index.changed()
+
+		// The place node labelling the boundary describes the same locality
+		for _, m := range relation.Members {
+			if m.Type == "node" && m.Role == "label" {
+				b.placeLabels[elementID('n', m.Ref)] = area
+			}
+		}
+		if place := placeFromTags(elementID('r', relation.ID), relation.Tags); place != nil {
+			place.Location = area.Geometry.PointOnSurface()
+			place.Geometry = area.Geometry
+			b.places = append(b.places, place)
+			b.placeLabels[place.ID] = area
+		}
+	}
+
+	if relation.Tags.Get("type") == "multipolygon" {
+		_, isPOI := classify(relation.Tags)
+		// Boundaries tagged with a place were handled above
+		isPlace := relation.Tags.Get("place") != "" && !isAdminBoundary(relation.Tags)
+		if isPOI || isPlace || relation.Tags.Get("addr:housenumber") != "" {
+			footprint, issues, err := b.assembler().AssembleRelation(relation)
+			b.recordIssues("relation", relation.ID, issues, err)
+			if err == nil {
+				if isPOI {
+					b.processPOIArea(elementID('r', relation.ID), relation.Tags, footprint)
+				}
+				if isPlace {
+					b.processPlaceArea(elementID('r', relation.ID), relation.Tags, footprint)
+				}
+				if relation.Tags.Get("addr:housenumber") != "" {
+					b.processAddressArea(relation.Tags, footprint)
+				}
+			}
+		}
+	}
 	return nil
 }
 
+// GetIndex returns the index, first linking places to their boundaries,
+// filling in the city and country of addresses that lack them, merging
+// street ways per locality and setting the time zone of opening hours, using
+// the boundaries and places seen so far
 func (b *GeoBuilder) GetIndex() *GeoIndex {
+	b.resolvePlaces()
+	b.resolveStreets()
+	b.resolveTimeZones()
+	for _, addr := range b.unresolved {
+		b.index.fillFromAdminAreas(addr)
+	}
+	b.unresolved = nil
+	b.index.shareStreetNames()
+	b.index.changed()
+	// Build the text indexes now, so that no query waits for them
+	b.index.textIndex()
+	b.index.prefixIndex()
 	return b.index
 }
 
-func (b *GeoBuilder) calculateWayCentroid(way *osm.Way) (float64, float64) {
-	var sumLat, sumLon float64
-	var count int
+// recordIssues adds the issues of assembling an area to the diagnostics,
+// along with those that made it fail
+func (b *GeoBuilder) recordIssues(objectType string, id osm.ID, issues []AssemblyIssue, err error) {
+	for _, issue := range issues {
+		b.diagnostics = append(b.diagnostics, Diagnostic{ObjectType: objectType, ID: id, Issue: issue})
+	}
+	if err == nil {
+		return
+	}
+	var assemblyErr *AssemblyError
+	if !errors.As(err, &assemblyErr) {
+		assemblyErr = &AssemblyError{Issues: []AssemblyIssue{{Message: err.Error()}}}
+	}
+	for _, issue := range assemblyErr.Issues {
+		b.diagnostics = append(b.diagnostics, Diagnostic{ObjectType: objectType, ID: id, Issue: issue, Dropped: true})
+	}
+}
+
+// Diagnostics returns the issues found in the areas processed so far, such
+// as broken multipolygons and boundaries
+func (b *GeoBuilder) Diagnostics() []Diagnostic {
+	return b.diagnostics
+}
 
-	for _, nodeID := range way.Nodes {
-		if node, exists := b.nodes[nodeID]; exists {
-			sumLat += node.Lat
-			sumLon += node.Lon
-			count++
-		}
+func (b *GeoBuilder) trackUnresolved(addr *Address) {
+	if addr.City == "" || addr.Country == "" {
+		b.unresolved = append(b.unresolved, addr)
 	}
+}
 
-	if count == 0 {
-		return 0, 0
+// assembler returns an AreaAssembler reading from the cached ways and nodes
+func (b *GeoBuilder) assembler() *AreaAssembler {
+	return &AreaAssembler{
+		Way: func(id osm.ID) ([]osm.ID, bool) {
+			nodes, exists := b.ways[id]
+			return nodes, exists
+		},
+		Node: func(id osm.ID) (Coord, bool) {
+			lat, lon, exists := b.nodes.Get(id)
+			return Coord{Lat: lat, Lon: lon}, exists
+		},
 	}
+}
 
-	return sumLat / float64(count), sumLon / float64(count)
+// node returns a stored node with its location and, for address nodes, its
+// tags
+func (b *GeoBuilder) node(id osm.ID) (*osm.Node, bool) {
+	lat, lon, exists := b.nodes.Get(id)
+	if !exists {
+		return nil, false
+	}
+	return &osm.Node{ID: id, Lat: lat, Lon: lon, Tags: b.nodeTags[id]}, true
 }
 
-func (b *GeoBuilder) ClearNodeCache() {
-	b.nodes = make(map[osm.ID]*osm.Node)
+// ClearNodeCache releases the node store and the way members once the index
+// is built
+func (b *GeoBuilder) ClearNodeCache() error {
+	err := b.nodes.Close()
+	b.nodes = osm.NewSparseNodeStore()
+	b.nodeTags = make(map[osm.ID]osm.Tags)
+	b.ways = make(map[osm.ID][]osm.ID)
+	return err
 }
 
 func makeAddressKey(street, houseNumber, postcode string) string {
-	return strings.ToLower(street + ":" + houseNumber + ":" + postcode)
+	return strings.ToLower(street + ":" + houseNumberKey(houseNumber) + ":" + postcode)
 }
 
 func makeStreetKey(street, postcode string) string {
@@ -0,0 +1,180 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestGeoBuilder_BuildingAddresses(t *testing.T) {
+	b := NewGeoBuilder()
+
+	// An L-shaped building whose centroid lies outside its footprint
+	corners := []Coord{
+		{Lat: 0, Lon: 0}, {Lat: 0, Lon: 0.003}, {Lat: 0.001, Lon: 0.003},
+		{Lat: 0.001, Lon: 0.001}, {Lat: 0.003, Lon: 0.001}, {Lat: 0.003, Lon: 0},
+	}
+	for i, c := range corners {
+		b.ProcessNode(&osm.Node{ID: osm.ID(i + 1), Lat: c.Lat, Lon: c.Lon})
+	}
+	// A square building mapped as a multipolygon relation
+	addSquareNodes(t, b, 100, 0.01, 0.01, 0.001)
+	// A free-standing address node close to the L-shaped building
+	b.ProcessNode(&osm.Node{ID: 50, Lat: 0.0012, Lon: 0.0012, Tags: osm.Tags{
+		{Key: "addr:housenumber", Value: "2"},
+		{Key: "addr:street", Value: "Carrer Major"},
+	}})
+
+	ways := []*osm.Way{
+		{ID: 1, Nodes: []osm.ID{1, 2, 3, 4, 5, 6, 1}, Tags: osm.Tags{
+			{Key: "building", Value: "yes"},
+			{Key: "addr:housenumber", Value: "1"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+		{ID: 2, Nodes: []osm.ID{100, 101, 102, 103, 100}},
+	}
+	for _, w := range ways {
+		if err := b.ProcessWay(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := b.ProcessRelation(&osm.Relation{
+		ID: 1,
+		Tags: osm.Tags{
+			{Key: "type", Value: "multipolygon"},
+			{Key: "building", Value: "yes"},
+			{Key: "addr:housenumber", Value: "5"},
+			{Key: "addr:street", Value: "Avinguda Meritxell"},
+		},
+		Members: []osm.Member{{Type: "way", Ref: 2, Role: "outer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := b.GetIndex()
+	lShaped := idx.Addresses[makeAddressKey("Carrer Major", "1", "")]
+	if lShaped == nil || lShaped.Footprint == nil {
+		t.Fatalf("Expected building address with footprint, got %+v", lShaped)
+	}
+	if !lShaped.Footprint.Contains(Coord{Lat: lShaped.Lat, Lon: lShaped.Lon}) {
+		t.Errorf("Address location (%f, %f) is outside the footprint", lShaped.Lat, lShaped.Lon)
+	}
+	if idx.Addresses[makeAddressKey("Avinguda Meritxell", "5", "")] == nil {
+		t.Error("Expected address from multipolygon relation")
+	}
+
+	t.Run("Reverse geocoding inside a building", func(t *testing.T) {
+		// Closer to the address node than to the building's own location
+		result, err := idx.ReverseGeocode(0.0009, 0.0011)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == nil || result.HouseNumber != "1" || result.Distance != 0 {
+			t.Errorf("Expected building 1 at distance 0, got %+v", result)
+		}
+	})
+
+	t.Run("BuildingAt", func(t *testing.T) {
+		if addr := idx.BuildingAt(0.0105, 0.0105); addr == nil || addr.HouseNumber != "5" {
+			t.Errorf("Expected building 5, got %+v", addr)
+		}
+		if addr := idx.BuildingAt(0.002, 0.002); addr != nil {
+			t.Errorf("Expected no building in the notch of the L, got %+v", addr)
+		}
+	})
+}
+
+func TestGeoBuilder_Interpolation(t *testing.T) {
+	b := NewGeoBuilder()
+	number := func(n string) osm.Tags {
+		return osm.Tags{{Key: "addr:housenumber", Value: n}, {Key: "addr:street", Value: "Carrer Major"}}
+	}
+	// An L-shaped way numbered 2 to 10 with 6 tagged at its corner, and a
+	// second range further along the same street
+	nodes := []osm.Node{
+		{ID: 1, Lat: 0, Lon: 0, Tags: number("2")},
+		{ID: 2, Lat: 0, Lon: 0.002},
+		{ID: 3, Lat: 0, Lon: 0.004, Tags: number("6")},
+		{ID: 4, Lat: 0.004, Lon: 0.004, Tags: number("10a")},
+		{ID: 5, Lat: 0.01, Lon: 0, Tags: number("20")},
+		{ID: 6, Lat: 0.01, Lon: 0.004, Tags: number("24")},
+		{ID: 7, Lat: 0.02, Lon: 0, Tags: number("31")},
+		{ID: 8, Lat: 0.02, Lon: 0.004, Tags: number("36")},
+	}
+	for i := range nodes {
+		if err := b.ProcessNode(&nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ways := []*osm.Way{
+		{ID: 1, Nodes: []osm.ID{1, 2, 3, 4}, Tags: osm.Tags{{Key: "addr:interpolation", Value: "even"}}},
+		{ID: 2, Nodes: []osm.ID{6, 5}, Tags: osm.Tags{{Key: "addr:interpolation", Value: "even"}}},
+		// Ends of different parity cannot be interpolated as odd
+		{ID: 3, Nodes: []osm.ID{7, 8}, Tags: osm.Tags{{Key: "addr:interpolation", Value: "odd"}}},
+		// A node outside the extract
+		{ID: 4, Nodes: []osm.ID{1, 99}, Tags: osm.Tags{{Key: "addr:interpolation", Value: "all"}}},
+	}
+	for _, w := range ways {
+		if err := b.ProcessWay(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := b.GetIndex()
+
+	if n := len(idx.AddressRanges[makeStreetKey("Carrer Major", "")]); n != 3 {
+		t.Fatalf("Expected 3 ranges, got %d", n)
+	}
+	tests := []struct {
+		number   string
+		lat, lon float64
+	}{
+		{"4", 0, 0.002},     // Halfway to the corner node
+		{"8", 0.002, 0.004}, // Halfway from the corner to 10a
+		{"22", 0.01, 0.002}, // In the second range, numbered against the way
+	}
+	for _, tt := range tests {
+		addr := idx.Addresses[makeAddressKey("Carrer Major", tt.number, "")]
+		if addr == nil || !almostEqual(addr.Lat, tt.lat, 1e-6) || !almostEqual(addr.Lon, tt.lon, 1e-6) {
+			t.Errorf("%s: expected (%f, %f), got %+v", tt.number, tt.lat, tt.lon, addr)
+		}
+	}
+	for _, number := range []string{"5", "7", "33"} {
+		if addr := idx.Addresses[makeAddressKey("Carrer Major", number, "")]; addr != nil {
+			t.Errorf("%s: expected no address, got %+v", number, addr)
+		}
+	}
+	if addr := idx.Addresses[makeAddressKey("Carrer Major", "6", "")]; addr.Lat != 0 || addr.Lon != 0.004 {
+		t.Errorf("Expected the mapped address to be kept, got %+v", addr)
+	}
+	if result, err := idx.Geocode("Carrer Major", "24", ""); err != nil || result.Lat != 0.01 {
+		t.Errorf("Geocode() = %+v, %v", result, err)
+	}
+}
+
+func TestGeoBuilder_TwoPass(t *testing.T) {
+	path := "../../data/andorra-latest.osm.pbf"
+	single := NewGeoBuilder()
+	if err := osm.ParsePBF(path, nil, single); err != nil {
+		t.Fatal(err)
+	}
+	b := NewGeoBuilder()
+	if err := osm.ParsePBFTwoPass(path, osm.MustParseFilter("n/*"), b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the node lists of the ways that relations refer to are kept
+	if len(b.ways) == 0 || len(b.ways) >= len(single.ways)/2 {
+		t.Errorf("Expected far fewer than the %d ways, kept %d", len(single.ways), len(b.ways))
+	}
+	want, got := single.GetIndex(), b.GetIndex()
+	if got.AdminAreas.Size() != want.AdminAreas.Size() || got.Buildings.Size() != want.Buildings.Size() ||
+		got.POIs.Size() != want.POIs.Size() || len(got.Addresses) != len(want.Addresses) {
+		t.Errorf("Expected the same index as a single pass, got %d boundaries, %d buildings, %d POIs and %d addresses, want %d, %d, %d and %d",
+			got.AdminAreas.Size(), got.Buildings.Size(), got.POIs.Size(), len(got.Addresses),
+			want.AdminAreas.Size(), want.Buildings.Size(), want.POIs.Size(), len(want.Addresses))
+	}
+	if len(b.Diagnostics()) != len(single.Diagnostics()) {
+		t.Errorf("Expected %d diagnostics, got %d", len(single.Diagnostics()), len(b.Diagnostics()))
+	}
+}
@@ -0,0 +1,191 @@
+// internal/geo/category.go
+package geo
+
+import (
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// Category identifies a kind of POI as a dot-separated path from a broad
+// group to a specific kind, such as "health.pharmacy"
+type Category string
+
+// Group returns the broad group of the category, such as "health"
+func (c Category) Group() Category {
+	group, _, _ := strings.Cut(string(c), ".")
+	return Category(group)
+}
+
+// Is reports whether c is other or one of its subcategories
+func (c Category) Is(other Category) bool {
+	return other == "" || c == other || strings.HasPrefix(string(c), string(other)+".")
+}
+
+// categoryRule classifies features tagged key=value
+type categoryRule struct {
+	key, value string
+	category   Category
+	keywords   []string // Words naming the category in queries, besides its own name
+}
+
+// categoryRules is the taxonomy of POIs. Features matching none of these
+// rules are classified by fallbackGroups.
+var categoryRules = []categoryRule{
+	{"amenity", "pharmacy", "health.pharmacy", []string{"chemist", "farmacia", "pharmacie", "drugstore"}},
+	{"healthcare", "pharmacy", "health.pharmacy", nil},
+	{"amenity", "hospital", "health.hospital", []string{"hospital", "hopital"}},
+	{"amenity", "clinic", "health.clinic", nil},
+	{"amenity", "doctors", "health.doctor", []string{"doctor", "metge", "medico", "medecin"}},
+	{"amenity", "dentist", "health.dentist", nil},
+	{"amenity", "veterinary", "health.veterinary", []string{"vet"}},
+
+	{"amenity", "restaurant", "food.restaurant", []string{"restaurante"}},
+	{"amenity", "cafe", "food.cafe", []string{"coffee", "cafeteria"}},
+	{"amenity", "fast_food", "food.fast_food", nil},
+	{"amenity", "bar", "food.bar", nil},
+	{"amenity", "pub", "food.pub", nil},
+	{"amenity", "ice_cream", "food.ice_cream", nil},
+
+	{"shop", "supermarket", "shopping.supermarket", []string{"supermercat", "supermercado", "grocery"}},
+	{"shop", "convenience", "shopping.convenience", nil},
+	{"shop", "bakery", "shopping.bakery", []string{"forn", "panaderia", "boulangerie"}},
+	{"shop", "butcher", "shopping.butcher", nil},
+	{"shop", "clothes", "shopping.clothes", nil},
+	{"shop", "shoes", "shopping.shoes", nil},
+	{"shop", "chemist", "shopping.chemist", nil},
+	{"shop", "electronics", "shopping.electronics", nil},
+	{"shop", "hairdresser", "shopping.hairdresser", nil},
+	{"shop", "mall", "shopping.mall", nil},
+	{"shop", "department_store", "shopping.department_store", nil},
+	{"shop", "sports", "shopping.sports", nil},
+	{"shop", "alcohol", "shopping.alcohol", nil},
+	{"shop", "tobacco", "shopping.tobacco", nil},
+
+	{"amenity", "fuel", "transport.fuel", []string{"gas station", "petrol station", "gasolinera", "benzinera", "station service"}},
+	{"amenity", "charging_station", "transport.charging_station", nil},
+	{"amenity", "parking", "transport.parking", []string{"aparcament", "aparcamiento"}},
+	{"amenity", "bus_station", "transport.bus_station", nil},
+	{"highway", "bus_stop", "transport.bus_stop", nil},
+	{"amenity", "taxi", "transport.taxi", nil},
+	{"amenity", "car_rental", "transport.car_rental", nil},
+	{"aerialway", "station", "transport.aerialway_station", nil},
+
+	{"amenity", "bank", "finance.bank", []string{"banc", "banco", "banque"}},
+	{"amenity", "atm", "finance.atm", []string{"cash machine"}},
+	{"amenity", "bureau_de_change", "finance.bureau_de_change", nil},
+
+	{"tourism", "hotel", "accommodation.hotel", []string{"hotel"}},
+	{"tourism", "guest_house", "accommodation.guest_house", nil},
+	{"tourism", "hostel", "accommodation.hostel", nil},
+	{"tourism", "apartment", "accommodation.apartment", nil},
+	{"tourism", "camp_site", "accommodation.camp_site", []string{"camping"}},
+	{"tourism", "alpine_hut", "accommodation.alpine_hut", []string{"refugi", "refugio"}},
+	{"tourism", "wilderness_hut", "accommodation.wilderness_hut", nil},
+
+	{"tourism", "museum", "tourism.museum", []string{"museu", "museo", "musee"}},
+	{"tourism", "attraction", "tourism.attraction", nil},
+	{"tourism", "viewpoint", "tourism.viewpoint", nil},
+	{"tourism", "information", "tourism.information", nil},
+	{"tourism", "artwork", "tourism.artwork", nil},
+
+	{"amenity", "school", "education.school", []string{"escola", "escuela", "ecole"}},
+	{"amenity", "kindergarten", "education.kindergarten", nil},
+	{"amenity", "university", "education.university", nil},
+	{"amenity", "library", "education.library", []string{"biblioteca", "bibliotheque"}},
+
+	{"amenity", "police", "public.police", []string{"policia", "police station"}},
+	{"amenity", "fire_station", "public.fire_station", nil},
+	{"amenity", "post_office", "public.post_office", []string{"post", "correus", "correos"}},
+	{"amenity", "townhall", "public.townhall", []string{"town hall", "comu", "ajuntament"}},
+	{"amenity", "toilets", "public.toilets", []string{"wc"}},
+	{"amenity", "place_of_worship", "religion.place_of_worship", []string{"church", "esglesia", "iglesia", "eglise"}},
+
+	{"leisure", "park", "leisure.park", nil},
+	{"leisure", "playground", "leisure.playground", nil},
+	{"leisure", "sports_centre", "leisure.sports_centre", nil},
+	{"leisure", "fitness_centre", "leisure.fitness_centre", []string{"gym"}},
+	{"leisure", "swimming_pool", "leisure.swimming_pool", nil},
+	{"leisure", "stadium", "leisure.stadium", nil},
+	{"leisure", "golf_course", "leisure.golf_course", nil},
+	{"amenity", "cinema", "leisure.cinema", nil},
+	{"amenity", "theatre", "leisure.theatre", nil},
+}
+
+// fallbackGroups classify named features that match no rule as
+// <group>.<value>, such as shop=books as "shopping.books"
+var fallbackGroups = []struct {
+	key   string
+	group Category
+}{
+	{"amenity", "amenity"},
+	{"shop", "shopping"},
+	{"tourism", "tourism"},
+	{"leisure", "leisure"},
+	{"healthcare", "health"},
+	{"office", "office"},
+	{"craft", "craft"},
+	{"historic", "historic"},
+}
+
+// categoryByTag and categoryByKeyword look up categories by key=value tag
+// and by query word
+var categoryByTag, categoryByKeyword = buildCategoryIndexes()
+
+func buildCategoryIndexes() (map[string]Category, map[string]Category) {
+	byTag := make(map[string]Category)
+	byKeyword := make(map[string]Category)
+	for _, rule := range categoryRules {
+		byTag[rule.key+"="+rule.value] = rule.category
+		name := string(rule.category[strings.LastIndex(string(rule.category), ".")+1:])
+		for _, keyword := range append([]string{strings.ReplaceAll(name, "_", " ")}, rule.keywords...) {
+			if _, exists := byKeyword[keyword]; !exists {
+				byKeyword[keyword] = rule.category
+			}
+		}
+	}
+	for _, g := range fallbackGroups {
+		if _, exists := byKeyword[string(g.group)]; !exists {
+			byKeyword[string(g.group)] = g.group
+		}
+	}
+	return byTag, byKeyword
+}
+
+// classify returns the category of a feature and whether it is a POI.
+// Features only matching a fallback group need a name to count as a POI, so
+// that benches and waste baskets are not indexed.
+func classify(tags osm.Tags) (Category, bool) {
+	for _, tag := range tags {
+		if category, ok := categoryByTag[tag.Key+"="+tag.Value]; ok {
+			return category, true
+		}
+	}
+	if tags.Get("name") == "" {
+		return "", false
+	}
+	for _, g := range fallbackGroups {
+		if value := tags.Get(g.key); value != "" && value != "yes" && value != "no" {
+			return g.group + "." + Category(value), true
+		}
+	}
+	return "", false
+}
+
+// CategoryFor returns the category named by a query word such as "pharmacy",
+// "farmacia" or "gas station", in singular or plural
+func CategoryFor(text string) (Category, bool) {
+	keyword := foldName(strings.ReplaceAll(text, "_", " "))
+	if category, ok := categoryByKeyword[keyword]; ok {
+		return category, true
+	}
+	// Plurals: pharmacies, churches, banks
+	for _, plural := range []struct{ suffix, singular string }{{"ies", "y"}, {"es", ""}, {"s", ""}} {
+		if stem, found := strings.CutSuffix(keyword, plural.suffix); found {
+			if category, ok := categoryByKeyword[stem+plural.singular]; ok {
+				return category, true
+			}
+		}
+	}
+	return "", false
+}
@@ -24,3 +24,43 @@ func HaversineDistance(c1, c2 Coord) float64 {
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 	return R * c
 }
+
+// boundsDistance returns the shortest great-circle distance in km from c to
+// any point inside b, or 0 if c lies within b
+func boundsDistance(b Bounds, c Coord) float64 {
+	if c.Lat >= b.MinLat && c.Lat <= b.MaxLat && c.Lon >= b.MinLon && c.Lon <= b.MaxLon {
+		return 0
+	}
+	if c.Lon >= b.MinLon && c.Lon <= b.MaxLon {
+		// Same meridian range, so the closest point is straight north or south
+		return HaversineDistance(c, Coord{Lat: math.Max(b.MinLat, math.Min(c.Lat, b.MaxLat)), Lon: c.Lon})
+	}
+
+	// Otherwise the closest point lies on the nearer of the two meridian edges
+	edgeLon := b.MinLon
+	if math.Abs(lonDiff(c.Lon, b.MaxLon)) < math.Abs(lonDiff(c.Lon, b.MinLon)) {
+		edgeLon = b.MaxLon
+	}
+	best := math.Min(
+		HaversineDistance(c, Coord{Lat: b.MinLat, Lon: edgeLon}),
+		HaversineDistance(c, Coord{Lat: b.MaxLat, Lon: edgeLon}),
+	)
+	// The foot of the perpendicular from c onto the edge meridian
+	dLon := degreesToRadians(lonDiff(c.Lon, edgeLon))
+	if math.Cos(dLon) > 0 {
+		footLat := radiansToDegrees(math.Atan(math.Tan(degreesToRadians(c.Lat)) / math.Cos(dLon)))
+		if footLat > b.MinLat && footLat < b.MaxLat {
+			best = math.Min(best, HaversineDistance(c, Coord{Lat: footLat, Lon: edgeLon}))
+		}
+	}
+	return best
+}
+
+// lonDiff returns the signed longitude difference a-b wrapped to [-180, 180)
+func lonDiff(a, b float64) float64 {
+	d := math.Mod(a-b+180, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d - 180
+}
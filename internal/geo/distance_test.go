@@ -20,3 +20,25 @@ func TestHaversineDistance(t *testing.T) {
 		t.Fatalf("Expected 140.447268 km, got %f", distance)
 	}
 }
+
+func TestBoundsDistance(t *testing.T) {
+	b := Bounds{MinLat: 42, MaxLat: 43, MinLon: 1, MaxLon: 2}
+
+	if d := boundsDistance(b, Coord{Lat: 42.5, Lon: 1.5}); d != 0 {
+		t.Errorf("Expected 0 for a point inside, got %f", d)
+	}
+
+	north := Coord{Lat: 44, Lon: 1.5}
+	if d, want := boundsDistance(b, north), HaversineDistance(north, Coord{Lat: 43, Lon: 1.5}); math.Abs(d-want) > epsilon {
+		t.Errorf("Expected %f for a point due north, got %f", want, d)
+	}
+
+	// The bound must never exceed the distance to any corner or edge point
+	west := Coord{Lat: 42.5, Lon: 0}
+	d := boundsDistance(b, west)
+	for _, c := range []Coord{{Lat: 42, Lon: 1}, {Lat: 43, Lon: 1}, {Lat: 42.5, Lon: 1}} {
+		if d > HaversineDistance(west, c)+epsilon {
+			t.Errorf("Bound %f exceeds distance to %v", d, c)
+		}
+	}
+}
@@ -4,163 +4,127 @@ package geo
 import (
 	"fmt"
 	"math"
-	"strconv"
-	"strings"
-	"unicode"
+
+	"github.com/sebastiaanwouters/geodude/internal/address"
+)
+
+// Layer identifies the kind of feature a geocoding result refers to
+type Layer string
+
+const (
+	LayerAddress       Layer = "address"       // A house number
+	LayerStreet        Layer = "street"        // A named street without house number
+	LayerNeighbourhood Layer = "neighbourhood" // A suburb or neighbourhood
+	LayerLocality      Layer = "locality"      // A city, town or village
+	LayerAdmin         Layer = "admin"         // A larger administrative area such as a region or country
+	LayerPOI           Layer = "poi"           // A point of interest such as a shop or pharmacy
 )
 
 type GeocodeResult struct {
 	Address
-	Distance float64
+	Name       string // Name of the matched feature for results other than addresses
+	Names      Names  // Variants of Name
+	Layer      Layer
+	Distance   float64 // Distance in km from the query or focus point, if any
+	Score      float64 // Text match quality between 0 and 1, set by text queries
+	Importance float64 // Prominence of the feature between 0 and 1
+	Confidence float64 // Overall rank between 0 and 1, set by ranked queries
+	Extent     *Bounds // Bounding box of the matched feature, set for streets and areas
+	POI        *POI    // The matched POI for results in LayerPOI
+	Place      *Place  // The matched place for results from place tags
+}
+
+// addressResult describes an address as a geocoding result
+func addressResult(addr *Address) GeocodeResult {
+	return GeocodeResult{Address: *addr, Layer: LayerAddress, Importance: addressImportance}
 }
 
 func (idx *GeoIndex) Geocode(street, houseNumber, postcode string) (*GeocodeResult, error) {
+	// Without a house number the street itself is the best answer
+	if houseNumber == "" {
+		if result := idx.geocodeStreet(street, ""); result != nil {
+			return result, nil
+		}
+	}
+
 	// Try exact match first
 	key := makeAddressKey(street, houseNumber, postcode)
 	if addr, exists := idx.Addresses[key]; exists {
-		return &GeocodeResult{
-			Address:  *addr,
-			Distance: 0,
-		}, nil
+		result := addressResult(addr)
+		result.Score = 1
+		return &result, nil
 	}
 
 	// Try interpolation
-	streetKey := makeStreetKey(street, postcode)
-	if range_, exists := idx.AddressRanges[streetKey]; exists {
-		if addr := range_.Interpolate(houseNumber); addr != nil {
-			return &GeocodeResult{
-				Address:  *addr,
-				Distance: 0,
-			}, nil
-		}
+	if addr := idx.interpolate(street, houseNumber, postcode); addr != nil {
+		result := addressResult(addr)
+		result.Score = 1
+		return &result, nil
 	}
 
 	// Fuzzy search
 	return idx.fuzzySearch(street, houseNumber, postcode)
 }
 
-func (idx *GeoIndex) ReverseGeocode(lat, lon float64) (*GeocodeResult, error) {
-	bounds := Bounds{
-		MinLat: lat - 0.001,
-		MaxLat: lat + 0.001,
-		MinLon: lon - 0.001,
-		MaxLon: lon + 0.001,
-	}
+// fuzzyThreshold is the similarity the street and postcode of an address
+// need for fuzzySearch to consider it
+const fuzzyThreshold = 0.55
 
-	points := idx.StreetIndex.Query(bounds)
-	if len(points) == 0 {
-		return nil, nil
-	}
-
-	// Find closest point
-	var closest *GeocodeResult
-	minDist := 1000.0 // Initialize with large value
-
-	for _, p := range points {
-		if addr, ok := p.Data.(*Address); ok {
-			dist := HaversineDistance(
-				Coord{Lat: lat, Lon: lon},
-				Coord{Lat: addr.Lat, Lon: addr.Lon},
-			)
-			if dist < minDist {
-				minDist = dist
-				closest = &GeocodeResult{
-					Address:  *addr,
-					Distance: dist,
-				}
-			}
-		}
+func (idx *GeoIndex) fuzzySearch(street, houseNumber, postcode string) (*GeocodeResult, error) {
+	results := idx.fuzzyCandidates(street, houseNumber, postcode)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no matching address found")
 	}
-
-	return closest, nil
+	return &rank(results, Bias{}, 1)[0], nil
 }
 
-func (idx *GeoIndex) fuzzySearch(street, houseNumber, postcode string) (*GeocodeResult, error) {
-	// Normalize input
+// fuzzyCandidates returns the addresses on streets named like street whose
+// postcode resembles postcode, scored by how well they match, the nearest
+// house numbers scoring highest
+func (idx *GeoIndex) fuzzyCandidates(street, houseNumber, postcode string) []GeocodeResult {
+	if _, ok := address.ParseHouseNumber(houseNumber); !ok {
+		return nil
+	}
 	normalizedStreet := normalizeString(street)
 	normalizedPostcode := normalizeString(postcode)
-
-	var bestMatch *GeocodeResult
-	minDistance := math.MaxFloat64
-	threshold := 0.55 // Similarity threshold
-
-	// Search through all addresses
-	for key, addr := range idx.Addresses {
-		// Split key back into components
-		parts := strings.Split(key, ":")
-		if len(parts) != 3 {
+	q := parsedQuery{street: street, houseNumber: houseNumber, postcode: postcode}
+
+	var results []GeocodeResult
+	// Only score addresses on streets with similar names
+	for _, addr := range idx.textIndex().candidateAddresses(street) {
+		streetSimilarity := 0.0
+		for _, name := range withVariants(addr.Street, addr.StreetNames) {
+			streetSimilarity = math.Max(streetSimilarity, calculateSimilarity(normalizedStreet, normalizeString(name)))
+		}
+		postcodeSimilarity := calculateSimilarity(normalizedPostcode, normalizeString(addr.PostCode))
+		if streetSimilarity <= fuzzyThreshold || postcodeSimilarity <= fuzzyThreshold {
 			continue
 		}
-		streetPart := parts[0]
-		postcodePart := parts[2]
-
-		// Calculate string similarity
-		streetSimilarity := calculateSimilarity(normalizedStreet, normalizeString(streetPart))
-		postcodeSimilarity := calculateSimilarity(normalizedPostcode, normalizeString(postcodePart))
-
-		// If similarity is above threshold, consider this address
-		if streetSimilarity > threshold && postcodeSimilarity > threshold {
-			// Convert house numbers to integers for comparison
-			targetNum, err := strconv.Atoi(houseNumber)
-			if err != nil {
-				continue
-			}
-			currentNum, err := strconv.Atoi(addr.HouseNumber)
-			if err != nil {
-				continue
-			}
-
-			// Calculate numeric distance between house numbers
-			numericDistance := math.Abs(float64(targetNum - currentNum))
-
-			// Combine string similarity and numeric distance into a single score
-			totalDistance := (1-streetSimilarity)*10 + (1-postcodeSimilarity)*5 + numericDistance*0.1
-
-			if totalDistance < minDistance {
-				minDistance = totalDistance
-				bestMatch = &GeocodeResult{
-					Address:  *addr,
-					Distance: totalDistance,
-				}
-			}
+		house, ok := houseNumberScore(houseNumber, addr.HouseNumber)
+		if !ok {
+			continue
 		}
+		result := addressResult(addr)
+		result.Score = componentScore(q, streetSimilarity, house, 0, postcodeSimilarity)
+		results = append(results, result)
 	}
-
-	if bestMatch == nil {
-		return nil, fmt.Errorf("no matching address found")
-	}
-
-	return bestMatch, nil
-}
-
-// Helper functions for fuzzy search
-func normalizeString(s string) string {
-	// Convert to lowercase and remove common prefixes/suffixes
-	s = strings.ToLower(s)
-	s = strings.TrimSpace(s)
-
-	// Remove special characters
-	s = strings.Map(func(r rune) rune {
-		if unicode.IsLetter(r) || unicode.IsNumber(r) {
-			return r
-		}
-		return -1
-	}, s)
-
-	return s
+	return results
 }
 
+// calculateSimilarity returns one minus the edit distance of two normalized
+// strings relative to the longer one
 func calculateSimilarity(s1, s2 string) float64 {
-	// Use Levenshtein distance for string similarity
-	distance := levenshteinDistance(s1, s2)
-	maxLen := math.Max(float64(len(s1)), float64(len(s2)))
+	r1, r2 := []rune(s1), []rune(s2)
+	maxLen := math.Max(float64(len(r1)), float64(len(r2)))
 	if maxLen == 0 {
 		return 1.0
 	}
-	return 1.0 - float64(distance)/maxLen
+	return 1.0 - float64(levenshteinDistance(r1, r2))/maxLen
 }
 
-func levenshteinDistance(s1, s2 string) int {
+// levenshteinDistance counts the insertions, deletions and substitutions of
+// characters needed to turn s1 into s2
+func levenshteinDistance(s1, s2 []rune) int {
 	if len(s1) == 0 {
 		return len(s2)
 	}
@@ -168,36 +132,30 @@ func levenshteinDistance(s1, s2 string) int {
 		return len(s1)
 	}
 
-	// Create matrix
-	matrix := make([][]int, len(s1)+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len(s2)+1)
-	}
-
-	// Initialize first row and column
-	for i := 0; i <= len(s1); i++ {
-		matrix[i][0] = i
-	}
-	for j := 0; j <= len(s2); j++ {
-		matrix[0][j] = j
+	// Only the previous row of the matrix is needed
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j
 	}
 
-	// Fill in the rest of the matrix
 	for i := 1; i <= len(s1); i++ {
+		curr[0] = i
 		for j := 1; j <= len(s2); j++ {
 			if s1[i-1] == s2[j-1] {
-				matrix[i][j] = matrix[i-1][j-1]
+				curr[j] = prev[j-1]
 			} else {
-				matrix[i][j] = min(
-					matrix[i-1][j]+1,   // deletion
-					matrix[i][j-1]+1,   // insertion
-					matrix[i-1][j-1]+1, // substitution
+				curr[j] = min(
+					prev[j]+1,   // deletion
+					curr[j-1]+1, // insertion
+					prev[j-1]+1, // substitution
 				)
 			}
 		}
+		prev, curr = curr, prev
 	}
 
-	return matrix[len(s1)][len(s2)]
+	return prev[len(s2)]
 }
 
 func min(numbers ...int) int {
@@ -196,11 +196,14 @@ func TestGeocoder_Integration(t *testing.T) {
 				maxDistance: 0.2,
 			},
 			{
+				// The closest address is returned however far away it is
 				name:        "Far location",
 				lat:         0.0,
 				lon:         0.0,
-				wantAddr:    false,
-				maxDistance: 0.0,
+				wantAddr:    true,
+				wantStreet:  "Main Street",
+				wantNumber:  "10",
+				maxDistance: 8500,
 			},
 		}
 
@@ -3,6 +3,8 @@ package geo
 import (
 	"fmt"
 	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
 )
 
 func TestAddressRange_Interpolate(t *testing.T) {
@@ -74,3 +76,44 @@ func TestGeoIndex_FuzzySearch(t *testing.T) {
 		})
 	}
 }
+
+func TestGeocode_HouseNumbers(t *testing.T) {
+	b := NewGeoBuilder()
+	numbers := []string{"12A", "12-16", "7 bis", "S/N", "20/3"}
+	for i, n := range numbers {
+		b.ProcessNode(&osm.Node{ID: osm.ID(i + 1), Lat: 42, Lon: 1 + float64(i)/1000, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: n},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}})
+	}
+	idx := b.GetIndex()
+
+	tests := []struct {
+		query, want string
+	}{
+		{"12a", "12A"},
+		{"12 A", "12A"},
+		{"12-16", "12-16"},
+		{"14", "12-16"},
+		{"7bis", "7 bis"},
+		{"7", "7 bis"},
+		{"s/n", "S/N"},
+		{"20/3", "20/3"},
+		{"21", "20/3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result, err := idx.Geocode("Carrer Major", tt.query, "")
+			if err != nil || result.HouseNumber != tt.want {
+				t.Errorf("Geocode() = %+v, %v, want %s", result, err, tt.want)
+			}
+		})
+	}
+
+	if results := idx.Search("Carrer Major 7", 1); len(results) != 1 || results[0].HouseNumber != "7 bis" {
+		t.Errorf("Expected 7 bis for 7, got %+v", results)
+	}
+	if score, _ := houseNumberScore("13", "12-16"); score >= suffixHouseScore {
+		t.Errorf("Expected 13 to score low for the even range 12-16, got %f", score)
+	}
+}
@@ -0,0 +1,375 @@
+// internal/geo/geometry.go
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// Geometry is a shape with a bounding box that can be stored in an RTree
+type Geometry interface {
+	Bounds() Bounds
+	// Contains reports whether c lies inside the geometry. Only areal
+	// geometries contain points.
+	Contains(c Coord) bool
+	// Distance returns the distance in km from c to the closest point of the
+	// geometry, or 0 if c is contained in it
+	Distance(c Coord) float64
+}
+
+// LineString is an ordered sequence of coordinates, e.g. a street
+type LineString []Coord
+
+// Ring is a closed sequence of coordinates whose first and last element are
+// equal
+type Ring []Coord
+
+// Polygon is an outer ring followed by zero or more inner rings (holes)
+type Polygon []Ring
+
+// MultiPolygon is a set of disjoint polygons
+type MultiPolygon []Polygon
+
+// emptyBounds returns bounds that contain nothing and act as the identity for Union
+func emptyBounds() Bounds {
+	return Bounds{
+		MinLat: math.Inf(1),
+		MaxLat: math.Inf(-1),
+		MinLon: math.Inf(1),
+		MaxLon: math.Inf(-1),
+	}
+}
+
+// IsEmpty reports whether the bounds contain no area or point at all
+func (b Bounds) IsEmpty() bool {
+	return b.MinLat > b.MaxLat || b.MinLon > b.MaxLon
+}
+
+// Extend returns the bounds grown to include c
+func (b Bounds) Extend(c Coord) Bounds {
+	return Bounds{
+		MinLat: math.Min(b.MinLat, c.Lat),
+		MaxLat: math.Max(b.MaxLat, c.Lat),
+		MinLon: math.Min(b.MinLon, c.Lon),
+		MaxLon: math.Max(b.MaxLon, c.Lon),
+	}
+}
+
+// Union returns the smallest bounds containing both b and other
+func (b Bounds) Union(other Bounds) Bounds {
+	return Bounds{
+		MinLat: math.Min(b.MinLat, other.MinLat),
+		MaxLat: math.Max(b.MaxLat, other.MaxLat),
+		MinLon: math.Min(b.MinLon, other.MinLon),
+		MaxLon: math.Max(b.MaxLon, other.MaxLon),
+	}
+}
+
+// ContainsCoord checks if a coordinate is within the bounds
+func (b Bounds) ContainsCoord(c Coord) bool {
+	return c.Lat >= b.MinLat && c.Lat <= b.MaxLat && c.Lon >= b.MinLon && c.Lon <= b.MaxLon
+}
+
+// area returns the area of the bounds in square degrees
+func (b Bounds) area() float64 {
+	if b.IsEmpty() {
+		return 0
+	}
+	return (b.MaxLat - b.MinLat) * (b.MaxLon - b.MinLon)
+}
+
+func coordsBounds(coords []Coord) Bounds {
+	b := emptyBounds()
+	for _, c := range coords {
+		b = b.Extend(c)
+	}
+	return b
+}
+
+// Bounds returns a zero-area box around the coordinate
+func (c Coord) Bounds() Bounds {
+	return Bounds{MinLat: c.Lat, MaxLat: c.Lat, MinLon: c.Lon, MaxLon: c.Lon}
+}
+
+// Contains reports whether other is the same location
+func (c Coord) Contains(other Coord) bool {
+	return c == other
+}
+
+// Distance returns the haversine distance to other
+func (c Coord) Distance(other Coord) float64 {
+	return HaversineDistance(c, other)
+}
+
+func (l LineString) Bounds() Bounds {
+	return coordsBounds(l)
+}
+
+// Contains always returns false since lines have no interior
+func (l LineString) Contains(c Coord) bool {
+	return false
+}
+
+func (l LineString) Distance(c Coord) float64 {
+	_, dist := l.ClosestPoint(c)
+	return dist
+}
+
+// ClosestPoint returns the point on the line closest to c and its distance in km
+func (l LineString) ClosestPoint(c Coord) (Coord, float64) {
+	switch len(l) {
+	case 0:
+		return Coord{}, math.Inf(1)
+	case 1:
+		return l[0], HaversineDistance(c, l[0])
+	}
+
+	best, bestDist := l[0], math.Inf(1)
+	for i := 0; i < len(l)-1; i++ {
+		p := closestOnSegment(c, l[i], l[i+1])
+		if dist := HaversineDistance(c, p); dist < bestDist {
+			best, bestDist = p, dist
+		}
+	}
+	return best, bestDist
+}
+
+// Length returns the length of the line in km
+func (l LineString) Length() float64 {
+	var length float64
+	for i := 0; i < len(l)-1; i++ {
+		length += HaversineDistance(l[i], l[i+1])
+	}
+	return length
+}
+
+// closestOnSegment projects c onto the segment a-b using a local
+// equirectangular approximation, which is accurate for street-scale segments
+func closestOnSegment(c, a, b Coord) Coord {
+	scale := math.Cos(degreesToRadians(c.Lat))
+	ax, ay := lonDiff(a.Lon, c.Lon)*scale, a.Lat-c.Lat
+	bx, by := lonDiff(b.Lon, c.Lon)*scale, b.Lat-c.Lat
+
+	dx, dy := bx-ax, by-ay
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return a
+	}
+	t := -(ax*dx + ay*dy) / lenSq
+	t = math.Max(0, math.Min(1, t))
+	return Coord{Lat: a.Lat + (b.Lat-a.Lat)*t, Lon: a.Lon + (b.Lon-a.Lon)*t}
+}
+
+func (r Ring) Bounds() Bounds {
+	return coordsBounds(r)
+}
+
+// Contains uses the even-odd rule, so points on the boundary may fall either way
+func (r Ring) Contains(c Coord) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > c.Lat) != (b.Lat > c.Lat) &&
+			c.Lon < (b.Lon-a.Lon)*(c.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (r Ring) Distance(c Coord) float64 {
+	if r.Contains(c) {
+		return 0
+	}
+	return LineString(r).Distance(c)
+}
+
+func (p Polygon) Bounds() Bounds {
+	if len(p) == 0 {
+		return emptyBounds()
+	}
+	return p[0].Bounds()
+}
+
+func (p Polygon) Contains(c Coord) bool {
+	if len(p) == 0 || !p[0].Contains(c) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Polygon) Distance(c Coord) float64 {
+	if p.Contains(c) {
+		return 0
+	}
+	dist := math.Inf(1)
+	for _, ring := range p {
+		dist = math.Min(dist, LineString(ring).Distance(c))
+	}
+	return dist
+}
+
+func (m MultiPolygon) Bounds() Bounds {
+	b := emptyBounds()
+	for _, p := range m {
+		b = b.Union(p.Bounds())
+	}
+	return b
+}
+
+func (m MultiPolygon) Contains(c Coord) bool {
+	for _, p := range m {
+		if p.Contains(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MultiPolygon) Distance(c Coord) float64 {
+	dist := math.Inf(1)
+	for _, p := range m {
+		dist = math.Min(dist, p.Distance(c))
+	}
+	return dist
+}
+
+// Centroid returns the area-weighted centroid of the ring
+func (r Ring) Centroid() Coord {
+	var area, lat, lon float64
+	for i := 0; i < len(r)-1; i++ {
+		cross := r[i].Lon*r[i+1].Lat - r[i+1].Lon*r[i].Lat
+		area += cross
+		lon += (r[i].Lon + r[i+1].Lon) * cross
+		lat += (r[i].Lat + r[i+1].Lat) * cross
+	}
+	if area == 0 {
+		// Degenerate ring, fall back to the box center
+		centerLat, centerLon := r.Bounds().Center()
+		return Coord{Lat: centerLat, Lon: centerLon}
+	}
+	return Coord{Lat: lat / (3 * area), Lon: lon / (3 * area)}
+}
+
+// PointOnSurface returns a point guaranteed to lie inside the largest polygon:
+// its centroid when that is inside, otherwise the middle of the widest
+// horizontal span through the polygon
+func (m MultiPolygon) PointOnSurface() Coord {
+	var largest Polygon
+	largestArea := -1.0
+	for _, p := range m {
+		if len(p) == 0 {
+			continue
+		}
+		if area := math.Abs(signedArea(p[0])); area > largestArea {
+			largest, largestArea = p, area
+		}
+	}
+	if largest == nil {
+		return Coord{}
+	}
+
+	centroid := largest[0].Centroid()
+	if largest.Contains(centroid) {
+		return centroid
+	}
+
+	bounds := largest.Bounds()
+	for _, lat := range []float64{centroid.Lat, (bounds.MinLat + bounds.MaxLat) / 2} {
+		if c, ok := widestSpanMidpoint(largest, lat); ok {
+			return c
+		}
+	}
+	return largest[0][0]
+}
+
+// widestSpanMidpoint intersects the polygon with the parallel at lat and
+// returns the middle of the widest inside span
+func widestSpanMidpoint(p Polygon, lat float64) (Coord, bool) {
+	var crossings []float64
+	for _, ring := range p {
+		for i := 0; i < len(ring)-1; i++ {
+			a, b := ring[i], ring[i+1]
+			if (a.Lat > lat) != (b.Lat > lat) {
+				crossings = append(crossings, a.Lon+(lat-a.Lat)*(b.Lon-a.Lon)/(b.Lat-a.Lat))
+			}
+		}
+	}
+	sort.Float64s(crossings)
+
+	best, bestWidth := Coord{}, 0.0
+	for i := 0; i+1 < len(crossings); i += 2 {
+		if width := crossings[i+1] - crossings[i]; width > bestWidth {
+			best, bestWidth = Coord{Lat: lat, Lon: (crossings[i] + crossings[i+1]) / 2}, width
+		}
+	}
+	return best, bestWidth > 0
+}
+
+// Interpolate returns the point at the given fraction (0 to 1) of the line's
+// length
+func (l LineString) Interpolate(fraction float64) Coord {
+	if len(l) == 0 {
+		return Coord{}
+	}
+	target := math.Max(0, math.Min(1, fraction)) * l.Length()
+	for i := 0; i < len(l)-1; i++ {
+		segment := HaversineDistance(l[i], l[i+1])
+		if segment > 0 && target <= segment {
+			t := target / segment
+			return Coord{
+				Lat: l[i].Lat + (l[i+1].Lat-l[i].Lat)*t,
+				Lon: l[i].Lon + (l[i+1].Lon-l[i].Lon)*t,
+			}
+		}
+		target -= segment
+	}
+	return l[len(l)-1]
+}
+
+// MultiLineString is a set of lines, e.g. all pieces of one street
+type MultiLineString []LineString
+
+func (m MultiLineString) Bounds() Bounds {
+	b := emptyBounds()
+	for _, l := range m {
+		b = b.Union(l.Bounds())
+	}
+	return b
+}
+
+// Contains always returns false since lines have no interior
+func (m MultiLineString) Contains(c Coord) bool {
+	return false
+}
+
+func (m MultiLineString) Distance(c Coord) float64 {
+	_, dist := m.ClosestPoint(c)
+	return dist
+}
+
+// ClosestPoint returns the point on any of the lines closest to c and its
+// distance in km
+func (m MultiLineString) ClosestPoint(c Coord) (Coord, float64) {
+	best, bestDist := Coord{}, math.Inf(1)
+	for _, l := range m {
+		if p, dist := l.ClosestPoint(c); dist < bestDist {
+			best, bestDist = p, dist
+		}
+	}
+	return best, bestDist
+}
+
+// Length returns the total length of all lines in km
+func (m MultiLineString) Length() float64 {
+	var length float64
+	for _, l := range m {
+		length += l.Length()
+	}
+	return length
+}
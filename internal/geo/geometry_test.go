@@ -0,0 +1,81 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func square(minLat, minLon, size float64) Ring {
+	return Ring{
+		{Lat: minLat, Lon: minLon},
+		{Lat: minLat, Lon: minLon + size},
+		{Lat: minLat + size, Lon: minLon + size},
+		{Lat: minLat + size, Lon: minLon},
+		{Lat: minLat, Lon: minLon},
+	}
+}
+
+func TestPolygon_Contains(t *testing.T) {
+	poly := Polygon{square(42, 1, 1), square(42.4, 1.4, 0.2)}
+
+	tests := []struct {
+		name string
+		c    Coord
+		want bool
+	}{
+		{"Inside outer ring", Coord{Lat: 42.1, Lon: 1.1}, true},
+		{"Inside hole", Coord{Lat: 42.5, Lon: 1.5}, false},
+		{"Outside", Coord{Lat: 43.5, Lon: 1.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := poly.Contains(tt.c); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.c, got, tt.want)
+			}
+		})
+	}
+
+	if d := poly.Distance(Coord{Lat: 42.5, Lon: 1.5}); d <= 0 {
+		t.Errorf("Expected positive distance from inside a hole, got %f", d)
+	}
+}
+
+func TestLineString_Distance(t *testing.T) {
+	line := LineString{{Lat: 42, Lon: 1}, {Lat: 42, Lon: 2}}
+
+	// A point north of the middle of the segment is closest to the interior, not the ends
+	c := Coord{Lat: 42.01, Lon: 1.5}
+	p, dist := line.ClosestPoint(c)
+	if math.Abs(p.Lon-1.5) > 1e-6 {
+		t.Errorf("Expected closest point at lon 1.5, got %v", p)
+	}
+	if want := HaversineDistance(c, Coord{Lat: 42, Lon: 1.5}); math.Abs(dist-want) > 1e-6 {
+		t.Errorf("Distance = %f, want %f", dist, want)
+	}
+
+	if line.Contains(c) {
+		t.Error("Lines should never contain points")
+	}
+	if l := line.Length(); math.Abs(l-HaversineDistance(line[0], line[1])) > 1e-9 {
+		t.Errorf("Unexpected length %f", l)
+	}
+}
+
+func TestMultiPolygon_PointOnSurface(t *testing.T) {
+	// A U shape: the centroid falls in the gap between the arms
+	u := MultiPolygon{{Ring{
+		{Lat: 0, Lon: 0}, {Lat: 0, Lon: 3}, {Lat: 3, Lon: 3}, {Lat: 3, Lon: 2},
+		{Lat: 0.5, Lon: 2}, {Lat: 0.5, Lon: 1}, {Lat: 3, Lon: 1}, {Lat: 3, Lon: 0}, {Lat: 0, Lon: 0},
+	}}}
+	if u[0][0].Contains(u[0][0].Centroid()) {
+		t.Fatal("Test shape should have its centroid outside")
+	}
+	if p := u.PointOnSurface(); !u.Contains(p) {
+		t.Errorf("PointOnSurface() = %v is outside the polygon", p)
+	}
+
+	sq := MultiPolygon{{square(42, 1, 1)}}
+	if p := sq.PointOnSurface(); math.Abs(p.Lat-42.5) > 1e-9 || math.Abs(p.Lon-1.5) > 1e-9 {
+		t.Errorf("Expected the centroid of a square, got %v", p)
+	}
+}
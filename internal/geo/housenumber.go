@@ -0,0 +1,60 @@
+// internal/geo/housenumber.go
+package geo
+
+import (
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/address"
+)
+
+// Scores of house numbers that are not the queried one but share its number
+const (
+	rangeHouseScore  = 0.9 // A range such as "12-16" for 14
+	suffixHouseScore = 0.8 // Another suffix or unit, such as "12a" for 12
+)
+
+// houseNumberKey is the form of a house number in address keys, so that
+// "12 A", "12A" and "12a" find the same address
+func houseNumberKey(houseNumber string) string {
+	if h, ok := address.ParseHouseNumber(houseNumber); ok {
+		return h.String()
+	}
+	return strings.ToLower(strings.TrimSpace(houseNumber))
+}
+
+// houseNumberScore scores how well a house number answers a queried one
+// between 0 and 1: the same number scores 1, one sharing its number a bit
+// less, and other numbers less the further away they are. It reports false
+// if either is not a house number.
+func houseNumberScore(query, candidate string) (float64, bool) {
+	q, ok := address.ParseHouseNumber(query)
+	if !ok {
+		return 0, false
+	}
+	c, ok := address.ParseHouseNumber(candidate)
+	if !ok {
+		return 0, false
+	}
+	if q == c {
+		return 1, true
+	}
+	distance, ok := q.Distance(c)
+	if !ok {
+		return 0, true
+	}
+	if q.To == 0 && c.To > 0 {
+		if c.Covers(q.Number) && q.Suffix == "" && q.Unit == "" {
+			return rangeHouseScore, true
+		}
+		if distance == 0 {
+			// Within the range, but on the other side of the street
+			distance = 1
+		}
+	}
+	if distance == 0 {
+		return suffixHouseScore, true
+	}
+	// Another house number on the right street is worth up to half a
+	// matching one
+	return 0.5 / (1 + float64(distance)/10), true
+}
@@ -0,0 +1,415 @@
+// internal/geo/multipolygon.go
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// IssueKind classifies a problem found while assembling an area
+type IssueKind string
+
+const (
+	IssueMissingWay   IssueKind = "missing_way"   // Member way is not in the data
+	IssueMissingNode  IssueKind = "missing_node"  // Way references a node that is not in the data
+	IssueUnclosedRing IssueKind = "unclosed_ring" // Member ways do not join into closed rings
+	IssueDegenerate   IssueKind = "degenerate"    // Ring has fewer than 3 distinct points or no area
+	IssueSelfTouching IssueKind = "self_touching" // Ring visits a node twice and was split
+	IssueRoleMismatch IssueKind = "role_mismatch" // Member role contradicts the ring's nesting
+	IssueNoOuterRing  IssueKind = "no_outer_ring" // Nothing left to build a polygon from
+	IssueInvalidTag   IssueKind = "invalid_tag"   // A tag needed to use the area has an invalid value
+)
+
+// AssemblyIssue describes one problem with an area. Issues returned next to a
+// geometry were repaired or ignored; issues inside an AssemblyError were fatal.
+type AssemblyIssue struct {
+	Kind    IssueKind
+	WayID   osm.ID // Way involved, if any
+	NodeID  osm.ID // Node involved, if any
+	Message string
+}
+
+func (i AssemblyIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Kind, i.Message)
+}
+
+// AssemblyError is returned when an area cannot be turned into a valid geometry
+type AssemblyError struct {
+	ObjectType string // "way" or "relation"
+	ID         osm.ID
+	Issues     []AssemblyIssue
+}
+
+func (e *AssemblyError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return fmt.Sprintf("%s %d: %s", e.ObjectType, e.ID, strings.Join(msgs, "; "))
+}
+
+// AreaAssembler turns closed ways and multipolygon or boundary relations into
+// polygon geometries. Outer rings are returned counter-clockwise and inner
+// rings clockwise.
+type AreaAssembler struct {
+	// Way returns the node IDs of a way
+	Way func(id osm.ID) ([]osm.ID, bool)
+	// Node returns the location of a node
+	Node func(id osm.ID) (Coord, bool)
+}
+
+// memberRing is a ring of node IDs together with the ways and role it came from
+type memberRing struct {
+	nodes  []osm.ID
+	wayIDs []osm.ID
+	role   string
+}
+
+// assembledRing is a ring with coordinates and its nesting information
+type assembledRing struct {
+	memberRing
+	coords Ring
+	bounds Bounds
+	area   float64 // Absolute area in square degrees
+	depth  int     // Number of rings containing this one
+	parent int     // Index of the directly enclosing ring, or -1
+}
+
+// IsArea reports whether a closed way with these tags describes an area
+// rather than a closed line such as a roundabout or a fence
+func IsArea(tags osm.Tags) bool {
+	switch tags.Get("area") {
+	case "yes":
+		return true
+	case "no":
+		return false
+	}
+	for _, key := range []string{"building", "building:part", "landuse", "amenity", "leisure", "place", "shop", "tourism", "boundary"} {
+		if tags.Has(key) {
+			return true
+		}
+	}
+	switch tags.Get("natural") {
+	case "", "coastline", "cliff", "ridge", "arete", "tree_row":
+	default:
+		return true
+	}
+	return tags.Has("addr:housenumber") && !tags.Has("highway") && !tags.Has("barrier")
+}
+
+// AssembleWay builds the geometry of a closed way
+func (a *AreaAssembler) AssembleWay(way *osm.Way) (MultiPolygon, []AssemblyIssue, error) {
+	fail := func(issues ...AssemblyIssue) (MultiPolygon, []AssemblyIssue, error) {
+		return nil, nil, &AssemblyError{ObjectType: "way", ID: way.ID, Issues: issues}
+	}
+
+	if len(way.Nodes) < 4 || way.Nodes[0] != way.Nodes[len(way.Nodes)-1] {
+		return fail(AssemblyIssue{
+			Kind:    IssueUnclosedRing,
+			WayID:   way.ID,
+			Message: fmt.Sprintf("way %d is not closed", way.ID),
+		})
+	}
+
+	rings := []memberRing{{nodes: way.Nodes, wayIDs: []osm.ID{way.ID}}}
+	return a.build("way", way.ID, rings)
+}
+
+// AssembleRelation builds the geometry of a multipolygon or boundary
+// relation from its member ways. Member roles are only used as a hint; inner
+// and outer rings are classified by how they nest.
+func (a *AreaAssembler) AssembleRelation(relation *osm.Relation) (MultiPolygon, []AssemblyIssue, error) {
+	var fatal []AssemblyIssue
+	var ways [][]osm.ID
+	var wayIDs []osm.ID
+	var roles []string
+
+	for _, m := range relation.Members {
+		if m.Type != "way" {
+			continue
+		}
+		nodes, exists := a.Way(m.Ref)
+		if !exists {
+			fatal = append(fatal, AssemblyIssue{
+				Kind:    IssueMissingWay,
+				WayID:   m.Ref,
+				Message: fmt.Sprintf("member way %d not found", m.Ref),
+			})
+			continue
+		}
+		if len(nodes) < 2 {
+			continue
+		}
+		ways = append(ways, nodes)
+		wayIDs = append(wayIDs, m.Ref)
+		roles = append(roles, m.Role)
+	}
+	if len(fatal) > 0 {
+		return nil, nil, &AssemblyError{ObjectType: "relation", ID: relation.ID, Issues: fatal}
+	}
+
+	rings, stitchIssues := stitchMemberRings(ways, wayIDs, roles)
+	if len(stitchIssues) > 0 {
+		return nil, nil, &AssemblyError{ObjectType: "relation", ID: relation.ID, Issues: stitchIssues}
+	}
+	return a.build("relation", relation.ID, rings)
+}
+
+// build resolves coordinates, repairs and classifies rings and groups them
+// into polygons
+func (a *AreaAssembler) build(objectType string, id osm.ID, rings []memberRing) (MultiPolygon, []AssemblyIssue, error) {
+	var issues []AssemblyIssue
+	fail := func(fatal ...AssemblyIssue) (MultiPolygon, []AssemblyIssue, error) {
+		return nil, nil, &AssemblyError{ObjectType: objectType, ID: id, Issues: fatal}
+	}
+
+	// Split rings that touch themselves into simple rings
+	var simple []memberRing
+	for _, r := range rings {
+		parts, touching := splitSelfTouching(r.nodes)
+		for _, node := range touching {
+			issues = append(issues, AssemblyIssue{
+				Kind:    IssueSelfTouching,
+				NodeID:  node,
+				Message: fmt.Sprintf("ring touches itself at node %d and was split", node),
+			})
+		}
+		for _, p := range parts {
+			simple = append(simple, memberRing{nodes: p, wayIDs: r.wayIDs, role: r.role})
+		}
+	}
+
+	var resolved []*assembledRing
+	var fatal []AssemblyIssue
+	for _, r := range simple {
+		coords := make(Ring, 0, len(r.nodes))
+		for _, nodeID := range r.nodes {
+			c, exists := a.Node(nodeID)
+			if !exists {
+				fatal = append(fatal, AssemblyIssue{
+					Kind:    IssueMissingNode,
+					NodeID:  nodeID,
+					Message: fmt.Sprintf("node %d not found", nodeID),
+				})
+				break
+			}
+			coords = append(coords, c)
+		}
+		if len(coords) != len(r.nodes) {
+			continue
+		}
+
+		area := signedArea(coords)
+		if len(coords) < 4 || area == 0 {
+			issues = append(issues, AssemblyIssue{
+				Kind:    IssueDegenerate,
+				NodeID:  r.nodes[0],
+				Message: fmt.Sprintf("ring starting at node %d has no area and was dropped", r.nodes[0]),
+			})
+			continue
+		}
+		resolved = append(resolved, &assembledRing{
+			memberRing: r,
+			coords:     coords,
+			bounds:     coords.Bounds(),
+			area:       math.Abs(area),
+			parent:     -1,
+		})
+	}
+	if len(fatal) > 0 {
+		return fail(fatal...)
+	}
+	if len(resolved) == 0 {
+		return fail(AssemblyIssue{Kind: IssueNoOuterRing, Message: "no valid rings"})
+	}
+
+	classifyRings(resolved)
+
+	// Even depth rings are outers, odd depth rings are holes of their parent
+	var geometry MultiPolygon
+	polygonOf := make(map[int]int)
+	for i, r := range resolved {
+		if r.depth%2 != 0 {
+			continue
+		}
+		if r.role == "inner" {
+			issues = append(issues, AssemblyIssue{
+				Kind:    IssueRoleMismatch,
+				WayID:   r.wayIDs[0],
+				Message: fmt.Sprintf("ring with role inner starting at node %d is not inside another ring", r.nodes[0]),
+			})
+		}
+		polygonOf[i] = len(geometry)
+		geometry = append(geometry, Polygon{orient(r.coords, true)})
+	}
+	for _, r := range resolved {
+		if r.depth%2 == 0 {
+			continue
+		}
+		if r.role == "outer" {
+			issues = append(issues, AssemblyIssue{
+				Kind:    IssueRoleMismatch,
+				WayID:   r.wayIDs[0],
+				Message: fmt.Sprintf("ring with role outer starting at node %d lies inside another ring", r.nodes[0]),
+			})
+		}
+		p := polygonOf[r.parent]
+		geometry[p] = append(geometry[p], orient(r.coords, false))
+	}
+
+	return geometry, issues, nil
+}
+
+// stitchMemberRings joins way segments end to end into closed rings. Ways
+// may be listed in any order and direction. Ways with different roles are
+// only joined when nothing else fits.
+func stitchMemberRings(ways [][]osm.ID, wayIDs []osm.ID, roles []string) ([]memberRing, []AssemblyIssue) {
+	used := make([]bool, len(ways))
+	endpoints := make(map[osm.ID][]int)
+	for i, w := range ways {
+		endpoints[w[0]] = append(endpoints[w[0]], i)
+		endpoints[w[len(w)-1]] = append(endpoints[w[len(w)-1]], i)
+	}
+
+	var rings []memberRing
+	var issues []AssemblyIssue
+	for start := range ways {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		ring := memberRing{
+			nodes:  append([]osm.ID(nil), ways[start]...),
+			wayIDs: []osm.ID{wayIDs[start]},
+			role:   roles[start],
+		}
+
+		for ring.nodes[0] != ring.nodes[len(ring.nodes)-1] {
+			end := ring.nodes[len(ring.nodes)-1]
+			next := -1
+			for _, i := range endpoints[end] {
+				if used[i] {
+					continue
+				}
+				if next == -1 || (roles[i] == ring.role && roles[next] != ring.role) {
+					next = i
+				}
+			}
+			if next == -1 {
+				issues = append(issues, AssemblyIssue{
+					Kind:    IssueUnclosedRing,
+					WayID:   ring.wayIDs[len(ring.wayIDs)-1],
+					NodeID:  end,
+					Message: fmt.Sprintf("ring starting at node %d ends at node %d without closing", ring.nodes[0], end),
+				})
+				break
+			}
+
+			used[next] = true
+			way := ways[next]
+			if way[0] == end {
+				ring.nodes = append(ring.nodes, way[1:]...)
+			} else {
+				for j := len(way) - 2; j >= 0; j-- {
+					ring.nodes = append(ring.nodes, way[j])
+				}
+			}
+			ring.wayIDs = append(ring.wayIDs, wayIDs[next])
+		}
+
+		if ring.nodes[0] == ring.nodes[len(ring.nodes)-1] {
+			rings = append(rings, ring)
+		}
+	}
+	return rings, issues
+}
+
+// splitSelfTouching cuts a closed ring at every node it visits more than once,
+// returning the simple rings and the nodes where the ring touched itself
+func splitSelfTouching(nodes []osm.ID) ([][]osm.ID, []osm.ID) {
+	var parts [][]osm.ID
+	var touching []osm.ID
+
+	// Walk the ring keeping a path; when a node repeats, the loop since its
+	// previous visit is cut out as a separate ring
+	path := make([]osm.ID, 0, len(nodes))
+	position := make(map[osm.ID]int, len(nodes))
+	for i, id := range nodes {
+		if prev, seen := position[id]; seen {
+			loop := append(append([]osm.ID(nil), path[prev:]...), id)
+			parts = append(parts, loop)
+			for _, removed := range path[prev+1:] {
+				delete(position, removed)
+			}
+			path = path[:prev+1]
+			if i != len(nodes)-1 || prev != 0 {
+				touching = append(touching, id)
+			}
+			continue
+		}
+		position[id] = len(path)
+		path = append(path, id)
+	}
+	return parts, touching
+}
+
+// classifyRings computes the nesting depth and direct parent of every ring
+func classifyRings(rings []*assembledRing) {
+	for i, inner := range rings {
+		parentArea := math.Inf(1)
+		for j, outer := range rings {
+			if i == j || outer.area <= inner.area || !outer.bounds.Intersects(inner.bounds) {
+				continue
+			}
+			if ringInside(inner.coords, outer.coords) {
+				inner.depth++
+				if outer.area < parentArea {
+					inner.parent, parentArea = j, outer.area
+				}
+			}
+		}
+	}
+}
+
+// ringInside reports whether inner lies inside outer, testing a vertex of
+// inner that is not shared with outer since touching rings are allowed
+func ringInside(inner, outer Ring) bool {
+	shared := make(map[Coord]bool, len(outer))
+	for _, c := range outer {
+		shared[c] = true
+	}
+	for _, c := range inner {
+		if !shared[c] {
+			return outer.Contains(c)
+		}
+	}
+	// All vertices are shared; fall back to the midpoint of the first edge
+	mid := Coord{Lat: (inner[0].Lat + inner[1].Lat) / 2, Lon: (inner[0].Lon + inner[1].Lon) / 2}
+	return outer.Contains(mid)
+}
+
+// signedArea returns the shoelace area of a ring in square degrees, positive
+// for counter-clockwise rings
+func signedArea(r Ring) float64 {
+	var sum float64
+	for i := 0; i < len(r)-1; i++ {
+		sum += r[i].Lon*r[i+1].Lat - r[i+1].Lon*r[i].Lat
+	}
+	return sum / 2
+}
+
+// orient returns the ring in counter-clockwise order if ccw is true and
+// clockwise order otherwise
+func orient(r Ring, ccw bool) Ring {
+	if (signedArea(r) > 0) == ccw {
+		return r
+	}
+	reversed := make(Ring, len(r))
+	for i, c := range r {
+		reversed[len(r)-1-i] = c
+	}
+	return reversed
+}
@@ -0,0 +1,179 @@
+package geo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// testAssembler builds an AreaAssembler over a fixed set of nodes and ways
+func testAssembler(nodes map[osm.ID]Coord, ways map[osm.ID][]osm.ID) *AreaAssembler {
+	return &AreaAssembler{
+		Way: func(id osm.ID) ([]osm.ID, bool) {
+			w, ok := ways[id]
+			return w, ok
+		},
+		Node: func(id osm.ID) (Coord, bool) {
+			c, ok := nodes[id]
+			return c, ok
+		},
+	}
+}
+
+// gridNodes returns nodes 1..16 on a 4x4 grid with 1 degree spacing, numbered
+// row by row from the south-west corner
+func gridNodes() map[osm.ID]Coord {
+	nodes := make(map[osm.ID]Coord)
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			nodes[osm.ID(row*4+col+1)] = Coord{Lat: float64(row), Lon: float64(col)}
+		}
+	}
+	return nodes
+}
+
+func multipolygon(members ...osm.Member) *osm.Relation {
+	return &osm.Relation{
+		ID:      1,
+		Tags:    osm.Tags{{Key: "type", Value: "multipolygon"}},
+		Members: members,
+	}
+}
+
+func TestAssembleRelation_OuterWithHole(t *testing.T) {
+	// Outer ring 1-4-16-13 split over two ways, one drawn backwards; inner ring
+	// 6-7-11-10 drawn counter-clockwise
+	a := testAssembler(gridNodes(), map[osm.ID][]osm.ID{
+		10: {1, 4, 16},
+		11: {1, 13, 16},
+		20: {6, 7, 11, 10, 6},
+	})
+
+	geometry, issues, err := a.AssembleRelation(multipolygon(
+		osm.Member{Type: "way", Ref: 10, Role: "outer"},
+		osm.Member{Type: "way", Ref: 20, Role: "inner"},
+		osm.Member{Type: "way", Ref: 11, Role: "outer"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+	if len(geometry) != 1 || len(geometry[0]) != 2 {
+		t.Fatalf("Expected one polygon with one hole, got %v", geometry)
+	}
+	if signedArea(geometry[0][0]) <= 0 {
+		t.Error("Expected outer ring to be counter-clockwise")
+	}
+	if signedArea(geometry[0][1]) >= 0 {
+		t.Error("Expected inner ring to be clockwise")
+	}
+	if geometry.Contains(Coord{Lat: 1.5, Lon: 1.5}) {
+		t.Error("Point inside the hole should not be contained")
+	}
+	if !geometry.Contains(Coord{Lat: 0.5, Lon: 0.5}) {
+		t.Error("Point between the rings should be contained")
+	}
+}
+
+func TestAssembleRelation_Diagnostics(t *testing.T) {
+	ways := map[osm.ID][]osm.ID{
+		10: {1, 4, 16, 13, 1},
+		11: {6, 7, 11, 10, 6},
+		12: {1, 2, 3},
+	}
+	a := testAssembler(gridNodes(), ways)
+
+	t.Run("Role mismatch is repaired", func(t *testing.T) {
+		geometry, issues, err := a.AssembleRelation(multipolygon(
+			osm.Member{Type: "way", Ref: 10, Role: "outer"},
+			osm.Member{Type: "way", Ref: 11, Role: "outer"},
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(geometry) != 1 || len(geometry[0]) != 2 {
+			t.Errorf("Expected the second ring to become a hole, got %v", geometry)
+		}
+		if len(issues) != 1 || issues[0].Kind != IssueRoleMismatch || issues[0].WayID != 11 {
+			t.Errorf("Expected a role mismatch on way 11, got %v", issues)
+		}
+	})
+
+	t.Run("Unclosed ring", func(t *testing.T) {
+		_, _, err := a.AssembleRelation(multipolygon(osm.Member{Type: "way", Ref: 12, Role: "outer"}))
+		var assemblyErr *AssemblyError
+		if !errors.As(err, &assemblyErr) || assemblyErr.Issues[0].Kind != IssueUnclosedRing {
+			t.Fatalf("Expected an unclosed ring error, got %v", err)
+		}
+		if assemblyErr.Issues[0].NodeID != 3 {
+			t.Errorf("Expected the gap at node 3, got %d", assemblyErr.Issues[0].NodeID)
+		}
+	})
+
+	t.Run("Missing way", func(t *testing.T) {
+		_, _, err := a.AssembleRelation(multipolygon(osm.Member{Type: "way", Ref: 99, Role: "outer"}))
+		var assemblyErr *AssemblyError
+		if !errors.As(err, &assemblyErr) || assemblyErr.Issues[0].Kind != IssueMissingWay {
+			t.Fatalf("Expected a missing way error, got %v", err)
+		}
+	})
+
+	t.Run("Missing node", func(t *testing.T) {
+		ways[13] = []osm.ID{1, 2, 99, 1}
+		_, _, err := a.AssembleRelation(multipolygon(osm.Member{Type: "way", Ref: 13, Role: "outer"}))
+		var assemblyErr *AssemblyError
+		if !errors.As(err, &assemblyErr) || assemblyErr.Issues[0].NodeID != 99 {
+			t.Fatalf("Expected a missing node error for node 99, got %v", err)
+		}
+	})
+}
+
+func TestAssembleWay_SelfTouching(t *testing.T) {
+	// Two squares sharing node 6, drawn as a single figure-eight way
+	a := testAssembler(gridNodes(), nil)
+	way := &osm.Way{ID: 1, Nodes: []osm.ID{1, 2, 6, 7, 11, 10, 6, 5, 1}}
+
+	geometry, issues, err := a.AssembleWay(way)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geometry) != 2 {
+		t.Fatalf("Expected two polygons, got %d", len(geometry))
+	}
+	if len(issues) != 1 || issues[0].Kind != IssueSelfTouching || issues[0].NodeID != 6 {
+		t.Errorf("Expected a self-touching issue at node 6, got %v", issues)
+	}
+	for _, p := range geometry {
+		if signedArea(p[0]) <= 0 {
+			t.Error("Expected outer rings to be counter-clockwise")
+		}
+	}
+
+	if _, _, err := a.AssembleWay(&osm.Way{ID: 2, Nodes: []osm.ID{1, 2, 3}}); err == nil {
+		t.Error("Expected an error for an unclosed way")
+	}
+}
+
+func TestIsArea(t *testing.T) {
+	tests := []struct {
+		name string
+		tags osm.Tags
+		want bool
+	}{
+		{"Building", osm.Tags{{Key: "building", Value: "yes"}}, true},
+		{"Roundabout", osm.Tags{{Key: "highway", Value: "primary"}, {Key: "junction", Value: "roundabout"}}, false},
+		{"Explicit area", osm.Tags{{Key: "highway", Value: "pedestrian"}, {Key: "area", Value: "yes"}}, true},
+		{"Lake", osm.Tags{{Key: "natural", Value: "water"}}, true},
+		{"Tree row", osm.Tags{{Key: "natural", Value: "tree_row"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsArea(tt.tags); got != tt.want {
+				t.Errorf("IsArea() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// internal/geo/names.go
+package geo
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// Names are the variants of a feature's name besides its default name
+type Names struct {
+	Localized    map[string]string // Name per language, from name:<lang> tags
+	Alternatives []string          // Values of official_name, short_name, alt_name and old_name
+}
+
+// alternativeNameKeys are the tags holding other names of a feature, which
+// may list several names separated by semicolons
+var alternativeNameKeys = []string{"official_name", "short_name", "alt_name", "old_name"}
+
+// languageSuffix matches language codes such as "ca", "zh-Hant" or "be-tarask",
+// but not suffixes like "etymology" or "pronunciation"
+var languageSuffix = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]+)?$`)
+
+// namesFromTags collects the variants of the name in key, such as "name" or
+// "addr:street", from its key:<lang> tags. Alternative names are only read
+// for "name".
+func namesFromTags(tags osm.Tags, key string) Names {
+	var n Names
+	prefix := key + ":"
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag.Key, prefix) || tag.Value == "" {
+			continue
+		}
+		if lang := tag.Key[len(prefix):]; languageSuffix.MatchString(lang) {
+			if n.Localized == nil {
+				n.Localized = make(map[string]string)
+			}
+			n.Localized[lang] = tag.Value
+		}
+	}
+
+	if key == "name" {
+		for _, k := range alternativeNameKeys {
+			for _, value := range strings.Split(tags.Get(k), ";") {
+				if value = strings.TrimSpace(value); value != "" {
+					n.Alternatives = append(n.Alternatives, value)
+				}
+			}
+		}
+	}
+	return n
+}
+
+// In returns the name in the given language, falling back to the base
+// language of a regional code such as "zh" for "zh-Hant"
+func (n Names) In(lang string) (string, bool) {
+	if name, ok := n.Localized[lang]; ok {
+		return name, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if name, ok := n.Localized[base]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// All returns every localized name, by language code, then every alternative
+// name, without duplicates
+func (n Names) All() []string {
+	var all []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			all = append(all, name)
+		}
+	}
+	langs := make([]string, 0, len(n.Localized))
+	for lang := range n.Localized {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		add(n.Localized[lang])
+	}
+	for _, name := range n.Alternatives {
+		add(name)
+	}
+	return all
+}
+
+// IsEmpty reports whether there are no variants
+func (n Names) IsEmpty() bool {
+	return len(n.Localized) == 0 && len(n.Alternatives) == 0
+}
+
+// merge adds the variants of other that n lacks
+func (n *Names) merge(other Names) {
+	for lang, name := range other.Localized {
+		if _, exists := n.Localized[lang]; exists {
+			continue
+		}
+		if n.Localized == nil {
+			n.Localized = make(map[string]string)
+		}
+		n.Localized[lang] = name
+	}
+	for _, name := range other.Alternatives {
+		found := false
+		for _, existing := range n.Alternatives {
+			if existing == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			n.Alternatives = append(n.Alternatives, name)
+		}
+	}
+}
+
+// withVariants returns name followed by its variants
+func withVariants(name string, names Names) []string {
+	variants := []string{name}
+	for _, v := range names.All() {
+		if v != name {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// bestNameSimilarity compares a query with a name and all its variants
+func bestNameSimilarity(query, name string, names Names) float64 {
+	best := 0.0
+	for _, v := range withVariants(name, names) {
+		if sim := nameSimilarity(query, v); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// Localize returns the result with its name, street and city in the given
+// language where the index knows a translation
+func (idx *GeoIndex) Localize(result GeocodeResult, lang string) GeocodeResult {
+	if lang == "" {
+		return result
+	}
+	if name, ok := result.Names.In(lang); ok {
+		if result.Street == result.Name {
+			result.Street = name
+		}
+		result.Name = name
+	}
+	if street, ok := result.StreetNames.In(lang); ok {
+		result.Street = street
+	}
+	if result.City != "" {
+		if names, ok := idx.textIndex().localityNames[normalizeString(result.City)]; ok {
+			if city, ok := names.In(lang); ok {
+				result.City = city
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+// internal/geo/names_test.go
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestNamesFromTags(t *testing.T) {
+	tags := osm.Tags{
+		{Key: "name", Value: "Carrer Major"},
+		{Key: "name:ca", Value: "Carrer Major"},
+		{Key: "name:es", Value: "Calle Mayor"},
+		{Key: "name:zh-Hant", Value: "大街"},
+		{Key: "name:etymology:wikidata", Value: "Q1"},
+		{Key: "alt_name", Value: "Carrer Gran; Carrer del Mig"},
+		{Key: "old_name", Value: "Camí Ral"},
+		{Key: "addr:street:fr", Value: "Rue Majeure"},
+	}
+
+	names := namesFromTags(tags, "name")
+	if len(names.Localized) != 3 {
+		t.Errorf("Expected 3 localized names, got %v", names.Localized)
+	}
+	if name, ok := names.In("es"); !ok || name != "Calle Mayor" {
+		t.Errorf("In(es) = %q, %v", name, ok)
+	}
+	if name, ok := names.In("zh-Hant-TW"); ok {
+		t.Errorf("Expected no name for an unknown region, got %q", name)
+	}
+	if _, ok := names.In("de"); ok {
+		t.Error("Expected no German name")
+	}
+	want := []string{"Carrer Gran", "Carrer del Mig", "Camí Ral"}
+	if len(names.Alternatives) != len(want) {
+		t.Fatalf("Alternatives = %v, want %v", names.Alternatives, want)
+	}
+	for i := range want {
+		if names.Alternatives[i] != want[i] {
+			t.Errorf("Alternatives[%d] = %q, want %q", i, names.Alternatives[i], want[i])
+		}
+	}
+	// Localized names come by language code, so that results are stable
+	all := names.All()
+	want = []string{"Carrer Major", "Calle Mayor", "大街", "Carrer Gran", "Carrer del Mig", "Camí Ral"}
+	if len(all) != len(want) {
+		t.Fatalf("All() = %v, want %v", all, want)
+	}
+	for i := range want {
+		if all[i] != want[i] {
+			t.Errorf("All()[%d] = %q, want %q", i, all[i], want[i])
+		}
+	}
+	if variants := withVariants("Carrer Major", names); len(variants) != 6 {
+		t.Errorf("Expected the name and 5 distinct variants, got %v", variants)
+	}
+
+	street := namesFromTags(tags, "addr:street")
+	if name, _ := street.In("fr"); name != "Rue Majeure" || len(street.Alternatives) != 0 {
+		t.Errorf("Unexpected street names %+v", street)
+	}
+}
+
+func newMultilingualTestIndex(t *testing.T) *GeoIndex {
+	t.Helper()
+	b := NewGeoBuilder()
+	addSquareNodes(t, b, 100, 42.4, 1.4, 0.2)
+	b.ProcessWay(&osm.Way{ID: 10, Nodes: []osm.ID{100, 101, 102, 103, 100}})
+	b.ProcessRelation(&osm.Relation{
+		ID: 1,
+		Tags: osm.Tags{
+			{Key: "type", Value: "boundary"},
+			{Key: "boundary", Value: "administrative"},
+			{Key: "admin_level", Value: "7"},
+			{Key: "name", Value: "Andorra la Vella"},
+			{Key: "name:es", Value: "Andorra la Vieja"},
+			{Key: "name:fr", Value: "Andorre-la-Vieille"},
+		},
+		Members: []osm.Member{{Type: "way", Ref: 10, Role: "outer"}},
+	})
+
+	nodes := []osm.Node{
+		{ID: 1, Lat: 42.507, Lon: 1.521},
+		{ID: 2, Lat: 42.508, Lon: 1.523},
+		{ID: 3, Lat: 42.5075, Lon: 1.5215, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "12"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+	}
+	for i := range nodes {
+		b.ProcessNode(&nodes[i])
+	}
+	b.ProcessWay(&osm.Way{ID: 1, Nodes: []osm.ID{1, 2}, Tags: osm.Tags{
+		{Key: "highway", Value: "residential"},
+		{Key: "name", Value: "Carrer Major"},
+		{Key: "name:es", Value: "Calle Mayor"},
+		{Key: "alt_name", Value: "Carrer Gran"},
+	}})
+	return b.GetIndex()
+}
+
+func TestMultilingualNames(t *testing.T) {
+	idx := newMultilingualTestIndex(t)
+
+	addr := idx.Addresses[makeAddressKey("Carrer Major", "12", "")]
+	if name, _ := addr.StreetNames.In("es"); name != "Calle Mayor" {
+		t.Errorf("Expected the address to share the street's names, got %+v", addr.StreetNames)
+	}
+
+	t.Run("Search by variant", func(t *testing.T) {
+		for _, query := range []string{"Calle Mayor 12", "Carrer Gran 12, Andorra la Vieja"} {
+			results := idx.Search(query, 1)
+			if len(results) == 0 || results[0].Layer != LayerAddress || results[0].Street != "Carrer Major" {
+				t.Errorf("Search(%q) = %+v, want the address on Carrer Major", query, results)
+			}
+		}
+		results := idx.Search("Andorre-la-Vieille", 1)
+		if len(results) == 0 || results[0].Name != "Andorra la Vella" {
+			t.Errorf("Expected the parish by its French name, got %+v", results)
+		}
+	})
+
+	t.Run("Geocode by variant", func(t *testing.T) {
+		if result, err := idx.Geocode("Calle Mayor", "", ""); err != nil || result.Name != "Carrer Major" {
+			t.Errorf("Geocode() = %+v, %v", result, err)
+		}
+		if result, err := idx.Geocode("Calle Mayor", "12", ""); err != nil || result.HouseNumber != "12" {
+			t.Errorf("Geocode() = %+v, %v", result, err)
+		}
+	})
+
+	t.Run("Localize", func(t *testing.T) {
+		results := idx.Search("Carrer Major 12", 1)
+		if len(results) == 0 {
+			t.Fatal("Expected a result")
+		}
+		es := idx.Localize(results[0], "es")
+		if es.Street != "Calle Mayor" || es.City != "Andorra la Vieja" {
+			t.Errorf("Localize(es) = %q, %q", es.Street, es.City)
+		}
+		de := idx.Localize(results[0], "de")
+		if de.Street != "Carrer Major" || de.City != "Andorra la Vella" {
+			t.Errorf("Expected default names without a translation, got %q, %q", de.Street, de.City)
+		}
+	})
+
+	t.Run("Autocomplete in a language", func(t *testing.T) {
+		results := idx.Autocomplete("calle ma", AutocompleteOptions{Language: "es"})
+		if len(results) == 0 || results[0].Name != "Calle Mayor" {
+			t.Errorf("Expected the street by its Spanish name, got %+v", results)
+		}
+	})
+}
@@ -0,0 +1,331 @@
+// internal/geo/ngram.go
+package geo
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// NameIndex is an inverted index from trigrams to names. It retrieves the
+// names sharing most trigrams with a query without scanning every name, so
+// that the expensive similarity measures only run on a bounded candidate set.
+type NameIndex struct {
+	names    []string           // Normalized names by ID
+	counts   []int              // Number of distinct trigrams per name
+	ids      map[string]int     // Normalized name to ID
+	postings map[string][]int32 // Trigram to ascending name IDs
+}
+
+// NameCandidate is a name retrieved for a query
+type NameCandidate struct {
+	ID       int
+	Name     string  // Normalized name
+	Coverage float64 // Fraction of the query trigrams found in the name
+	Dice     float64 // Dice coefficient of the trigram sets, penalising extra words
+}
+
+func NewNameIndex() *NameIndex {
+	return &NameIndex{
+		ids:      make(map[string]int),
+		postings: make(map[string][]int32),
+	}
+}
+
+// Add indexes a name and returns its ID. Names that normalize to the same
+// text share an ID.
+func (n *NameIndex) Add(name string) int {
+	normalized := normalizeName(name)
+	if id, exists := n.ids[normalized]; exists {
+		return id
+	}
+
+	id := len(n.names)
+	grams := trigrams(normalized)
+	n.names = append(n.names, normalized)
+	n.counts = append(n.counts, len(grams))
+	n.ids[normalized] = id
+	for _, g := range grams {
+		n.postings[g] = append(n.postings[g], int32(id))
+	}
+	return id
+}
+
+// Lookup returns the ID of a name if it was added
+func (n *NameIndex) Lookup(name string) (int, bool) {
+	id, exists := n.ids[normalizeName(name)]
+	return id, exists
+}
+
+// Len returns the number of distinct names
+func (n *NameIndex) Len() int {
+	return len(n.names)
+}
+
+// Candidates returns up to limit names containing at least minCoverage of the
+// query trigrams, best first
+func (n *NameIndex) Candidates(query string, minCoverage float64, limit int) []NameCandidate {
+	grams := trigrams(normalizeName(query))
+	if len(grams) == 0 {
+		return nil
+	}
+
+	// Rarest trigrams first. A name with at least required shared trigrams
+	// must contain one of the first len-required+1 of them, so only those
+	// lists generate candidates and the long lists of common trigrams are
+	// only probed for candidates already found.
+	lists := make([][]int32, 0, len(grams))
+	for _, g := range grams {
+		lists = append(lists, n.postings[g])
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+	required := int(math.Ceil(minCoverage * float64(len(grams))))
+	if required < 1 {
+		required = 1
+	}
+	generating := len(lists) - required + 1
+
+	shared := make(map[int32]int)
+	for _, list := range lists[:generating] {
+		for _, id := range list {
+			shared[id]++
+		}
+	}
+	for _, list := range lists[generating:] {
+		for id := range shared {
+			i := sort.Search(len(list), func(i int) bool { return list[i] >= id })
+			if i < len(list) && list[i] == id {
+				shared[id]++
+			}
+		}
+	}
+
+	var candidates []NameCandidate
+	for id, count := range shared {
+		if count < required {
+			continue
+		}
+		candidates = append(candidates, NameCandidate{
+			ID:       int(id),
+			Name:     n.names[id],
+			Coverage: float64(count) / float64(len(grams)),
+			Dice:     2 * float64(count) / float64(len(grams)+n.counts[id]),
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Coverage != candidates[j].Coverage {
+			return candidates[i].Coverage > candidates[j].Coverage
+		}
+		if candidates[i].Dice != candidates[j].Dice {
+			return candidates[i].Dice > candidates[j].Dice
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// trigrams returns the distinct trigrams of the words of a normalized name,
+// each word padded so that word starts and ends form trigrams of their own
+func trigrams(normalized string) []string {
+	seen := make(map[string]bool)
+	var grams []string
+	for _, word := range strings.Fields(normalized) {
+		runes := []rune("$" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			g := string(runes[i : i+3])
+			if !seen[g] {
+				seen[g] = true
+				grams = append(grams, g)
+			}
+		}
+	}
+	return grams
+}
+
+// Bounds on the names retrieved per query before scoring
+const (
+	minCandidateCoverage = 0.3
+	maxNameCandidates    = 100
+)
+
+// textIndex groups the features of a GeoIndex by name for candidate retrieval
+type textIndex struct {
+	names         *NameIndex
+	addresses     [][]*Address      // Addresses by street name ID
+	streets       [][]*Street       // Streets by name ID
+	pois          [][]*POI          // POIs by name ID
+	places        [][]*Place        // Places by name ID
+	localities    map[string]string // Known city and area names, and their variants, by normalized form
+	localityNames map[string]Names  // Variants of area names by normalized default name
+	version       uint64            // Index version when built, to detect changes
+}
+
+// textIndex returns the name index, building it on first use or when the
+// features changed since it was built
+func (idx *GeoIndex) textIndex() *textIndex {
+	idx.textMu.Lock()
+	defer idx.textMu.Unlock()
+
+	if idx.text == nil || idx.text.version != idx.version {
+		idx.text = idx.buildTextIndex()
+		idx.text.version = idx.version
+	}
+	return idx.text
+}
+
+// changed records that features were added, replaced or removed, so that the
+// text indexes are rebuilt before the next query
+func (idx *GeoIndex) changed() {
+	idx.version++
+}
+
+func (idx *GeoIndex) buildTextIndex() *textIndex {
+	t := &textIndex{
+		names:         NewNameIndex(),
+		localities:    make(map[string]string),
+		localityNames: make(map[string]Names),
+	}
+	// Every variant of a name leads to the same features
+	add := func(name string, names Names) []int {
+		var ids []int
+		for _, v := range withVariants(name, names) {
+			id := t.names.Add(v)
+			if id == len(t.addresses) {
+				t.addresses = append(t.addresses, nil)
+				t.streets = append(t.streets, nil)
+				t.pois = append(t.pois, nil)
+				t.places = append(t.places, nil)
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	for _, addr := range idx.Addresses {
+		for _, id := range add(addr.Street, addr.StreetNames) {
+			t.addresses[id] = append(t.addresses[id], addr)
+		}
+		if addr.City != "" {
+			t.localities[normalizeString(addr.City)] = addr.City
+		}
+	}
+	for _, streets := range idx.Streets {
+		for _, s := range streets {
+			for _, id := range add(s.Name, s.Names) {
+				t.streets[id] = append(t.streets[id], s)
+			}
+			if s.City != "" {
+				t.localities[normalizeString(s.City)] = s.City
+			}
+		}
+	}
+	if idx.POIs != nil {
+		for _, e := range idx.POIs.All() {
+			p := e.Data.(*POI)
+			if p.City != "" {
+				t.localities[normalizeString(p.City)] = p.City
+			}
+			if p.Name == "" {
+				continue
+			}
+			for _, id := range add(p.Name, p.Names) {
+				t.pois[id] = append(t.pois[id], p)
+			}
+		}
+	}
+	if idx.AdminAreas != nil {
+		for _, e := range idx.AdminAreas.All() {
+			area := e.Data.(*AdminArea)
+			// Variants resolve to the default name addresses are tagged with
+			for _, v := range withVariants(area.Name, area.Names) {
+				if key := normalizeString(v); t.localities[key] == "" {
+					t.localities[key] = area.Name
+				}
+			}
+			t.localities[normalizeString(area.Name)] = area.Name
+			t.localityNames[normalizeString(area.Name)] = area.Names
+		}
+	}
+	if idx.Places != nil {
+		for _, e := range idx.Places.All() {
+			p := e.Data.(*Place)
+			for _, id := range add(p.Name, p.Names) {
+				t.places[id] = append(t.places[id], p)
+			}
+			// Boundaries name localities first, places fill the gaps
+			for _, v := range withVariants(p.Name, p.Names) {
+				if key := normalizeString(v); t.localities[key] == "" {
+					t.localities[key] = p.Name
+				}
+			}
+			if key := normalizeString(p.Name); t.localityNames[key].IsEmpty() {
+				t.localityNames[key] = p.Names
+			}
+		}
+	}
+	return t
+}
+
+// candidateAddresses returns the addresses on streets whose names resemble street
+func (t *textIndex) candidateAddresses(street string) []*Address {
+	var addrs []*Address
+	seen := make(map[*Address]bool)
+	for _, c := range t.names.Candidates(street, minCandidateCoverage, maxNameCandidates) {
+		for _, addr := range t.addresses[c.ID] {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+// candidateStreets returns the streets whose names resemble name
+func (t *textIndex) candidateStreets(name string) []*Street {
+	var streets []*Street
+	seen := make(map[*Street]bool)
+	for _, c := range t.names.Candidates(name, minCandidateCoverage, maxNameCandidates) {
+		for _, s := range t.streets[c.ID] {
+			if !seen[s] {
+				seen[s] = true
+				streets = append(streets, s)
+			}
+		}
+	}
+	return streets
+}
+
+// candidatePOIs returns the POIs whose names resemble name
+func (t *textIndex) candidatePOIs(name string) []*POI {
+	var pois []*POI
+	seen := make(map[*POI]bool)
+	for _, c := range t.names.Candidates(name, minCandidateCoverage, maxNameCandidates) {
+		for _, p := range t.pois[c.ID] {
+			if !seen[p] {
+				seen[p] = true
+				pois = append(pois, p)
+			}
+		}
+	}
+	return pois
+}
+
+// candidatePlaces returns the places whose names resemble name
+func (t *textIndex) candidatePlaces(name string) []*Place {
+	var places []*Place
+	seen := make(map[*Place]bool)
+	for _, c := range t.names.Candidates(name, minCandidateCoverage, maxNameCandidates) {
+		for _, p := range t.places[c.ID] {
+			if !seen[p] {
+				seen[p] = true
+				places = append(places, p)
+			}
+		}
+	}
+	return places
+}
@@ -0,0 +1,127 @@
+// internal/geo/ngram_test.go
+package geo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNameIndex_Candidates(t *testing.T) {
+	n := NewNameIndex()
+	for _, name := range []string{"Carrer Major", "Carrer de la Unió", "Avinguda Meritxell", "Main Street", "Maine Road"} {
+		n.Add(name)
+	}
+
+	if id, ok := n.Lookup("carrer  MAJOR"); !ok || n.names[id] != "carrer major" {
+		t.Errorf("Lookup() = %d, %v, want the normalized name", id, ok)
+	}
+	if id := n.Add("Carrer Major"); id != 0 || n.Len() != 5 {
+		t.Errorf("Add() of a known name = %d with %d names, want 0 with 5", id, n.Len())
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Carer Major", "carrer major"},
+		{"major", "carrer major"},
+		{"Main Strt", "main street"},
+		{"unio", "carrer de la unio"},
+		{"Meritxel", "avinguda meritxell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			candidates := n.Candidates(tt.query, 0.3, 10)
+			if len(candidates) == 0 || candidates[0].Name != tt.want {
+				t.Fatalf("Candidates() = %+v, want %q first", candidates, tt.want)
+			}
+			for i := 1; i < len(candidates); i++ {
+				if candidates[i].Coverage > candidates[i-1].Coverage {
+					t.Errorf("Candidates not sorted by coverage at %d", i)
+				}
+			}
+		})
+	}
+
+	if candidates := n.Candidates("Zzyzx", 0.3, 10); len(candidates) != 0 {
+		t.Errorf("Expected no candidates for an unrelated query, got %+v", candidates)
+	}
+	if candidates := n.Candidates("Carrer", 0.3, 1); len(candidates) != 1 {
+		t.Errorf("Expected the limit to apply, got %d candidates", len(candidates))
+	}
+}
+
+// Probing the common trigrams must find the same candidates as counting all
+// posting lists
+func TestNameIndex_CandidatesMatchFullCount(t *testing.T) {
+	n := NewNameIndex()
+	for i := 0; i < 500; i++ {
+		n.Add(fmt.Sprintf("Carrer %d del Sol", i))
+		n.Add(fmt.Sprintf("Street %d", i))
+	}
+
+	query := "Carrer 12 del Sal"
+	grams := trigrams(normalizeName(query))
+	shared := make(map[int]int)
+	for _, g := range grams {
+		for _, id := range n.postings[g] {
+			shared[int(id)]++
+		}
+	}
+	want := 0
+	for _, count := range shared {
+		if float64(count) >= 0.6*float64(len(grams)) {
+			want++
+		}
+	}
+
+	got := n.Candidates(query, 0.6, 0)
+	if len(got) != want {
+		t.Fatalf("Candidates() returned %d names, want %d", len(got), want)
+	}
+	if got[0].Name != "carrer 12 del sol" {
+		t.Errorf("Expected the closest name first, got %q", got[0].Name)
+	}
+}
+
+func TestGeoIndex_TextIndexRebuild(t *testing.T) {
+	idx := &GeoIndex{Addresses: map[string]*Address{
+		"main street:10:": {Street: "Main Street", HouseNumber: "10"},
+	}}
+	if _, err := idx.fuzzySearch("Main Stret", "10", ""); err != nil {
+		t.Fatalf("fuzzySearch() error = %v", err)
+	}
+
+	// Addresses added after the first query are found too
+	idx.Addresses["high street:4:"] = &Address{Street: "High Street", HouseNumber: "4"}
+	idx.changed()
+	result, err := idx.fuzzySearch("High Stret", "4", "")
+	if err != nil || result.Street != "High Street" {
+		t.Errorf("fuzzySearch() = %+v, %v, want High Street", result, err)
+	}
+
+	// So are replacements, which leave the number of features unchanged
+	delete(idx.Addresses, "high street:4:")
+	idx.Addresses["low street:4:"] = &Address{Street: "Low Street", HouseNumber: "4"}
+	idx.changed()
+	result, err = idx.fuzzySearch("Low Stret", "4", "")
+	if err != nil || result.Street != "Low Street" {
+		t.Errorf("fuzzySearch() = %+v, %v, want Low Street", result, err)
+	}
+
+	// And streets sharing the name of one already indexed, which add no name
+	mayor := Names{Localized: map[string]string{"es": "Calle Mayor"}}
+	idx.Streets = map[string][]*Street{
+		"carrer major": {{Name: "Carrer Major", Names: mayor, City: "Andorra la Vella"}},
+	}
+	idx.changed()
+	if streets := idx.FindStreets("Calle Mayor", ""); len(streets) != 1 {
+		t.Fatalf("FindStreets() = %d streets, want 1", len(streets))
+	}
+	idx.Streets["carrer major"] = append(idx.Streets["carrer major"],
+		&Street{Name: "Carrer Major", Names: mayor, City: "Encamp"})
+	idx.changed()
+	if streets := idx.FindStreets("Calle Mayor", "Encamp"); len(streets) != 1 {
+		t.Errorf("FindStreets() = %d streets in Encamp, want 1", len(streets))
+	}
+}
@@ -0,0 +1,271 @@
+// internal/geo/normalize.go
+package geo
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldedLetters are letters that do not decompose into a base letter and
+// accents but are commonly typed as the replacement
+var foldedLetters = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+	'·': "", // Catalan l·l, as in "Col·legi"
+}
+
+// wordPosition restricts where in a name an abbreviation is expanded
+type wordPosition int
+
+const (
+	anywhere wordPosition = iota
+	firstWord
+	lastWord
+	notLastWord
+)
+
+// abbreviation is a short form of a word in one language
+type abbreviation struct {
+	short    string
+	full     string
+	position wordPosition
+}
+
+// abbreviations lists common street type and title abbreviations per
+// language, as folded words without punctuation
+var abbreviations = map[string][]abbreviation{
+	"ca": {
+		{"av", "avinguda", anywhere},
+		{"avda", "avinguda", anywhere},
+		{"avgda", "avinguda", anywhere},
+		{"c", "carrer", firstWord},
+		{"cr", "carrer", firstWord},
+		{"ctra", "carretera", anywhere},
+		{"pl", "placa", firstWord},
+		{"pg", "passeig", anywhere},
+		{"ptge", "passatge", anywhere},
+		{"rda", "ronda", anywhere},
+		{"tv", "travessia", anywhere},
+		{"st", "sant", notLastWord},
+		{"sta", "santa", notLastWord},
+	},
+	"es": {
+		{"av", "avenida", anywhere},
+		{"avda", "avenida", anywhere},
+		{"c", "calle", firstWord},
+		{"cl", "calle", firstWord},
+		{"ctra", "carretera", anywhere},
+		{"pl", "plaza", firstWord},
+		{"pza", "plaza", anywhere},
+		{"po", "paseo", firstWord},
+		{"pso", "paseo", anywhere},
+		{"rda", "ronda", anywhere},
+		{"sta", "santa", notLastWord},
+		{"sto", "santo", notLastWord},
+	},
+	"fr": {
+		{"av", "avenue", anywhere},
+		{"bd", "boulevard", anywhere},
+		{"bld", "boulevard", anywhere},
+		{"ch", "chemin", firstWord},
+		{"imp", "impasse", anywhere},
+		{"pl", "place", firstWord},
+		{"r", "rue", firstWord},
+		{"rte", "route", anywhere},
+		{"st", "saint", notLastWord},
+		{"ste", "sainte", notLastWord},
+	},
+	"en": {
+		{"ave", "avenue", anywhere},
+		{"av", "avenue", anywhere},
+		{"blvd", "boulevard", anywhere},
+		{"ct", "court", lastWord},
+		{"dr", "drive", lastWord},
+		{"hwy", "highway", anywhere},
+		{"ln", "lane", lastWord},
+		{"mt", "mount", notLastWord},
+		{"pl", "place", lastWord},
+		{"rd", "road", anywhere},
+		{"sq", "square", anywhere},
+		// "St Mary Street" and "Main St"
+		{"st", "street", lastWord},
+		{"st", "saint", notLastWord},
+	},
+	"de": {
+		{"str", "strasse", anywhere},
+		{"pl", "platz", lastWord},
+	},
+}
+
+// languages is the order in which languages are consulted when the language
+// of a name is unknown. The first language defining a word decides its
+// canonical form, so that "Av.", "Avenida" and "Avinguda" compare equal.
+var languages = []string{"ca", "es", "fr", "en", "de"}
+
+// canonicalWords maps full forms in every language to the full form of the
+// first language defining an abbreviation for the same word
+var canonicalWords = buildCanonicalWords()
+
+func buildCanonicalWords() map[string]string {
+	canonical := make(map[string]string)
+	// Full forms sharing an abbreviation (avinguda, avenida, avenue) are the
+	// same word in different languages
+	byShort := make(map[string]string)
+	for _, lang := range languages {
+		for _, a := range abbreviations[lang] {
+			if _, exists := canonical[a.full]; exists {
+				continue
+			}
+			if first, exists := byShort[a.short+"/"+positionClass(a.position)]; exists {
+				canonical[a.full] = first
+			} else {
+				canonical[a.full] = a.full
+				byShort[a.short+"/"+positionClass(a.position)] = a.full
+			}
+		}
+	}
+	return canonical
+}
+
+// positionClass separates abbreviations that are only ambiguous by position,
+// such as "st" for street at the end of a name and saint elsewhere
+func positionClass(p wordPosition) string {
+	if p == notLastWord {
+		return "prefix"
+	}
+	return "type"
+}
+
+// stopwords are articles and prepositions that carry little meaning in names
+var stopwords = map[string]bool{
+	"d": true, "de": true, "del": true, "dels": true, "des": true, "du": true,
+	"el": true, "els": true, "l": true, "la": true, "las": true, "le": true,
+	"les": true, "lo": true, "los": true, "i": true, "y": true, "et": true,
+	"the": true, "of": true, "and": true, "s": true, "sa": true, "ses": true,
+	"der": true, "die": true, "das": true, "von": true, "am": true,
+}
+
+// foldString lowercases s and removes accents: "Església" becomes "esglesia"
+// and "Straße" becomes "strasse"
+func foldString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := foldedLetters[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// foldWords folds s and splits it into words of letters and digits
+func foldWords(s string) []string {
+	return strings.FieldsFunc(foldString(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// foldName folds a name and joins its words with single spaces
+func foldName(s string) string {
+	return strings.Join(foldWords(s), " ")
+}
+
+// NormalizeName folds a name and expands abbreviations in the given language.
+// Without a language, abbreviations and the full forms of every language are
+// mapped to a canonical form so that names can be compared across languages.
+func NormalizeName(s, lang string) string {
+	return strings.Join(expandAbbreviations(foldWords(s), lang), " ")
+}
+
+// normalizeName is the language independent form of a name used for matching
+func normalizeName(s string) string {
+	return NormalizeName(s, "")
+}
+
+// normalizeString reduces a name to a single token without stopwords, for
+// comparing whole names by edit distance
+func normalizeString(s string) string {
+	words := expandAbbreviations(foldWords(s), "")
+	return strings.Join(withoutStopwords(words), "")
+}
+
+// expandAbbreviations replaces abbreviated words by their full form
+func expandAbbreviations(words []string, lang string) []string {
+	expanded := make([]string, len(words))
+	for i, word := range words {
+		expanded[i] = word
+		if lang != "" {
+			if full, ok := expandWord(word, i, len(words), lang); ok {
+				expanded[i] = full
+			}
+			continue
+		}
+		for _, l := range languages {
+			if full, ok := expandWord(word, i, len(words), l); ok {
+				expanded[i] = canonicalWords[full]
+				break
+			}
+		}
+		if canonical, ok := canonicalWords[expanded[i]]; ok {
+			expanded[i] = canonical
+		}
+	}
+	return expanded
+}
+
+func expandWord(word string, i, n int, lang string) (string, bool) {
+	// A name consisting of the abbreviation alone is not abbreviated
+	if n == 1 {
+		return "", false
+	}
+	for _, a := range abbreviations[lang] {
+		if a.short != word {
+			continue
+		}
+		switch a.position {
+		case firstWord:
+			if i != 0 {
+				continue
+			}
+		case lastWord:
+			if i != n-1 {
+				continue
+			}
+		case notLastWord:
+			if i == n-1 {
+				continue
+			}
+		}
+		return a.full, true
+	}
+	return "", false
+}
+
+// withoutStopwords drops stopwords unless the name consists of nothing else
+func withoutStopwords(words []string) []string {
+	var kept []string
+	for _, w := range words {
+		if !stopwords[w] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return words
+	}
+	return kept
+}
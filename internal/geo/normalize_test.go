@@ -0,0 +1,99 @@
+// internal/geo/normalize_test.go
+package geo
+
+import "testing"
+
+func TestFoldString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Carrer de l'Església", "carrer de l'esglesia"},
+		{"Plaça del Col·legi", "placa del collegi"},
+		{"Hauptstraße", "hauptstrasse"},
+		{"Søndre Gate", "sondre gate"},
+		{"Œuvre", "oeuvre"},
+		{"ＡＢＣ", "abc"},
+	}
+	for _, tt := range tests {
+		if got := foldString(tt.input); got != tt.want {
+			t.Errorf("foldString(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		input string
+		lang  string
+		want  string
+	}{
+		{"Av. Meritxell", "ca", "avinguda meritxell"},
+		{"Av. de Mayo", "es", "avenida de mayo"},
+		{"Av. Foch", "fr", "avenue foch"},
+		{"C/ Major", "ca", "carrer major"},
+		{"Main St", "en", "main street"},
+		{"St. Mary Road", "en", "saint mary road"},
+		{"Hauptstr.", "de", "hauptstr"},
+		{"Goethe Str.", "de", "goethe strasse"},
+		// The same word in other languages maps to one canonical form
+		{"Avenida Meritxell", "", "avinguda meritxell"},
+		{"Avenue Meritxell", "", "avinguda meritxell"},
+		{"Av Meritxell", "", "avinguda meritxell"},
+		{"Saint Julià", "", "sant julia"},
+		{"Main St", "", "main street"},
+		// An abbreviation alone is a name
+		{"St", "", "st"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeName(tt.input, tt.lang); got != tt.want {
+			t.Errorf("NormalizeName(%q, %q) = %q, want %q", tt.input, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeString(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Carrer de l'Església", "Carrer Esglesia"},
+		{"Av. Meritxell", "Avinguda Meritxell"},
+		{"Main St", "Main Street"},
+		{"La", "la"},
+	}
+	for _, tt := range tests {
+		if a, b := normalizeString(tt.a), normalizeString(tt.b); a != b {
+			t.Errorf("normalizeString(%q) = %q differs from normalizeString(%q) = %q", tt.a, a, tt.b, b)
+		}
+	}
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"esglesia", "església", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"çà", "ca", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshteinDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshteinDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAutocomplete_Abbreviations(t *testing.T) {
+	idx := &GeoIndex{Addresses: map[string]*Address{
+		"avinguda meritxell:5:":   {Street: "Avinguda Meritxell", HouseNumber: "5"},
+		"carrer de l'església:1:": {Street: "Carrer de l'Església", HouseNumber: "1"},
+	}}
+
+	for _, prefix := range []string{"av. merit", "Av merit", "avinguda meri", "carrer de l'esgl", "esglé"} {
+		if results := idx.Autocomplete(prefix, AutocompleteOptions{}); len(results) != 1 {
+			t.Errorf("Autocomplete(%q) returned %d results, want 1", prefix, len(results))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// internal/geo/place.go
+package geo
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// PlaceType is the value of the place tag of a settlement or part of one
+type PlaceType string
+
+const (
+	PlaceCity          PlaceType = "city"
+	PlaceTown          PlaceType = "town"
+	PlaceVillage       PlaceType = "village"
+	PlaceHamlet        PlaceType = "hamlet"
+	PlaceSuburb        PlaceType = "suburb"
+	PlaceNeighbourhood PlaceType = "neighbourhood"
+)
+
+// placeTypeInfo describes each place type: the radius in km within which a
+// place node is taken as the locality of features without a boundary, its
+// importance before population is considered, and whether it is a
+// settlement of its own rather than part of one
+var placeTypeInfo = map[PlaceType]struct {
+	radiusKm   float64
+	importance float64
+	settlement bool
+}{
+	PlaceCity:          {15, 0.8, true},
+	PlaceTown:          {6, 0.65, true},
+	PlaceVillage:       {3, 0.5, true},
+	PlaceHamlet:        {1.5, 0.35, true},
+	PlaceSuburb:        {2, 0.45, false},
+	PlaceNeighbourhood: {1, 0.3, false},
+}
+
+// maxPlaceRadius is the largest radius in placeTypeInfo
+const maxPlaceRadius = 15.0
+
+// Place is a city, town, village, hamlet, suburb or neighbourhood mapped
+// with a place tag
+type Place struct {
+	ID         string // OSM element type and ID, such as "n123" or "r45"
+	Name       string
+	Names      Names
+	Type       PlaceType
+	Population int
+	Wikidata   string       // Value of the wikidata tag, taken from the boundary if missing
+	Wikipedia  string       // Value of the wikipedia tag, taken from the boundary if missing
+	Location   Coord        // The place node, or a point inside the area
+	Geometry   MultiPolygon // Area of the place or its boundary, nil if only a node is known
+	Area       *AdminArea   // Administrative boundary of the place, if linked
+	Parent     *Place       // Settlement a suburb or neighbourhood belongs to
+}
+
+// Importance ranks places between 0 and 1 by type, population and
+// notability, so that a city outranks a village of the same name
+func (p *Place) Importance() float64 {
+	importance := placeTypeInfo[p.Type].importance
+	if p.Population > 0 {
+		// 10 inhabitants add nothing, 10 million the full 0.2
+		importance += 0.2 * math.Min(1, math.Max(0, math.Log10(float64(p.Population))-1)/6)
+	}
+	if p.Wikidata != "" || p.Wikipedia != "" {
+		importance += wikiImportance
+	}
+	return math.Min(1, importance)
+}
+
+// Layer returns LayerLocality for settlements and LayerNeighbourhood for
+// parts of them
+func (p *Place) Layer() Layer {
+	if placeTypeInfo[p.Type].settlement {
+		return LayerLocality
+	}
+	return LayerNeighbourhood
+}
+
+func (p *Place) geometry() Geometry {
+	if p.Geometry != nil {
+		return p.Geometry
+	}
+	return p.Location
+}
+
+// placeResult describes the place as a geocoding result. Its city is the
+// settlement a suburb belongs to, or the administrative locality.
+func (idx *GeoIndex) placeResult(p *Place) GeocodeResult {
+	result := GeocodeResult{
+		Address:    Address{Lat: p.Location.Lat, Lon: p.Location.Lon},
+		Name:       p.Name,
+		Names:      p.Names,
+		Layer:      p.Layer(),
+		Importance: p.Importance(),
+		Place:      p,
+	}
+	if p.Parent != nil {
+		result.City = p.Parent.Name
+	}
+	if p.Geometry != nil {
+		extent := p.Geometry.Bounds()
+		result.Extent = &extent
+	}
+	idx.fillFromAdminAreas(&result.Address)
+	return result
+}
+
+// placeFromTags creates a place if the tags describe a named one
+func placeFromTags(id string, tags osm.Tags) *Place {
+	placeType := PlaceType(tags.Get("place"))
+	if _, ok := placeTypeInfo[placeType]; !ok || tags.Get("name") == "" {
+		return nil
+	}
+	population, _ := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(tags.Get("population")), " ", ""))
+	return &Place{
+		ID:         id,
+		Name:       tags.Get("name"),
+		Names:      namesFromTags(tags, "name"),
+		Type:       placeType,
+		Population: population,
+		Wikidata:   tags.Get("wikidata"),
+		Wikipedia:  tags.Get("wikipedia"),
+	}
+}
+
+// processPlaceArea collects a place mapped as an area
+func (b *GeoBuilder) processPlaceArea(id string, tags osm.Tags, area MultiPolygon) {
+	if p := placeFromTags(id, tags); p != nil {
+		p.Location = area.PointOnSurface()
+		p.Geometry = area
+		b.places = append(b.places, p)
+	}
+}
+
+// resolvePlaces links the collected places to the boundaries labelled with
+// them or named like them, adds them to the index and assigns suburbs and
+// neighbourhoods to their settlement
+func (b *GeoBuilder) resolvePlaces() {
+	for _, p := range b.places {
+		area := b.placeLabels[p.ID]
+		if area == nil {
+			area = b.index.boundaryNamed(p)
+		}
+		if area != nil && area.Place == nil {
+			p.Area, area.Place = area, p
+			p.Names.merge(area.Names)
+			if p.Wikidata == "" && p.Wikipedia == "" {
+				p.Wikidata, p.Wikipedia = area.Wikidata, area.Wikipedia
+			}
+			if p.Geometry == nil {
+				p.Geometry = area.Geometry
+			}
+		}
+		b.index.Places.Insert(RTreeEntry{Geometry: p.geometry(), Data: p})
+		b.index.changed()
+	}
+
+	for _, p := range b.places {
+		if placeTypeInfo[p.Type].settlement {
+			continue
+		}
+		p.Parent = b.index.nearestPlace(p.Location, func(other *Place) bool {
+			if other == p {
+				return false
+			}
+			// Neighbourhoods may belong to a suburb, suburbs only to a settlement
+			return placeTypeInfo[other.Type].settlement || (p.Type == PlaceNeighbourhood && other.Type == PlaceSuburb)
+		})
+	}
+	b.places = nil
+	b.placeLabels = make(map[string]*AdminArea)
+}
+
+// boundaryNamed returns the most specific administrative area containing the
+// place that has the same name, or nil
+func (idx *GeoIndex) boundaryNamed(p *Place) *AdminArea {
+	areas := idx.AdminAreasAt(p.Location.Lat, p.Location.Lon)
+	names := make(map[string]bool)
+	for _, name := range withVariants(p.Name, p.Names) {
+		names[normalizeString(name)] = true
+	}
+	for i := len(areas) - 1; i >= 0; i-- {
+		if areas[i].Level >= localityMinLevel && names[normalizeString(areas[i].Name)] {
+			return areas[i]
+		}
+	}
+	return nil
+}
+
+// nearestPlace returns the place accepted by the filter that c belongs to:
+// the one whose area contains c, or else the one nearest relative to its
+// radius, so that a city draws features from further away than a hamlet.
+// It returns nil if c is beyond the radius of every place.
+func (idx *GeoIndex) nearestPlace(c Coord, accept func(*Place) bool) *Place {
+	if idx.Places == nil {
+		return nil
+	}
+	filter := func(e RTreeEntry) bool { return accept(e.Data.(*Place)) }
+	var best *Place
+	bestRatio := math.Inf(1)
+	for _, n := range idx.Places.Nearest(c, 16, maxPlaceRadius, filter) {
+		p := n.Data.(*Place)
+		radius := placeTypeInfo[p.Type].radiusKm
+		if n.Distance > radius {
+			continue
+		}
+		ratio := n.Distance / radius
+		// Among areas containing c, prefer the most specific
+		if ratio < bestRatio || (ratio == bestRatio && best != nil && radius < placeTypeInfo[best.Type].radiusKm) {
+			best, bestRatio = p, ratio
+		}
+	}
+	return best
+}
+
+// settlementAt returns the city, town, village or hamlet c belongs to
+func (idx *GeoIndex) settlementAt(c Coord) *Place {
+	return idx.nearestPlace(c, func(p *Place) bool { return placeTypeInfo[p.Type].settlement })
+}
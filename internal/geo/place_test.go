@@ -0,0 +1,126 @@
+// internal/geo/place_test.go
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func newPlaceTestIndex(t *testing.T) *GeoIndex {
+	t.Helper()
+	b := NewGeoBuilder()
+	nodes := []osm.Node{
+		// A town with a boundary labelled by its place node
+		{ID: 1, Lat: 42.1, Lon: 1.1, Tags: osm.Tags{
+			{Key: "place", Value: "town"},
+			{Key: "name", Value: "Vila"},
+			{Key: "population", Value: "12 000"},
+		}},
+		// A suburb of the town
+		{ID: 2, Lat: 42.12, Lon: 1.12, Tags: osm.Tags{
+			{Key: "place", Value: "suburb"},
+			{Key: "name", Value: "Santa Coloma"},
+		}},
+		// Villages without boundaries
+		{ID: 3, Lat: 43.0, Lon: 2.0, Tags: osm.Tags{
+			{Key: "place", Value: "village"},
+			{Key: "name", Value: "Santa Coloma"},
+			{Key: "population", Value: "300"},
+		}},
+		{ID: 4, Lat: 43.0, Lon: 2.05, Tags: osm.Tags{
+			{Key: "place", Value: "hamlet"},
+			{Key: "name", Value: "Mas"},
+		}},
+		// Addresses without addr:city near the village and the hamlet
+		{ID: 5, Lat: 43.005, Lon: 2.01, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "1"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+		{ID: 6, Lat: 43.0, Lon: 2.045, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "2"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+		{ID: 7, Lat: 44.0, Lon: 3.0, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "3"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+	}
+	for i := range nodes {
+		if err := b.ProcessNode(&nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addSquareNodes(t, b, 100, 42, 1, 0.2)
+	b.ProcessWay(&osm.Way{ID: 10, Nodes: []osm.ID{100, 101, 102, 103, 100}})
+	b.ProcessRelation(&osm.Relation{
+		ID: 20,
+		Tags: osm.Tags{
+			{Key: "type", Value: "boundary"},
+			{Key: "boundary", Value: "administrative"},
+			{Key: "admin_level", Value: "8"},
+			{Key: "name", Value: "Vila"},
+			{Key: "name:es", Value: "Villa"},
+		},
+		Members: []osm.Member{
+			{Type: "way", Ref: 10, Role: "outer"},
+			{Type: "node", Ref: 1, Role: "label"},
+		},
+	})
+	return b.GetIndex()
+}
+
+func TestPlaces(t *testing.T) {
+	idx := newPlaceTestIndex(t)
+	if idx.Places.Size() != 4 {
+		t.Fatalf("Expected 4 places, got %d", idx.Places.Size())
+	}
+
+	t.Run("Linked to boundary", func(t *testing.T) {
+		results := idx.Search("Vila", 10)
+		if len(results) != 1 {
+			t.Fatalf("Expected the town once rather than also its boundary, got %+v", results)
+		}
+		town := results[0]
+		if town.Layer != LayerLocality || town.Place == nil || town.Place.Area == nil || town.Place.Population != 12000 {
+			t.Fatalf("Unexpected town %+v", town)
+		}
+		if name, _ := town.Names.In("es"); name != "Villa" || town.Extent == nil {
+			t.Errorf("Expected the town to take the boundary's names and extent, got %+v", town)
+		}
+	})
+
+	t.Run("Suburb", func(t *testing.T) {
+		results := idx.Search("Santa Coloma, Vila", 1)
+		if len(results) == 0 || results[0].Layer != LayerNeighbourhood || results[0].City != "Vila" {
+			t.Errorf("Expected the suburb of Vila, got %+v", results)
+		}
+		// Without a city the village outranks the suburb
+		results = idx.Search("Santa Coloma", 2)
+		if len(results) != 2 || results[0].Layer != LayerLocality || results[0].Importance <= results[1].Importance {
+			t.Errorf("Expected both places by importance, got %+v", results)
+		}
+	})
+
+	t.Run("Address localities", func(t *testing.T) {
+		tests := []struct {
+			house, city string
+		}{
+			{"1", "Santa Coloma"},
+			{"2", "Mas"},
+			{"3", ""},
+		}
+		for _, tt := range tests {
+			if addr := idx.Addresses[makeAddressKey("Carrer Major", tt.house, "")]; addr.City != tt.city {
+				t.Errorf("Address %s: city %q, want %q", tt.house, addr.City, tt.city)
+			}
+		}
+	})
+
+	t.Run("Autocomplete", func(t *testing.T) {
+		results := idx.Autocomplete("vil", AutocompleteOptions{})
+		if len(results) != 1 || results[0].Name != "Vila" || results[0].Layer != LayerLocality {
+			t.Errorf("Expected the town, got %+v", results)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+// internal/geo/poi.go
+package geo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	_ "time/tzdata" // Tagged zones must load on hosts without zone files
+
+	"github.com/sebastiaanwouters/geodude/internal/openinghours"
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// POI is a point of interest such as a shop, restaurant or pharmacy. Its
+// address fields hold the addr:* tags where present, filled in from the
+// administrative boundaries otherwise.
+type POI struct {
+	Address
+	ID           string // OSM element type and ID, such as "n123" or "w45"
+	Name         string
+	Names        Names
+	Category     Category
+	OpeningHours string // Value of the opening_hours tag, if any
+	Wikidata     string // Value of the wikidata tag, if any
+	Wikipedia    string // Value of the wikipedia tag, if any
+
+	schedule *openinghours.Schedule // Parsed OpeningHours, nil if missing or invalid
+	timeZone string                 // Value of the timezone tag, if any
+}
+
+// POIQuery selects POIs. All set criteria must match.
+type POIQuery struct {
+	Category Category        // The category or one of its subcategories
+	Name     string          // Fuzzy match against the name and its variants
+	Near     *Coord          // Orders results by distance to this point
+	RadiusKm float64         // Maximum distance from Near, unlimited if zero
+	Bounds   *Bounds         // Area the POI must lie in
+	OpenAt   *time.Time      // Time the POI must be open at, in any time zone
+	Filter   func(*POI) bool // Any further condition
+	Limit    int             // Maximum number of results, 10 if zero
+}
+
+// minPOINameSimilarity is the name similarity a POI needs to match a query name
+const minPOINameSimilarity = 0.75
+
+// FindPOIs returns the POIs matching the query, best name match first when a
+// name is given, otherwise nearest first when a point is given
+func (idx *GeoIndex) FindPOIs(q POIQuery) []GeocodeResult {
+	if idx.POIs == nil {
+		return nil
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	match := func(p *POI) bool {
+		if !p.Category.Is(q.Category) {
+			return false
+		}
+		if q.Bounds != nil && !q.Bounds.ContainsCoord(p.Location()) {
+			return false
+		}
+		if q.OpenAt != nil {
+			if open, known := p.IsOpen(*q.OpenAt); !open || !known {
+				return false
+			}
+		}
+		return q.Filter == nil || q.Filter(p)
+	}
+
+	var results []GeocodeResult
+	switch {
+	case q.Name != "":
+		for _, p := range idx.textIndex().candidatePOIs(q.Name) {
+			if !match(p) {
+				continue
+			}
+			score := bestNameSimilarity(q.Name, p.Name, p.Names)
+			if score < minPOINameSimilarity {
+				continue
+			}
+			result := p.result()
+			result.Score = score
+			if q.Near != nil {
+				result.Distance = HaversineDistance(*q.Near, p.Location())
+				if q.RadiusKm > 0 && result.Distance > q.RadiusKm {
+					continue
+				}
+			}
+			results = append(results, result)
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			if results[i].Score != results[j].Score {
+				return results[i].Score > results[j].Score
+			}
+			return results[i].Distance < results[j].Distance
+		})
+
+	case q.Near != nil:
+		radius := q.RadiusKm
+		if radius <= 0 {
+			radius = math.Inf(1)
+		}
+		filter := func(e RTreeEntry) bool { return match(e.Data.(*POI)) }
+		for _, n := range idx.POIs.Nearest(*q.Near, limit, radius, filter) {
+			result := n.Data.(*POI).result()
+			result.Distance = n.Distance
+			results = append(results, result)
+		}
+
+	default:
+		var entries []RTreeEntry
+		if q.Bounds != nil {
+			entries = idx.POIs.Search(*q.Bounds)
+		} else {
+			entries = idx.POIs.All()
+		}
+		for _, e := range entries {
+			if p := e.Data.(*POI); match(p) {
+				results = append(results, p.result())
+			}
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Location returns the coordinate of the POI
+func (p *POI) Location() Coord {
+	return Coord{Lat: p.Lat, Lon: p.Lon}
+}
+
+// IsOpen reports whether the POI is open at t according to its opening hours,
+// evaluated in the POI's time zone: that of its timezone tag, else of the
+// most specific boundary containing it that has one, else the zone set with
+// GeoBuilder.SetTimeZone. Known is false if the POI has no valid opening
+// hours.
+func (p *POI) IsOpen(t time.Time) (open, known bool) {
+	if p.schedule == nil {
+		return false, false
+	}
+	return p.schedule.IsOpen(t), true
+}
+
+// Importance ranks POIs between 0 and 1, notable ones above others
+func (p *POI) Importance() float64 {
+	if p.Wikidata != "" || p.Wikipedia != "" {
+		return poiImportance + wikiImportance
+	}
+	return poiImportance
+}
+
+func (p *POI) result() GeocodeResult {
+	return GeocodeResult{
+		Address:    p.Address,
+		Name:       p.Name,
+		Names:      p.Names,
+		Layer:      LayerPOI,
+		Importance: p.Importance(),
+		POI:        p,
+	}
+}
+
+// poiFromTags creates a POI if the tags describe one
+func poiFromTags(id string, tags osm.Tags, location Coord) *POI {
+	category, ok := classify(tags)
+	if !ok {
+		return nil
+	}
+	poi := &POI{
+		Address:      *addressFromTags(tags, location),
+		ID:           id,
+		Name:         tags.Get("name"),
+		Names:        namesFromTags(tags, "name"),
+		Category:     category,
+		OpeningHours: tags.Get("opening_hours"),
+		Wikidata:     tags.Get("wikidata"),
+		Wikipedia:    tags.Get("wikipedia"),
+		timeZone:     tags.Get("timezone"),
+	}
+	if poi.OpeningHours != "" {
+		// Invalid values are kept as text but cannot be evaluated
+		poi.schedule, _ = openinghours.Parse(poi.OpeningHours)
+	}
+	return poi
+}
+
+// SetTimeZone sets the zone of the opening hours of POIs that neither they
+// nor a boundary containing them tag with a timezone. It defaults to UTC.
+func (b *GeoBuilder) SetTimeZone(loc *time.Location) {
+	b.timeZone = loc
+}
+
+// addPOI stores a POI in the spatial index
+func (b *GeoBuilder) addPOI(p *POI) {
+	if p.schedule != nil {
+		// The zone may come from boundaries, which arrive later
+		b.unzoned = append(b.unzoned, p)
+	}
+	b.index.POIs.Insert(RTreeEntry{Geometry: p.Location(), Data: p})
+	b.index.changed()
+	b.trackUnresolved(&p.Address)
+}
+
+// resolveTimeZones sets the zone of the opening hours of the POIs added since
+// the last call. Opening hours are in local time, whatever zone the query is
+// in.
+func (b *GeoBuilder) resolveTimeZones() {
+	for _, p := range b.unzoned {
+		name := p.timeZone
+		if name == "" {
+			areas := b.index.AdminAreasAt(p.Lat, p.Lon)
+			for i := len(areas) - 1; i >= 0 && name == ""; i-- {
+				name = areas[i].TimeZone
+			}
+		}
+		p.schedule.Location = b.timeZone
+		if loc := b.loadTimeZone(name); loc != nil {
+			p.schedule.Location = loc
+		}
+	}
+	b.unzoned = nil
+}
+
+// loadTimeZone returns the zone with the IANA name, or nil if there is no
+// such zone
+func (b *GeoBuilder) loadTimeZone(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	loc, ok := b.timeZones[name]
+	if !ok {
+		// Unknown names are cached as nil
+		loc, _ = time.LoadLocation(name)
+		b.timeZones[name] = loc
+	}
+	return loc
+}
+
+// processPOIArea indexes a POI mapped as an area at a point inside it
+func (b *GeoBuilder) processPOIArea(id string, tags osm.Tags, area MultiPolygon) {
+	if p := poiFromTags(id, tags, area.PointOnSurface()); p != nil {
+		b.addPOI(p)
+	}
+}
+
+func elementID(kind byte, id osm.ID) string {
+	return fmt.Sprintf("%c%d", kind, id)
+}
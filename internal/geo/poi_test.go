@@ -0,0 +1,199 @@
+// internal/geo/poi_test.go
+package geo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		tags osm.Tags
+		want Category
+		ok   bool
+	}{
+		{osm.Tags{{Key: "amenity", Value: "pharmacy"}}, "health.pharmacy", true},
+		{osm.Tags{{Key: "building", Value: "yes"}, {Key: "shop", Value: "bakery"}}, "shopping.bakery", true},
+		{osm.Tags{{Key: "shop", Value: "books"}, {Key: "name", Value: "La Puça"}}, "shopping.books", true},
+		{osm.Tags{{Key: "shop", Value: "books"}}, "", false},
+		{osm.Tags{{Key: "amenity", Value: "bench"}}, "", false},
+		{osm.Tags{{Key: "name", Value: "Carrer Major"}, {Key: "highway", Value: "residential"}}, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := classify(tt.tags); got != tt.want || ok != tt.ok {
+			t.Errorf("classify(%v) = %q, %v, want %q, %v", tt.tags, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if !Category("health.pharmacy").Is("health") || Category("healthcare").Is("health") {
+		t.Error("Is() should match subcategories only")
+	}
+	for _, text := range []string{"pharmacy", "Pharmacies", "farmàcia", "gas station"} {
+		if _, ok := CategoryFor(text); !ok {
+			t.Errorf("CategoryFor(%q) found no category", text)
+		}
+	}
+}
+
+func newPOITestIndex(t *testing.T) *GeoIndex {
+	t.Helper()
+	b := NewGeoBuilder()
+	nodes := []osm.Node{
+		{ID: 1, Lat: 42.507, Lon: 1.521, Tags: osm.Tags{
+			{Key: "amenity", Value: "pharmacy"},
+			{Key: "name", Value: "Farmàcia Central"},
+			{Key: "opening_hours", Value: "Mo-Sa 09:00-20:00"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+		{ID: 2, Lat: 42.515, Lon: 1.521, Tags: osm.Tags{
+			{Key: "amenity", Value: "pharmacy"},
+			{Key: "name", Value: "Farmàcia del Pont"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+		{ID: 3, Lat: 42.556, Lon: 1.533, Tags: osm.Tags{
+			{Key: "amenity", Value: "pharmacy"},
+			{Key: "name", Value: "Farmàcia Ordino"},
+			{Key: "addr:city", Value: "Ordino"},
+		}},
+		{ID: 4, Lat: 42.5071, Lon: 1.5211, Tags: osm.Tags{
+			{Key: "amenity", Value: "restaurant"},
+			{Key: "name", Value: "El Raconet"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+	}
+	for i := range nodes {
+		if err := b.ProcessNode(&nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A museum mapped as a building
+	addSquareNodes(t, b, 100, 42.510, 1.530, 0.001)
+	b.ProcessWay(&osm.Way{ID: 50, Nodes: []osm.ID{100, 101, 102, 103, 100}, Tags: osm.Tags{
+		{Key: "building", Value: "yes"},
+		{Key: "tourism", Value: "museum"},
+		{Key: "name", Value: "Casa de la Vall"},
+		{Key: "addr:housenumber", Value: "1"},
+		{Key: "addr:street", Value: "Carrer de la Vall"},
+	}})
+	// The country, whose time zone the opening hours are in
+	addSquareNodes(t, b, 200, 42.4, 1.4, 0.3)
+	b.ProcessWay(&osm.Way{ID: 60, Nodes: []osm.ID{200, 201, 202, 203, 200}})
+	b.ProcessRelation(&osm.Relation{ID: 70, Members: []osm.Member{{Type: "way", Ref: 60, Role: "outer"}}, Tags: osm.Tags{
+		{Key: "type", Value: "boundary"},
+		{Key: "boundary", Value: "administrative"},
+		{Key: "admin_level", Value: "2"},
+		{Key: "name", Value: "Andorra"},
+		{Key: "timezone", Value: "Europe/Andorra"},
+	}})
+	return b.GetIndex()
+}
+
+func TestFindPOIs(t *testing.T) {
+	idx := newPOITestIndex(t)
+	here := Coord{Lat: 42.507, Lon: 1.521}
+
+	t.Run("Category near a point", func(t *testing.T) {
+		results := idx.FindPOIs(POIQuery{Category: "health.pharmacy", Near: &here, RadiusKm: 2})
+		if len(results) != 2 {
+			t.Fatalf("Expected the 2 pharmacies within 2 km, got %+v", results)
+		}
+		if results[0].Name != "Farmàcia Central" || results[0].POI.OpeningHours != "Mo-Sa 09:00-20:00" {
+			t.Errorf("Expected the nearest pharmacy first, got %+v", results[0])
+		}
+		if results[1].Distance < results[0].Distance {
+			t.Error("Expected results ordered by distance")
+		}
+	})
+
+	t.Run("Group and filter", func(t *testing.T) {
+		results := idx.FindPOIs(POIQuery{
+			Category: "health",
+			Filter:   func(p *POI) bool { return p.OpeningHours != "" },
+		})
+		if len(results) != 1 || results[0].Name != "Farmàcia Central" {
+			t.Errorf("Expected the pharmacy with opening hours, got %+v", results)
+		}
+	})
+
+	t.Run("Open at", func(t *testing.T) {
+		monday := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+		sunday := monday.AddDate(0, 0, -1)
+		if results := idx.FindPOIs(POIQuery{Category: "health.pharmacy", Near: &here, OpenAt: &monday}); len(results) != 1 {
+			t.Errorf("Expected the pharmacy with opening hours on Monday, got %+v", results)
+		}
+		if results := idx.FindPOIs(POIQuery{Category: "health.pharmacy", OpenAt: &sunday}); len(results) != 0 {
+			t.Errorf("Expected no open pharmacy on Sunday, got %+v", results)
+		}
+
+		// Opening hours are in Andorran time, two hours ahead of UTC in June
+		opening := time.Date(2024, time.June, 3, 7, 30, 0, 0, time.UTC)
+		if results := idx.FindPOIs(POIQuery{Category: "health.pharmacy", OpenAt: &opening}); len(results) != 1 {
+			t.Errorf("Expected the pharmacy open at 09:30 local time, got %+v", results)
+		}
+		closing := time.Date(2024, time.June, 3, 18, 30, 0, 0, time.UTC)
+		if results := idx.FindPOIs(POIQuery{Category: "health.pharmacy", OpenAt: &closing}); len(results) != 0 {
+			t.Errorf("Expected the pharmacy closed at 20:30 local time, got %+v", results)
+		}
+
+		// Without a tagged zone, opening hours are in the builder's zone
+		pharmacy := osm.Tags{
+			{Key: "amenity", Value: "pharmacy"},
+			{Key: "opening_hours", Value: "Mo-Sa 09:00-20:00"},
+		}
+		b := NewGeoBuilder()
+		b.ProcessNode(&osm.Node{ID: 1, Lat: 42.5, Lon: 1.5, Tags: pharmacy})
+		if results := b.GetIndex().FindPOIs(POIQuery{OpenAt: &opening}); len(results) != 0 {
+			t.Errorf("Expected the pharmacy closed at 07:30 in UTC, got %+v", results)
+		}
+		b = NewGeoBuilder()
+		b.SetTimeZone(time.FixedZone("UTC+2", 2*60*60))
+		b.ProcessNode(&osm.Node{ID: 1, Lat: 42.5, Lon: 1.5, Tags: pharmacy})
+		if results := b.GetIndex().FindPOIs(POIQuery{OpenAt: &opening}); len(results) != 1 {
+			t.Errorf("Expected the pharmacy open at 09:30 in the builder's zone, got %+v", results)
+		}
+
+		// The POI's own timezone tag wins, here Lisbon one hour ahead of UTC
+		b = NewGeoBuilder()
+		b.SetTimeZone(time.FixedZone("UTC+2", 2*60*60))
+		b.ProcessNode(&osm.Node{ID: 1, Lat: 42.5, Lon: 1.5, Tags: append(pharmacy, osm.Tag{Key: "timezone", Value: "Europe/Lisbon"})})
+		if results := b.GetIndex().FindPOIs(POIQuery{OpenAt: &opening}); len(results) != 0 {
+			t.Errorf("Expected the pharmacy closed at 08:30 Lisbon time, got %+v", results)
+		}
+	})
+
+	t.Run("By name", func(t *testing.T) {
+		results := idx.FindPOIs(POIQuery{Name: "farmacia del pont"})
+		if len(results) == 0 || results[0].Name != "Farmàcia del Pont" || results[0].Layer != LayerPOI {
+			t.Errorf("Expected the pharmacy by name, got %+v", results)
+		}
+	})
+
+	t.Run("Area POI", func(t *testing.T) {
+		results := idx.FindPOIs(POIQuery{Category: "tourism.museum"})
+		if len(results) != 1 || results[0].POI.ID != "w50" || results[0].HouseNumber != "1" {
+			t.Fatalf("Expected the museum building, got %+v", results)
+		}
+		if _, ok := idx.Addresses[makeAddressKey("Carrer de la Vall", "1", "")]; !ok {
+			t.Error("Expected the museum to be indexed as an address too")
+		}
+	})
+}
+
+func TestSearch_POIs(t *testing.T) {
+	idx := newPOITestIndex(t)
+
+	results := idx.Search("pharmacies in Ordino", 10)
+	if len(results) != 1 || results[0].Name != "Farmàcia Ordino" {
+		t.Errorf("Expected the pharmacy in Ordino, got %+v", results)
+	}
+	results = idx.Search("El Raconet", 1)
+	if len(results) == 0 || results[0].Layer != LayerPOI || results[0].POI.Category != "food.restaurant" {
+		t.Errorf("Expected the restaurant by name, got %+v", results)
+	}
+	results = idx.Autocomplete("casa de la", AutocompleteOptions{})
+	if len(results) == 0 || results[0].Name != "Casa de la Vall" {
+		t.Errorf("Expected the museum to complete, got %+v", results)
+	}
+}
@@ -1,7 +1,10 @@
 // internal/geo/quadtree.go
 package geo
 
-import "math"
+import (
+	"container/heap"
+	"math"
+)
 
 type QuadTree struct {
 	Bounds    Bounds
@@ -51,8 +54,27 @@ func (b Bounds) Center() (lat, lon float64) {
 	return (b.MinLat + b.MaxLat) / 2, (b.MinLon + b.MaxLon) / 2
 }
 
+// maxQuadTreeDepth bounds subdivision so that many points sharing a location
+// cannot split a node forever; nodes at this depth simply grow beyond MaxPoints
+const maxQuadTreeDepth = 32
+
 // split divides the quadtree into four children
 func (qt *QuadTree) split() {
+	qt.makeChildren()
+
+	// Redistribute existing points to children
+	for _, p := range qt.Points {
+		if child := qt.childFor(p); child != nil {
+			child.Insert(p)
+		}
+	}
+
+	// Clear points from parent
+	qt.Points = nil
+}
+
+// makeChildren creates the four empty child quadrants
+func (qt *QuadTree) makeChildren() {
 	centerLat, centerLon := qt.Bounds.Center()
 
 	// Create four children
@@ -88,19 +110,25 @@ func (qt *QuadTree) split() {
 	for i := 0; i < 4; i++ {
 		qt.Children[i].Level = qt.Level + 1
 	}
+}
 
-	// Redistribute existing points to children
-	for _, p := range qt.Points {
-		for i := 0; i < 4; i++ {
-			if qt.Children[i].Bounds.Contains(p) {
-				qt.Children[i].Insert(p)
-				break
-			}
+// childIndex returns the index of the child quadrant that owns p, or -1 if p
+// is outside all of them. Points on a shared edge belong to the first match.
+func (qt *QuadTree) childIndex(p Point) int {
+	for i := 0; i < 4; i++ {
+		if qt.Children[i].Bounds.Contains(p) {
+			return i
 		}
 	}
+	return -1
+}
 
-	// Clear points from parent
-	qt.Points = nil
+// childFor returns the child quadrant that owns p, or nil
+func (qt *QuadTree) childFor(p Point) *QuadTree {
+	if i := qt.childIndex(p); i >= 0 {
+		return qt.Children[i]
+	}
+	return nil
 }
 
 func (qt *QuadTree) Insert(p Point) {
@@ -110,17 +138,14 @@ func (qt *QuadTree) Insert(p Point) {
 
 	// If we have children, insert into appropriate child
 	if qt.Children[0] != nil {
-		for i := 0; i < 4; i++ {
-			if qt.Children[i].Bounds.Contains(p) {
-				qt.Children[i].Insert(p)
-				return
-			}
+		if child := qt.childFor(p); child != nil {
+			child.Insert(p)
 		}
 		return
 	}
 
 	// If we haven't reached capacity, add the point
-	if len(qt.Points) < qt.MaxPoints {
+	if len(qt.Points) < qt.MaxPoints || qt.Level >= maxQuadTreeDepth {
 		qt.Points = append(qt.Points, p)
 		return
 	}
@@ -130,6 +155,125 @@ func (qt *QuadTree) Insert(p Point) {
 	qt.Insert(p) // Re-insert the new point
 }
 
+// Remove deletes the first point with the same coordinates and Data as p and
+// reports whether one was found. Data values are compared with ==, so they
+// must be of a comparable type; use RemoveFunc otherwise.
+func (qt *QuadTree) Remove(p Point) bool {
+	return qt.RemoveFunc(p.Lat, p.Lon, func(candidate Point) bool {
+		return candidate.Data == p.Data
+	})
+}
+
+// RemoveFunc deletes the first point at (lat, lon) for which match returns
+// true. Quadrants whose children together fit into a single node again are
+// merged back into a leaf.
+func (qt *QuadTree) RemoveFunc(lat, lon float64, match func(Point) bool) bool {
+	target := Point{Lat: lat, Lon: lon}
+	if !qt.Bounds.Contains(target) {
+		return false
+	}
+
+	if qt.Children[0] != nil {
+		child := qt.childFor(target)
+		if child == nil || !child.RemoveFunc(lat, lon, match) {
+			return false
+		}
+		qt.merge()
+		return true
+	}
+
+	for i, p := range qt.Points {
+		if p.Lat == lat && p.Lon == lon && match(p) {
+			qt.Points = append(qt.Points[:i], qt.Points[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Update moves the point old to the location and data of updated. It reports
+// false and leaves the tree unchanged if old is not present or updated lies
+// outside the tree bounds.
+func (qt *QuadTree) Update(old, updated Point) bool {
+	if !qt.Bounds.Contains(updated) || !qt.Remove(old) {
+		return false
+	}
+	qt.Insert(updated)
+	return true
+}
+
+// merge collapses the children into this node when they are all leaves and
+// their points fit within MaxPoints
+func (qt *QuadTree) merge() {
+	total := 0
+	for _, child := range qt.Children {
+		if child.Children[0] != nil {
+			return
+		}
+		total += len(child.Points)
+	}
+	if total > qt.MaxPoints {
+		return
+	}
+
+	points := make([]Point, 0, qt.MaxPoints)
+	for i, child := range qt.Children {
+		points = append(points, child.Points...)
+		qt.Children[i] = nil
+	}
+	qt.Points = points
+}
+
+// BuildQuadTree bulk loads points into a new quadtree. Points are partitioned
+// top-down by quadrant in a single pass per level, so no node is ever split
+// and refilled as with repeated Insert calls. Points outside bounds are
+// dropped. The input slice is not modified.
+func BuildQuadTree(bounds Bounds, maxPoints int, points []Point) *QuadTree {
+	qt := NewQuadTree(bounds, maxPoints)
+
+	inside := make([]Point, 0, len(points))
+	for _, p := range points {
+		if bounds.Contains(p) {
+			inside = append(inside, p)
+		}
+	}
+	qt.bulkLoad(inside, make([]Point, len(inside)))
+	return qt
+}
+
+// bulkLoad fills an empty node with points, using scratch (of equal length)
+// as the buffer for partitioning
+func (qt *QuadTree) bulkLoad(points, scratch []Point) {
+	if len(points) <= qt.MaxPoints || qt.Level >= maxQuadTreeDepth {
+		qt.Points = append(qt.Points[:0], points...)
+		return
+	}
+
+	qt.makeChildren()
+	qt.Points = nil
+
+	// Counting sort by quadrant keeps every child's points contiguous
+	var offsets [5]int
+	quadrants := make([]int8, len(points))
+	for i, p := range points {
+		quadrants[i] = int8(qt.childIndex(p))
+		offsets[quadrants[i]+1]++
+	}
+	for i := 1; i < 5; i++ {
+		offsets[i] += offsets[i-1]
+	}
+	next := offsets
+	for i, p := range points {
+		scratch[next[quadrants[i]]] = p
+		next[quadrants[i]]++
+	}
+	copy(points, scratch)
+
+	for i, child := range qt.Children {
+		child.bulkLoad(points[offsets[i]:offsets[i+1]], scratch[offsets[i]:offsets[i+1]])
+	}
+}
+
 func (qt *QuadTree) Query(bounds Bounds) []Point {
 	var results []Point
 
@@ -205,3 +349,83 @@ func (qt *QuadTree) Size() int {
 	}
 	return count
 }
+
+// Neighbor is a point returned by a nearest-neighbour query together with its
+// distance in km from the query location
+type Neighbor struct {
+	Point
+	Distance float64
+}
+
+// Nearest returns up to k points closest to (lat, lon), ordered by increasing
+// haversine distance. If filter is non-nil only points whose Data satisfies it
+// are considered.
+func (qt *QuadTree) Nearest(lat, lon float64, k int, filter func(data interface{}) bool) []Neighbor {
+	return qt.NearestWithin(lat, lon, k, math.Inf(1), filter)
+}
+
+// NearestWithin is like Nearest but ignores points further than maxDistanceKm
+func (qt *QuadTree) NearestWithin(lat, lon float64, k int, maxDistanceKm float64, filter func(data interface{}) bool) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+	origin := Coord{Lat: lat, Lon: lon}
+
+	// Best-first search: quadrants and points share one queue ordered by
+	// distance, so a point is only popped once nothing closer can remain
+	queue := &nearestQueue{{node: qt, distance: boundsDistance(qt.Bounds, origin)}}
+	results := make([]Neighbor, 0, k)
+	for queue.Len() > 0 && len(results) < k {
+		item := heap.Pop(queue).(nearestItem)
+		if item.distance > maxDistanceKm {
+			break
+		}
+		if item.node == nil {
+			results = append(results, Neighbor{Point: item.point, Distance: item.distance})
+			continue
+		}
+		for _, p := range item.node.Points {
+			if filter != nil && !filter(p.Data) {
+				continue
+			}
+			dist := HaversineDistance(origin, Coord{Lat: p.Lat, Lon: p.Lon})
+			if dist <= maxDistanceKm {
+				heap.Push(queue, nearestItem{point: p, distance: dist})
+			}
+		}
+		if item.node.Children[0] != nil {
+			for _, child := range item.node.Children {
+				if dist := boundsDistance(child.Bounds, origin); dist <= maxDistanceKm {
+					heap.Push(queue, nearestItem{node: child, distance: dist})
+				}
+			}
+		}
+	}
+	return results
+}
+
+// nearestItem is either a quadrant (node set) or a candidate point
+type nearestItem struct {
+	node     *QuadTree
+	point    Point
+	distance float64
+}
+
+type nearestQueue []nearestItem
+
+func (q nearestQueue) Len() int { return len(q) }
+func (q nearestQueue) Less(i, j int) bool {
+	// Points win ties so they are returned before equally distant quadrants are expanded
+	if q[i].distance == q[j].distance {
+		return q[i].node == nil && q[j].node != nil
+	}
+	return q[i].distance < q[j].distance
+}
+func (q nearestQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nearestQueue) Push(x interface{}) { *q = append(*q, x.(nearestItem)) }
+func (q *nearestQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
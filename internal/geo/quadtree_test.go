@@ -0,0 +1,199 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func newTestQuadTree(n int) (*QuadTree, []Point) {
+	rng := rand.New(rand.NewSource(1))
+	qt := NewQuadTree(Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}, 8)
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			Lat:  42.4 + rng.Float64()*0.3,
+			Lon:  1.4 + rng.Float64()*0.4,
+			Data: i,
+		}
+		qt.Insert(points[i])
+	}
+	return qt, points
+}
+
+func TestQuadTree_Nearest(t *testing.T) {
+	qt, points := newTestQuadTree(2000)
+	origin := Coord{Lat: 42.55, Lon: 1.6}
+
+	// Brute force reference ordering
+	sorted := append([]Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return HaversineDistance(origin, Coord{Lat: sorted[i].Lat, Lon: sorted[i].Lon}) <
+			HaversineDistance(origin, Coord{Lat: sorted[j].Lat, Lon: sorted[j].Lon})
+	})
+
+	got := qt.Nearest(origin.Lat, origin.Lon, 10, nil)
+	if len(got) != 10 {
+		t.Fatalf("Expected 10 neighbours, got %d", len(got))
+	}
+	for i, n := range got {
+		if n.Data != sorted[i].Data {
+			t.Errorf("Neighbour %d = %v, want %v", i, n.Data, sorted[i].Data)
+		}
+		if i > 0 && n.Distance < got[i-1].Distance {
+			t.Errorf("Neighbours not ordered by distance at %d", i)
+		}
+	}
+}
+
+func TestQuadTree_NearestFilterAndMaxDistance(t *testing.T) {
+	qt, _ := newTestQuadTree(500)
+
+	even := func(data interface{}) bool { return data.(int)%2 == 0 }
+	for _, n := range qt.Nearest(42.5, 1.5, 20, even) {
+		if n.Data.(int)%2 != 0 {
+			t.Errorf("Filter not applied, got %v", n.Data)
+		}
+	}
+
+	// Far outside the data set nothing is within 10 km but the nearest point is still found
+	if got := qt.NearestWithin(10, 10, 1, 10, nil); len(got) != 0 {
+		t.Errorf("Expected no neighbours within 10 km, got %d", len(got))
+	}
+	if got := qt.Nearest(10, 10, 1, nil); len(got) != 1 {
+		t.Errorf("Expected 1 neighbour without a distance limit, got %d", len(got))
+	}
+
+	if got := qt.Nearest(42.5, 1.5, 1000, nil); len(got) != 500 {
+		t.Errorf("Expected all 500 points, got %d", len(got))
+	}
+}
+
+func TestReverseGeocode_Rural(t *testing.T) {
+	builder := NewGeoBuilder()
+	// An isolated farm about 3 km from the query point
+	builder.ProcessNode(&osm.Node{
+		ID:  1,
+		Lat: 42.6,
+		Lon: 1.45,
+		Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "1"},
+			{Key: "addr:street", Value: "Cami de la Borda"},
+		},
+	})
+
+	result, err := builder.GetIndex().ReverseGeocode(42.627, 1.45)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Street != "Cami de la Borda" {
+		t.Fatalf("Expected the farm address, got %+v", result)
+	}
+	if result.Distance < 2.9 || result.Distance > 3.1 {
+		t.Errorf("Expected a distance of about 3 km, got %f", result.Distance)
+	}
+}
+
+func TestQuadTree_RemoveAndMerge(t *testing.T) {
+	qt, points := newTestQuadTree(200)
+	if qt.Children[0] == nil {
+		t.Fatal("Expected the tree to be split")
+	}
+
+	for _, p := range points[:195] {
+		if !qt.Remove(p) {
+			t.Fatalf("Failed to remove point %v", p.Data)
+		}
+	}
+	if qt.Remove(points[0]) {
+		t.Error("Removing a point twice should fail")
+	}
+	if qt.Size() != 5 {
+		t.Errorf("Expected 5 remaining points, got %d", qt.Size())
+	}
+	if qt.Children[0] != nil {
+		t.Error("Expected underfull children to be merged into the root")
+	}
+
+	got := qt.Query(qt.Bounds)
+	if len(got) != 5 {
+		t.Errorf("Expected 5 points from query, got %d", len(got))
+	}
+}
+
+func TestQuadTree_Update(t *testing.T) {
+	qt, points := newTestQuadTree(100)
+
+	moved := Point{Lat: 42.0, Lon: 1.0, Data: points[10].Data}
+	if !qt.Update(points[10], moved) {
+		t.Fatal("Update() returned false for an existing point")
+	}
+	if got := qt.Nearest(42.0, 1.0, 1, nil); got[0].Data != moved.Data || got[0].Distance != 0 {
+		t.Errorf("Expected moved point at new location, got %+v", got[0])
+	}
+	if qt.Size() != 100 {
+		t.Errorf("Expected size to stay 100, got %d", qt.Size())
+	}
+
+	outside := Point{Lat: 100, Lon: 0, Data: 1}
+	if qt.Update(points[1], outside) {
+		t.Error("Update() to a location outside the bounds should fail")
+	}
+	if qt.Size() != 100 {
+		t.Errorf("Failed update changed size to %d", qt.Size())
+	}
+}
+
+func TestQuadTree_DuplicateLocations(t *testing.T) {
+	qt := NewQuadTree(Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}, 4)
+	for i := 0; i < 50; i++ {
+		qt.Insert(Point{Lat: 42.5, Lon: 1.5, Data: i})
+	}
+	if qt.Size() != 50 {
+		t.Errorf("Expected 50 points, got %d", qt.Size())
+	}
+}
+
+func TestBuildQuadTree(t *testing.T) {
+	_, points := newTestQuadTree(5000)
+	bounds := Bounds{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}
+	qt := BuildQuadTree(bounds, 8, points)
+
+	if qt.Size() != len(points) {
+		t.Fatalf("Expected %d points, got %d", len(points), qt.Size())
+	}
+
+	// Every leaf respects the capacity, and queries match an incrementally built tree
+	var check func(node *QuadTree)
+	check = func(node *QuadTree) {
+		if node.Children[0] == nil {
+			if len(node.Points) > node.MaxPoints {
+				t.Errorf("Leaf at level %d holds %d points", node.Level, len(node.Points))
+			}
+			return
+		}
+		if len(node.Points) != 0 {
+			t.Errorf("Internal node at level %d holds points", node.Level)
+		}
+		for _, child := range node.Children {
+			check(child)
+		}
+	}
+	check(qt)
+
+	incremental := NewQuadTree(bounds, 8)
+	for _, p := range points {
+		incremental.Insert(p)
+	}
+	query := Bounds{MinLat: 42.5, MaxLat: 42.6, MinLon: 1.5, MaxLon: 1.6}
+	if a, b := len(qt.Query(query)), len(incremental.Query(query)); a != b {
+		t.Errorf("Bulk loaded query returned %d points, incremental %d", a, b)
+	}
+
+	// Removal works on bulk loaded trees
+	if !qt.Remove(points[42]) {
+		t.Error("Failed to remove point from bulk loaded tree")
+	}
+}
@@ -0,0 +1,120 @@
+// internal/geo/rank.go
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// Weights of the ranking components. Proximity only counts when a focus
+// point or viewport is given.
+const (
+	matchWeight        = 0.6
+	importanceWeight   = 0.2
+	proximityWeight    = 0.15
+	completenessWeight = 0.05
+)
+
+// Importance of features without a better measure, between 0 and 1
+const (
+	addressImportance = 0.1
+	streetImportance  = 0.3
+	poiImportance     = 0.2
+
+	// wikiImportance is added for features with a wikipedia or wikidata tag,
+	// which only notable features have
+	wikiImportance = 0.15
+)
+
+// focusScale is the distance in kilometres from the focus point at which the
+// proximity bias has halved
+const focusScale = 10.0
+
+// Bias favours results near a location
+type Bias struct {
+	Focus    *Coord  // Results near this point rank higher
+	Viewport *Bounds // Results inside this area, such as the visible map, rank higher
+}
+
+// rank sets the confidence of each result from its match score, importance,
+// proximity and completeness, orders the results by it and keeps the best
+// limit of them. With a focus point it also sets the distance to it.
+func rank(results []GeocodeResult, bias Bias, limit int) []GeocodeResult {
+	for i := range results {
+		r := &results[i]
+		if bias.Focus != nil {
+			r.Distance = HaversineDistance(*bias.Focus, r.Location())
+		}
+		r.Confidence = confidence(r, bias)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Confidence != results[j].Confidence {
+			return results[i].Confidence > results[j].Confidence
+		}
+		return results[i].Name < results[j].Name
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// confidence combines the ranking components of a result into a value
+// between 0 and 1
+func confidence(r *GeocodeResult, bias Bias) float64 {
+	total := matchWeight*r.Score + importanceWeight*r.Importance + completenessWeight*completeness(r)
+	weight := matchWeight + importanceWeight + completenessWeight
+	if proximity, ok := proximity(r.Location(), bias); ok {
+		total += proximityWeight * proximity
+		weight += proximityWeight
+	}
+	return total / weight
+}
+
+// proximity scores closeness to the focus point and viewport between 0 and
+// 1, and reports false if neither is given
+func proximity(c Coord, bias Bias) (float64, bool) {
+	var total float64
+	var n int
+	if bias.Focus != nil {
+		total += 1 / (1 + HaversineDistance(*bias.Focus, c)/focusScale)
+		n++
+	}
+	if bias.Viewport != nil {
+		// Distance outside the viewport counts relative to its size
+		size := math.Max(1, HaversineDistance(
+			Coord{Lat: bias.Viewport.MinLat, Lon: bias.Viewport.MinLon},
+			Coord{Lat: bias.Viewport.MaxLat, Lon: bias.Viewport.MaxLon},
+		))
+		total += 1 / (1 + boundsDistance(*bias.Viewport, c)/size)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return total / float64(n), true
+}
+
+// completeness is the share of the address fields that apply to the result's
+// layer that are known
+func completeness(r *GeocodeResult) float64 {
+	fields := []string{r.City, r.Country}
+	switch r.Layer {
+	case LayerAddress:
+		fields = append(fields, r.Street, r.HouseNumber, r.PostCode)
+	case LayerStreet, LayerPOI:
+		fields = append(fields, r.Street, r.PostCode)
+	}
+	known := 0
+	for _, f := range fields {
+		if f != "" {
+			known++
+		}
+	}
+	return float64(known) / float64(len(fields))
+}
+
+// Location returns the coordinate of the result
+func (r *GeocodeResult) Location() Coord {
+	return Coord{Lat: r.Lat, Lon: r.Lon}
+}
@@ -0,0 +1,80 @@
+// internal/geo/rank_test.go
+package geo
+
+import (
+	"testing"
+)
+
+func TestRank(t *testing.T) {
+	at := func(name string, lat, lon, score, importance float64) GeocodeResult {
+		return GeocodeResult{
+			Address:    Address{Lat: lat, Lon: lon, City: "Vila", Country: "Andorra"},
+			Name:       name,
+			Layer:      LayerLocality,
+			Score:      score,
+			Importance: importance,
+		}
+	}
+
+	t.Run("Importance breaks equal matches", func(t *testing.T) {
+		results := rank([]GeocodeResult{at("village", 42, 1, 1, 0.5), at("city", 43, 2, 1, 0.9)}, Bias{}, 0)
+		if results[0].Name != "city" {
+			t.Errorf("Expected the city first, got %+v", results)
+		}
+		for _, r := range results {
+			if r.Confidence <= 0 || r.Confidence > 1 || r.Distance != 0 {
+				t.Errorf("Unexpected confidence or distance without focus %+v", r)
+			}
+		}
+	})
+
+	t.Run("Better match outweighs importance", func(t *testing.T) {
+		results := rank([]GeocodeResult{at("city", 43, 2, 0.6, 1), at("village", 42, 1, 1, 0.3)}, Bias{}, 1)
+		if len(results) != 1 || results[0].Name != "village" {
+			t.Errorf("Expected only the village, got %+v", results)
+		}
+	})
+
+	t.Run("Focus", func(t *testing.T) {
+		focus := Coord{Lat: 42, Lon: 1}
+		results := rank([]GeocodeResult{at("far", 43, 2, 1, 0.5), at("near", 42.01, 1, 1, 0.5)}, Bias{Focus: &focus}, 0)
+		if results[0].Name != "near" || results[0].Distance > 2 || results[1].Distance < 100 {
+			t.Errorf("Expected the nearer result first with distances, got %+v", results)
+		}
+	})
+
+	t.Run("Viewport", func(t *testing.T) {
+		viewport := Bounds{MinLat: 42.9, MinLon: 1.9, MaxLat: 43.1, MaxLon: 2.1}
+		results := rank([]GeocodeResult{at("outside", 42, 1, 1, 0.5), at("inside", 43, 2, 1, 0.5)}, Bias{Viewport: &viewport}, 0)
+		if results[0].Name != "inside" {
+			t.Errorf("Expected the result in the viewport first, got %+v", results)
+		}
+	})
+
+	t.Run("Completeness", func(t *testing.T) {
+		partial := at("partial", 42, 1, 1, 0.5)
+		partial.City = ""
+		results := rank([]GeocodeResult{partial, at("complete", 42, 1, 1, 0.5)}, Bias{}, 0)
+		if results[0].Name != "complete" {
+			t.Errorf("Expected the complete address first, got %+v", results)
+		}
+	})
+}
+
+func TestGeocode_FuzzyRanking(t *testing.T) {
+	idx := &GeoIndex{Addresses: map[string]*Address{}}
+	for _, n := range []string{"2", "10", "40"} {
+		idx.Addresses[makeAddressKey("Main Street", n, "12345")] = &Address{Street: "Main Street", HouseNumber: n, PostCode: "12345"}
+	}
+
+	result, err := idx.Geocode("Main Street", "12", "12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HouseNumber != "10" || result.Distance != 0 {
+		t.Errorf("Expected the nearest house number without a distance, got %+v", result)
+	}
+	if result.Confidence <= 0 || result.Confidence >= 1 {
+		t.Errorf("Expected a confidence below that of an exact match, got %f", result.Confidence)
+	}
+}
@@ -0,0 +1,117 @@
+// internal/geo/reverse.go
+package geo
+
+import "math"
+
+const (
+	// ReverseHouseRadius is the distance in km within which the nearest house
+	// number is returned even when a street is closer
+	ReverseHouseRadius = 0.2
+
+	// ReverseStreetRadius is the distance in km within which the nearest street
+	// is returned when there is no house number nearby
+	ReverseStreetRadius = 1.0
+)
+
+// localityMinLevel is the lowest admin_level reported as a locality rather
+// than a broader administrative area
+const localityMinLevel = 7
+
+// ReverseGeocode finds the most precise description of a location, trying in
+// order: the building containing it, the nearest house number, the nearest
+// street and the locality or administrative area it lies in. If none of
+// these match, the nearest house number is returned however far away it
+// is, with its distance. Result.Layer tells which level matched.
+func (idx *GeoIndex) ReverseGeocode(lat, lon float64) (*GeocodeResult, error) {
+	// A point inside an addressed building belongs to that building
+	if addr := idx.BuildingAt(lat, lon); addr != nil {
+		result := addressResult(addr)
+		return &result, nil
+	}
+
+	var house *Neighbor
+	if nearest := idx.StreetIndex.Nearest(lat, lon, 1, isAddress); len(nearest) > 0 {
+		house = &nearest[0]
+	}
+	if house != nil && house.Distance <= ReverseHouseRadius {
+		return houseResult(house), nil
+	}
+
+	street, streetDist := idx.NearestStreet(lat, lon, ReverseStreetRadius)
+	if street != nil {
+		if house != nil && house.Distance <= streetDist {
+			return houseResult(house), nil
+		}
+		return idx.streetResult(street, lat, lon), nil
+	}
+
+	if result := idx.areaResult(lat, lon); result != nil {
+		return result, nil
+	}
+	if house != nil {
+		return houseResult(house), nil
+	}
+	return nil, nil
+}
+
+func houseResult(n *Neighbor) *GeocodeResult {
+	result := addressResult(n.Data.(*Address))
+	result.Distance = n.Distance
+	return &result
+}
+
+// streetResult describes a location by the closest point on a street
+func (idx *GeoIndex) streetResult(street *Street, lat, lon float64) *GeocodeResult {
+	closest, dist := street.Geometry.ClosestPoint(Coord{Lat: lat, Lon: lon})
+	extent := street.Geometry.Bounds()
+	result := &GeocodeResult{
+		Address: Address{
+			Street: street.Name,
+			City:   street.City,
+			Lat:    closest.Lat,
+			Lon:    closest.Lon,
+		},
+		Name:       street.Name,
+		Names:      street.Names,
+		Layer:      LayerStreet,
+		Distance:   dist,
+		Importance: streetImportance,
+		Extent:     &extent,
+	}
+	idx.fillFromAdminAreas(&result.Address)
+	return result
+}
+
+// areaResult describes a location by the most specific administrative area
+// containing it
+func (idx *GeoIndex) areaResult(lat, lon float64) *GeocodeResult {
+	areas := idx.AdminAreasAt(lat, lon)
+	if len(areas) == 0 {
+		return nil
+	}
+
+	result := idx.adminAreaResult(areas[len(areas)-1])
+	return &result
+}
+
+// BuildingAt returns the address of the building whose footprint contains the
+// coordinate, preferring the smallest one when footprints overlap
+func (idx *GeoIndex) BuildingAt(lat, lon float64) *Address {
+	if idx.Buildings == nil {
+		return nil
+	}
+
+	var best *Address
+	bestArea := math.Inf(1)
+	for _, e := range idx.Buildings.Containing(Coord{Lat: lat, Lon: lon}) {
+		if area := e.Geometry.Bounds().area(); area < bestArea {
+			best, bestArea = e.Data.(*Address), area
+		}
+	}
+	return best
+}
+
+func isAddress(data interface{}) bool {
+	_, ok := data.(*Address)
+	return ok
+}
@@ -0,0 +1,94 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestReverseGeocode_Layers(t *testing.T) {
+	b := NewGeoBuilder()
+
+	// A parish covering (42, 1) to (42.2, 1.2) inside a region covering (41, 0) to (43, 2)
+	addSquareNodes(t, b, 100, 42, 1, 0.2)
+	addSquareNodes(t, b, 200, 41, 0, 2)
+	nodes := []osm.Node{
+		{ID: 1, Lat: 42.05, Lon: 1.05},
+		{ID: 2, Lat: 42.05, Lon: 1.07},
+		{ID: 3, Lat: 42.0501, Lon: 1.05, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "4"},
+			{Key: "addr:street", Value: "Carrer Major"},
+		}},
+	}
+	for i := range nodes {
+		b.ProcessNode(&nodes[i])
+	}
+	ways := []*osm.Way{
+		{ID: 1, Nodes: []osm.ID{1, 2}, Tags: osm.Tags{{Key: "highway", Value: "residential"}, {Key: "name", Value: "Carrer Major"}}},
+		{ID: 100, Nodes: []osm.ID{100, 101, 102, 103, 100}},
+		{ID: 200, Nodes: []osm.ID{200, 201, 202, 203, 200}},
+	}
+	for _, w := range ways {
+		b.ProcessWay(w)
+	}
+	boundary := func(id osm.ID, level, name string, way osm.ID) *osm.Relation {
+		return &osm.Relation{
+			ID: id,
+			Tags: osm.Tags{
+				{Key: "type", Value: "boundary"},
+				{Key: "boundary", Value: "administrative"},
+				{Key: "admin_level", Value: level},
+				{Key: "name", Value: name},
+			},
+			Members: []osm.Member{{Type: "way", Ref: way, Role: "outer"}},
+		}
+	}
+	b.ProcessRelation(boundary(1, "7", "Ordino", 100))
+	b.ProcessRelation(boundary(2, "4", "Pirineus", 200))
+	idx := b.GetIndex()
+
+	tests := []struct {
+		name      string
+		lat, lon  float64
+		wantLayer Layer
+		wantName  string
+		wantHouse string
+	}{
+		{"House", 42.0502, 1.0501, LayerAddress, "", "4"},
+		{"Street far from houses", 42.0505, 1.069, LayerStreet, "Carrer Major", ""},
+		{"Locality without streets", 42.15, 1.15, LayerLocality, "Ordino", ""},
+		{"Region only", 41.5, 0.5, LayerAdmin, "Pirineus", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := idx.ReverseGeocode(tt.lat, tt.lon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result == nil {
+				t.Fatal("Expected a result")
+			}
+			if result.Layer != tt.wantLayer {
+				t.Errorf("Layer = %q, want %q", result.Layer, tt.wantLayer)
+			}
+			if result.Name != tt.wantName || result.HouseNumber != tt.wantHouse {
+				t.Errorf("Got name %q and house number %q", result.Name, result.HouseNumber)
+			}
+		})
+	}
+
+	t.Run("Street distance", func(t *testing.T) {
+		result, _ := idx.ReverseGeocode(42.0505, 1.069)
+		if result.Distance > 0.06 || result.City != "Ordino" {
+			t.Errorf("Expected street in Ordino within 60 m, got %+v", result)
+		}
+	})
+
+	t.Run("Outside everything", func(t *testing.T) {
+		// The closest address is returned however far away it is
+		result, _ := idx.ReverseGeocode(10, 10)
+		if result == nil || result.Layer != LayerAddress || result.HouseNumber != "4" || result.Distance < 3000 {
+			t.Errorf("Expected the distant house number 4, got %+v", result)
+		}
+	})
+}
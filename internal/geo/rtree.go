@@ -0,0 +1,400 @@
+// internal/geo/rtree.go
+package geo
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// RTreeEntry is a geometry stored in an RTree together with its payload
+type RTreeEntry struct {
+	Geometry Geometry
+	Data     interface{}
+}
+
+// RTreeNeighbor is an entry returned by RTree.Nearest with its distance in km
+type RTreeNeighbor struct {
+	RTreeEntry
+	Distance float64
+}
+
+// RTree is a spatial index for geometries with a bounding box, such as
+// street lines and area polygons. It can be bulk loaded with BuildRTree and
+// extended afterwards with Insert.
+type RTree struct {
+	root       *rtreeNode
+	maxEntries int
+	minEntries int
+	size       int
+}
+
+type rtreeNode struct {
+	bounds   Bounds
+	children []*rtreeNode // nil for leaves
+	entries  []rtreeItem  // only set on leaves
+}
+
+// rtreeItem caches the entry bounds so geometries are not re-measured
+type rtreeItem struct {
+	bounds Bounds
+	entry  RTreeEntry
+}
+
+func (n *rtreeNode) isLeaf() bool {
+	return n.children == nil
+}
+
+// NewRTree creates an empty R-tree whose nodes hold at most maxEntries items
+func NewRTree(maxEntries int) *RTree {
+	if maxEntries < 4 {
+		maxEntries = 4
+	}
+	return &RTree{
+		root:       &rtreeNode{bounds: emptyBounds()},
+		maxEntries: maxEntries,
+		minEntries: int(math.Max(2, math.Ceil(float64(maxEntries)*0.4))),
+	}
+}
+
+// BuildRTree bulk loads entries using Sort-Tile-Recursive packing, which
+// produces well-filled nodes with little overlap
+func BuildRTree(entries []RTreeEntry, maxEntries int) *RTree {
+	t := NewRTree(maxEntries)
+	if len(entries) == 0 {
+		return t
+	}
+
+	items := make([]rtreeItem, len(entries))
+	for i, e := range entries {
+		items[i] = rtreeItem{bounds: e.Geometry.Bounds(), entry: e}
+	}
+
+	// Pack leaves first, then keep packing the resulting level until one node remains
+	var level []*rtreeNode
+	strTile(len(items), t.maxEntries,
+		func(i int) Bounds { return items[i].bounds },
+		func(less func(i, j int) bool, from, to int) {
+			sub := items[from:to]
+			sort.Slice(sub, func(i, j int) bool { return less(from+i, from+j) })
+		},
+		func(from, to int) {
+			leaf := &rtreeNode{entries: append([]rtreeItem(nil), items[from:to]...)}
+			leaf.recalculate()
+			level = append(level, leaf)
+		})
+	for len(level) > 1 {
+		nodes := level
+		level = nil
+		strTile(len(nodes), t.maxEntries,
+			func(i int) Bounds { return nodes[i].bounds },
+			func(less func(i, j int) bool, from, to int) {
+				sub := nodes[from:to]
+				sort.Slice(sub, func(i, j int) bool { return less(from+i, from+j) })
+			},
+			func(from, to int) {
+				node := &rtreeNode{children: append([]*rtreeNode(nil), nodes[from:to]...)}
+				node.recalculate()
+				level = append(level, node)
+			})
+	}
+
+	t.root = level[0]
+	t.size = len(entries)
+	return t
+}
+
+// strTile sorts n items into vertical slices by longitude and each slice by
+// latitude, calling pack for every run of at most m consecutive items
+func strTile(n, m int, bounds func(i int) Bounds, sortRange func(less func(i, j int) bool, from, to int), pack func(from, to int)) {
+	centerLon := func(i int) float64 { b := bounds(i); return b.MinLon + b.MaxLon }
+	centerLat := func(i int) float64 { b := bounds(i); return b.MinLat + b.MaxLat }
+
+	leaves := int(math.Ceil(float64(n) / float64(m)))
+	slices := int(math.Ceil(math.Sqrt(float64(leaves))))
+	sliceSize := slices * m
+
+	sortRange(func(i, j int) bool { return centerLon(i) < centerLon(j) }, 0, n)
+	for start := 0; start < n; start += sliceSize {
+		end := min(start+sliceSize, n)
+		sortRange(func(i, j int) bool { return centerLat(i) < centerLat(j) }, start, end)
+		for from := start; from < end; from += m {
+			pack(from, min(from+m, end))
+		}
+	}
+}
+
+// Size returns the number of entries in the tree
+func (t *RTree) Size() int {
+	return t.size
+}
+
+// Insert adds a single entry, splitting nodes along the way as needed
+func (t *RTree) Insert(e RTreeEntry) {
+	item := rtreeItem{bounds: e.Geometry.Bounds(), entry: e}
+	if sibling := t.insert(t.root, item); sibling != nil {
+		root := &rtreeNode{children: []*rtreeNode{t.root, sibling}}
+		root.recalculate()
+		t.root = root
+	}
+	t.size++
+}
+
+// insert adds item below node and returns a new sibling if node was split
+func (t *RTree) insert(node *rtreeNode, item rtreeItem) *rtreeNode {
+	node.bounds = node.bounds.Union(item.bounds)
+
+	if node.isLeaf() {
+		node.entries = append(node.entries, item)
+		if len(node.entries) > t.maxEntries {
+			return t.splitLeaf(node)
+		}
+		return nil
+	}
+
+	child := chooseSubtree(node.children, item.bounds)
+	if sibling := t.insert(child, item); sibling != nil {
+		node.children = append(node.children, sibling)
+		if len(node.children) > t.maxEntries {
+			return t.splitBranch(node)
+		}
+	}
+	return nil
+}
+
+// chooseSubtree picks the child needing the least enlargement, preferring
+// the smaller one on ties
+func chooseSubtree(children []*rtreeNode, b Bounds) *rtreeNode {
+	var best *rtreeNode
+	bestEnlargement, bestArea := math.Inf(1), math.Inf(1)
+	for _, child := range children {
+		area := child.bounds.area()
+		enlargement := child.bounds.Union(b).area() - area
+		if enlargement < bestEnlargement || (enlargement == bestEnlargement && area < bestArea) {
+			best, bestEnlargement, bestArea = child, enlargement, area
+		}
+	}
+	return best
+}
+
+func (t *RTree) splitLeaf(node *rtreeNode) *rtreeNode {
+	a, b := quadraticSplit(len(node.entries), t.minEntries, func(i int) Bounds { return node.entries[i].bounds })
+
+	entries := node.entries
+	node.entries = make([]rtreeItem, 0, t.maxEntries+1)
+	sibling := &rtreeNode{entries: make([]rtreeItem, 0, t.maxEntries+1)}
+	for _, i := range a {
+		node.entries = append(node.entries, entries[i])
+	}
+	for _, i := range b {
+		sibling.entries = append(sibling.entries, entries[i])
+	}
+	node.recalculate()
+	sibling.recalculate()
+	return sibling
+}
+
+func (t *RTree) splitBranch(node *rtreeNode) *rtreeNode {
+	a, b := quadraticSplit(len(node.children), t.minEntries, func(i int) Bounds { return node.children[i].bounds })
+
+	children := node.children
+	node.children = make([]*rtreeNode, 0, t.maxEntries+1)
+	sibling := &rtreeNode{children: make([]*rtreeNode, 0, t.maxEntries+1)}
+	for _, i := range a {
+		node.children = append(node.children, children[i])
+	}
+	for _, i := range b {
+		sibling.children = append(sibling.children, children[i])
+	}
+	node.recalculate()
+	sibling.recalculate()
+	return sibling
+}
+
+// quadraticSplit divides n boxes into two groups following Guttman's
+// quadratic algorithm and returns the indices of each group
+func quadraticSplit(n, minEntries int, bounds func(i int) Bounds) ([]int, []int) {
+	// Pick the pair of seeds that would waste the most area together
+	seedA, seedB, worst := 0, 1, math.Inf(-1)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			waste := bounds(i).Union(bounds(j)).area() - bounds(i).area() - bounds(j).area()
+			if waste > worst {
+				seedA, seedB, worst = i, j, waste
+			}
+		}
+	}
+
+	groupA, groupB := []int{seedA}, []int{seedB}
+	boundsA, boundsB := bounds(seedA), bounds(seedB)
+	assigned := make([]bool, n)
+	assigned[seedA], assigned[seedB] = true, true
+
+	for remaining := n - 2; remaining > 0; remaining-- {
+		// Make sure both groups can still reach the minimum fill
+		if len(groupA)+remaining == minEntries {
+			for i := 0; i < n; i++ {
+				if !assigned[i] {
+					groupA = append(groupA, i)
+				}
+			}
+			break
+		}
+		if len(groupB)+remaining == minEntries {
+			for i := 0; i < n; i++ {
+				if !assigned[i] {
+					groupB = append(groupB, i)
+				}
+			}
+			break
+		}
+
+		// Assign the entry with the strongest preference for one group
+		next, nextDiff := -1, math.Inf(-1)
+		var growA, growB float64
+		for i := 0; i < n; i++ {
+			if assigned[i] {
+				continue
+			}
+			da := boundsA.Union(bounds(i)).area() - boundsA.area()
+			db := boundsB.Union(bounds(i)).area() - boundsB.area()
+			if diff := math.Abs(da - db); diff > nextDiff {
+				next, nextDiff, growA, growB = i, diff, da, db
+			}
+		}
+
+		assigned[next] = true
+		if growA < growB || (growA == growB && len(groupA) <= len(groupB)) {
+			groupA = append(groupA, next)
+			boundsA = boundsA.Union(bounds(next))
+		} else {
+			groupB = append(groupB, next)
+			boundsB = boundsB.Union(bounds(next))
+		}
+	}
+	return groupA, groupB
+}
+
+func (n *rtreeNode) recalculate() {
+	n.bounds = emptyBounds()
+	for _, e := range n.entries {
+		n.bounds = n.bounds.Union(e.bounds)
+	}
+	for _, c := range n.children {
+		n.bounds = n.bounds.Union(c.bounds)
+	}
+}
+
+// Search returns all entries whose bounding box intersects bounds
+func (t *RTree) Search(bounds Bounds) []RTreeEntry {
+	var results []RTreeEntry
+	t.search(t.root, bounds, func(item rtreeItem) {
+		results = append(results, item.entry)
+	})
+	return results
+}
+
+func (t *RTree) search(node *rtreeNode, bounds Bounds, visit func(rtreeItem)) {
+	if node.bounds.IsEmpty() || !node.bounds.Intersects(bounds) {
+		return
+	}
+	for _, item := range node.entries {
+		if item.bounds.Intersects(bounds) {
+			visit(item)
+		}
+	}
+	for _, child := range node.children {
+		t.search(child, bounds, visit)
+	}
+}
+
+// Containing returns all entries whose geometry contains c
+func (t *RTree) Containing(c Coord) []RTreeEntry {
+	var results []RTreeEntry
+	t.search(t.root, c.Bounds(), func(item rtreeItem) {
+		if item.entry.Geometry.Contains(c) {
+			results = append(results, item.entry)
+		}
+	})
+	return results
+}
+
+// Nearest returns up to k entries closest to c by distance to their actual
+// geometry, ignoring entries further than maxDistanceKm. If filter is
+// non-nil only entries satisfying it are considered.
+func (t *RTree) Nearest(c Coord, k int, maxDistanceKm float64, filter func(RTreeEntry) bool) []RTreeNeighbor {
+	if k <= 0 || t.size == 0 {
+		return nil
+	}
+
+	queue := &rtreeQueue{{node: t.root, distance: boundsDistance(t.root.bounds, c)}}
+	results := make([]RTreeNeighbor, 0, k)
+	for queue.Len() > 0 && len(results) < k {
+		item := heap.Pop(queue).(rtreeQueueItem)
+		if item.distance > maxDistanceKm {
+			break
+		}
+		if item.node == nil {
+			results = append(results, RTreeNeighbor{RTreeEntry: item.entry, Distance: item.distance})
+			continue
+		}
+		for _, e := range item.node.entries {
+			if filter != nil && !filter(e.entry) {
+				continue
+			}
+			// Cheap box distance first, exact geometry distance only if it can qualify
+			if boundsDistance(e.bounds, c) > maxDistanceKm {
+				continue
+			}
+			if dist := e.entry.Geometry.Distance(c); dist <= maxDistanceKm {
+				heap.Push(queue, rtreeQueueItem{entry: e.entry, distance: dist})
+			}
+		}
+		for _, child := range item.node.children {
+			if dist := boundsDistance(child.bounds, c); dist <= maxDistanceKm {
+				heap.Push(queue, rtreeQueueItem{node: child, distance: dist})
+			}
+		}
+	}
+	return results
+}
+
+type rtreeQueueItem struct {
+	node     *rtreeNode
+	entry    RTreeEntry
+	distance float64
+}
+
+type rtreeQueue []rtreeQueueItem
+
+func (q rtreeQueue) Len() int { return len(q) }
+func (q rtreeQueue) Less(i, j int) bool {
+	if q[i].distance == q[j].distance {
+		return q[i].node == nil && q[j].node != nil
+	}
+	return q[i].distance < q[j].distance
+}
+func (q rtreeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *rtreeQueue) Push(x interface{}) { *q = append(*q, x.(rtreeQueueItem)) }
+func (q *rtreeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// All returns every entry in the tree
+func (t *RTree) All() []RTreeEntry {
+	results := make([]RTreeEntry, 0, t.size)
+	var walk func(node *rtreeNode)
+	walk = func(node *rtreeNode) {
+		for _, item := range node.entries {
+			results = append(results, item.entry)
+		}
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(t.root)
+	return results
+}
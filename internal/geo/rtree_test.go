@@ -0,0 +1,109 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomSquares(n int) []RTreeEntry {
+	rng := rand.New(rand.NewSource(7))
+	entries := make([]RTreeEntry, n)
+	for i := range entries {
+		size := 0.001 + rng.Float64()*0.01
+		entries[i] = RTreeEntry{
+			Geometry: Polygon{square(42.4+rng.Float64()*0.3, 1.4+rng.Float64()*0.4, size)},
+			Data:     i,
+		}
+	}
+	return entries
+}
+
+func sortedData(entries []RTreeEntry) []int {
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Data.(int)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestRTree_SearchMatchesBruteForce(t *testing.T) {
+	entries := randomSquares(3000)
+	bulk := BuildRTree(entries, 16)
+	dynamic := NewRTree(16)
+	for _, e := range entries {
+		dynamic.Insert(e)
+	}
+
+	if bulk.Size() != 3000 || dynamic.Size() != 3000 {
+		t.Fatalf("Unexpected sizes %d and %d", bulk.Size(), dynamic.Size())
+	}
+
+	query := Bounds{MinLat: 42.5, MaxLat: 42.55, MinLon: 1.5, MaxLon: 1.6}
+	var want []RTreeEntry
+	for _, e := range entries {
+		if e.Geometry.Bounds().Intersects(query) {
+			want = append(want, e)
+		}
+	}
+
+	for name, tree := range map[string]*RTree{"bulk": bulk, "dynamic": dynamic} {
+		got := sortedData(tree.Search(query))
+		if len(got) != len(want) {
+			t.Fatalf("%s: Search() returned %d entries, want %d", name, len(got), len(want))
+		}
+		for i, id := range sortedData(want) {
+			if got[i] != id {
+				t.Fatalf("%s: Search() entry %d = %d, want %d", name, i, got[i], id)
+			}
+		}
+	}
+}
+
+func TestRTree_Containing(t *testing.T) {
+	tree := NewRTree(4)
+	tree.Insert(RTreeEntry{Geometry: Polygon{square(42, 1, 1)}, Data: "outer"})
+	tree.Insert(RTreeEntry{Geometry: Polygon{square(42.2, 1.2, 0.2)}, Data: "inner"})
+	tree.Insert(RTreeEntry{Geometry: LineString{{Lat: 42, Lon: 1}, {Lat: 43, Lon: 2}}, Data: "line"})
+
+	got := tree.Containing(Coord{Lat: 42.3, Lon: 1.3})
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 containing polygons, got %d", len(got))
+	}
+	if got := tree.Containing(Coord{Lat: 42.9, Lon: 1.1}); len(got) != 1 || got[0].Data != "outer" {
+		t.Errorf("Expected only the outer polygon, got %v", got)
+	}
+}
+
+func TestRTree_Nearest(t *testing.T) {
+	entries := randomSquares(1000)
+	tree := BuildRTree(entries, 8)
+	origin := Coord{Lat: 42.55, Lon: 1.6}
+
+	sorted := append([]RTreeEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Geometry.Distance(origin) < sorted[j].Geometry.Distance(origin)
+	})
+
+	got := tree.Nearest(origin, 5, 1000, nil)
+	if len(got) != 5 {
+		t.Fatalf("Expected 5 neighbours, got %d", len(got))
+	}
+	for i, n := range got {
+		if n.Distance != sorted[i].Geometry.Distance(origin) {
+			t.Errorf("Neighbour %d distance = %f, want %f", i, n.Distance, sorted[i].Geometry.Distance(origin))
+		}
+	}
+
+	odd := func(e RTreeEntry) bool { return e.Data.(int)%2 == 1 }
+	for _, n := range tree.Nearest(origin, 5, 1000, odd) {
+		if n.Data.(int)%2 != 1 {
+			t.Errorf("Filter not applied, got %v", n.Data)
+		}
+	}
+
+	if got := tree.Nearest(Coord{Lat: 0, Lon: 0}, 1, 10, nil); len(got) != 0 {
+		t.Errorf("Expected no neighbours within 10 km, got %d", len(got))
+	}
+}
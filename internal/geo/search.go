@@ -0,0 +1,345 @@
+// internal/geo/search.go
+package geo
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sebastiaanwouters/geodude/internal/address"
+)
+
+// Weights of the query components when scoring a candidate. Only components
+// present in the query count, so a query without a postcode is not penalised.
+const (
+	streetWeight   = 0.5
+	houseWeight    = 0.3
+	localityWeight = 0.15
+	postcodeWeight = 0.05
+)
+
+const (
+	// minSearchScore is the score below which candidates are not returned
+	minSearchScore = 0.5
+
+	// minStreetSimilarity is the street name similarity an address or street
+	// needs to be considered at all, so that matching house numbers cannot
+	// make up for the wrong street
+	minStreetSimilarity = 0.75
+)
+
+// parsedQuery holds the components recognised in a free-text query
+type parsedQuery struct {
+	street      string // Remaining text, assumed to name a street
+	houseNumber string
+	postcode    string
+	locality    string
+}
+
+// SearchOptions tune a search query
+type SearchOptions struct {
+	Limit    int     // Maximum number of results, all if zero
+	Focus    *Coord  // Results near this point rank higher
+	Viewport *Bounds // Results inside this area rank higher
+}
+
+// Search geocodes a free-text query such as "Carrer Major 12, Andorra la
+// Vella" and returns up to limit candidates ordered by descending confidence.
+// Candidates are addresses, streets, POIs, places and administrative areas.
+func (idx *GeoIndex) Search(query string, limit int) []GeocodeResult {
+	return idx.SearchWithOptions(query, SearchOptions{Limit: limit})
+}
+
+// SearchWithOptions is Search biased towards a focus point or viewport.
+// Results are ranked by how well they match the query, the importance of
+// the feature, its proximity to the focus point and viewport, and how
+// complete its address is.
+func (idx *GeoIndex) SearchWithOptions(query string, opts SearchOptions) []GeocodeResult {
+	q := idx.parseQuery(query)
+	if q.street == "" && q.locality == "" && q.postcode == "" {
+		return nil
+	}
+
+	var results []GeocodeResult
+	results = append(results, idx.searchAddresses(q)...)
+	results = append(results, idx.searchStreets(q)...)
+	results = append(results, idx.searchPOIs(q)...)
+	results = append(results, idx.searchPlaces(q)...)
+	results = append(results, idx.searchAreas(q)...)
+
+	return rank(results, Bias{Focus: opts.Focus, Viewport: opts.Viewport}, opts.Limit)
+}
+
+// parseQuery splits a query into street, house number, postcode and
+// locality, recognising the localities known to the index
+func (idx *GeoIndex) parseQuery(query string) parsedQuery {
+	localities := idx.localityNames()
+	c := address.ParseWithOptions(query, address.Options{
+		Locality: func(text string) (string, bool) {
+			return matchLocality(text, localities)
+		},
+	})
+	return parsedQuery{
+		street:      strings.ToLower(c.Street),
+		houseNumber: strings.ToLower(c.HouseNumber),
+		postcode:    strings.ToLower(c.PostCode),
+		locality:    c.City,
+	}
+}
+
+// localityNames returns the names of all known cities and administrative
+// areas keyed by their normalized form
+func (idx *GeoIndex) localityNames() map[string]string {
+	return idx.textIndex().localities
+}
+
+// matchLocality returns the known locality closest to text if it is similar enough
+func matchLocality(text string, localities map[string]string) (string, bool) {
+	normalized := normalizeString(text)
+	if normalized == "" {
+		return "", false
+	}
+	if name, ok := localities[normalized]; ok {
+		return name, true
+	}
+	best, bestSim := "", 0.0
+	for key, name := range localities {
+		if sim := calculateSimilarity(normalized, key); sim > bestSim {
+			best, bestSim = name, sim
+		}
+	}
+	return best, bestSim >= 0.8
+}
+
+// nameSimilarity compares a query text with a name word by word, so that
+// "major" matches "Carrer Major" better than an unrelated name of similar
+// length. It returns a value between 0 and 1.
+func nameSimilarity(query, name string) float64 {
+	queryWords := withoutStopwords(strings.Fields(normalizeName(query)))
+	nameWords := withoutStopwords(strings.Fields(normalizeName(name)))
+	if len(queryWords) == 0 || len(nameWords) == 0 {
+		return 0
+	}
+
+	whole := calculateSimilarity(normalizeString(query), normalizeString(name))
+
+	// How well each query word is covered by some word of the name, weighted
+	// by length so that short words matter less
+	var covered, totalLength float64
+	for _, qw := range queryWords {
+		best := 0.0
+		for _, nw := range nameWords {
+			best = math.Max(best, calculateSimilarity(qw, nw))
+		}
+		length := float64(utf8.RuneCountInString(qw))
+		covered += best * length
+		totalLength += length
+	}
+	covered /= totalLength
+
+	// Penalise names with many words the query does not mention
+	coverage := math.Min(1, float64(len(queryWords))/float64(len(nameWords)))
+	return math.Max(whole, covered*(0.7+0.3*coverage))
+}
+
+// componentScore combines per-component similarities of the components
+// present in the query into a score between 0 and 1
+func componentScore(q parsedQuery, street, house, locality, postcode float64) float64 {
+	var total, weight float64
+	if q.street != "" {
+		total += streetWeight * street
+		weight += streetWeight
+	}
+	if q.houseNumber != "" {
+		total += houseWeight * house
+		weight += houseWeight
+	}
+	if q.locality != "" {
+		total += localityWeight * locality
+		weight += localityWeight
+	}
+	if q.postcode != "" {
+		total += postcodeWeight * postcode
+		weight += postcodeWeight
+	}
+	if weight == 0 {
+		return 0
+	}
+	return total / weight
+}
+
+func equalScore(a, b string) float64 {
+	if a != "" && normalizeString(a) == normalizeString(b) {
+		return 1
+	}
+	return 0
+}
+
+func (idx *GeoIndex) searchAddresses(q parsedQuery) []GeocodeResult {
+	if q.street == "" || q.houseNumber == "" {
+		return nil
+	}
+
+	var results []GeocodeResult
+	for _, addr := range idx.textIndex().candidateAddresses(q.street) {
+		house, ok := houseNumberScore(q.houseNumber, addr.HouseNumber)
+		if !ok {
+			house = equalScore(q.houseNumber, addr.HouseNumber)
+		}
+		// Only the queried number or one sharing it, such as 12a for 12
+		if house < suffixHouseScore {
+			continue
+		}
+		streetSim := bestNameSimilarity(q.street, addr.Street, addr.StreetNames)
+		if streetSim < minStreetSimilarity {
+			continue
+		}
+		score := componentScore(q, streetSim, house, equalScore(q.locality, addr.City), equalScore(q.postcode, addr.PostCode))
+		if score >= minSearchScore {
+			result := addressResult(addr)
+			result.Score = score
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (idx *GeoIndex) searchStreets(q parsedQuery) []GeocodeResult {
+	if q.street == "" {
+		return nil
+	}
+
+	var results []GeocodeResult
+	for _, s := range idx.textIndex().candidateStreets(q.street) {
+		streetSim := bestNameSimilarity(q.street, s.Name, s.Names)
+		if streetSim < minStreetSimilarity {
+			continue
+		}
+		// A street only partially answers a query with a house number
+		score := componentScore(q, streetSim, 0, equalScore(q.locality, s.City), 0)
+		if score < minSearchScore*componentScore(q, 1, 0, 1, 0) {
+			continue
+		}
+		result := s.result()
+		result.Score = score
+		results = append(results, result)
+	}
+	return results
+}
+
+// categoryMatchScore is the score of POIs found by a category name such as
+// "pharmacies", just below that of exact name matches
+const categoryMatchScore = 0.9
+
+// searchPOIs finds POIs by name, or by category for queries such as
+// "pharmacy" or "pharmacies in Ordino"
+func (idx *GeoIndex) searchPOIs(q parsedQuery) []GeocodeResult {
+	if q.street == "" || q.houseNumber != "" || idx.POIs == nil {
+		return nil
+	}
+
+	var results []GeocodeResult
+	keyword := strings.TrimSpace(q.street)
+	for _, suffix := range []string{" in", " near"} {
+		keyword = strings.TrimSuffix(keyword, suffix)
+	}
+	if category, ok := CategoryFor(keyword); ok {
+		for _, e := range idx.POIs.All() {
+			p := e.Data.(*POI)
+			if !p.Category.Is(category) || (q.locality != "" && equalScore(q.locality, p.City) == 0) {
+				continue
+			}
+			result := p.result()
+			result.Score = categoryMatchScore
+			results = append(results, result)
+		}
+		return results
+	}
+
+	for _, p := range idx.textIndex().candidatePOIs(q.street) {
+		nameSim := bestNameSimilarity(q.street, p.Name, p.Names)
+		if nameSim < minStreetSimilarity {
+			continue
+		}
+		if score := componentScore(q, nameSim, 0, equalScore(q.locality, p.City), 0); score >= minSearchScore {
+			result := p.result()
+			result.Score = score
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// areaQueryText returns the text naming a place or area in a query that
+// consists of a locality name only, or "" for other queries
+func areaQueryText(q parsedQuery) string {
+	if q.houseNumber != "" || (q.street != "" && q.locality != "") {
+		return ""
+	}
+	if q.street != "" {
+		return q.street
+	}
+	return q.locality
+}
+
+// searchPlaces finds cities, towns, villages and their parts by name. A query
+// naming both a suburb and its city, such as "Santa Coloma, Andorra la
+// Vella", finds the suburb.
+func (idx *GeoIndex) searchPlaces(q parsedQuery) []GeocodeResult {
+	if q.houseNumber != "" || idx.Places == nil {
+		return nil
+	}
+	text := areaQueryText(q)
+	if text == "" {
+		text = q.street
+	}
+	if text == "" {
+		return nil
+	}
+
+	var results []GeocodeResult
+	for _, p := range idx.textIndex().candidatePlaces(text) {
+		score := 0.0
+		for _, name := range withVariants(p.Name, p.Names) {
+			score = math.Max(score, calculateSimilarity(normalizeString(text), normalizeString(name)))
+		}
+		result := idx.placeResult(p)
+		if q.street != "" && q.locality != "" {
+			score = componentScore(q, score, 0, equalScore(q.locality, result.City), 0)
+		}
+		if score < minSearchScore {
+			continue
+		}
+		result.Score = score
+		results = append(results, result)
+	}
+	return results
+}
+
+func (idx *GeoIndex) searchAreas(q parsedQuery) []GeocodeResult {
+	// Areas answer queries that consist of a locality name only
+	text := areaQueryText(q)
+	if text == "" || idx.AdminAreas == nil {
+		return nil
+	}
+
+	var results []GeocodeResult
+	for _, e := range idx.AdminAreas.All() {
+		area := e.Data.(*AdminArea)
+		if area.Place != nil {
+			// Found as a place instead
+			continue
+		}
+		score := 0.0
+		for _, name := range withVariants(area.Name, area.Names) {
+			score = math.Max(score, calculateSimilarity(normalizeString(text), normalizeString(name)))
+		}
+		if score < minSearchScore {
+			continue
+		}
+		result := idx.adminAreaResult(area)
+		result.Score = score
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,125 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func newSearchTestIndex(t *testing.T) *GeoIndex {
+	t.Helper()
+	b := NewGeoBuilder()
+	nodes := []osm.Node{
+		{ID: 1, Lat: 42.507, Lon: 1.521},
+		{ID: 2, Lat: 42.508, Lon: 1.523},
+		{ID: 3, Lat: 42.556, Lon: 1.533},
+		{ID: 4, Lat: 42.557, Lon: 1.535},
+		{ID: 10, Lat: 42.5075, Lon: 1.5215, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "12"},
+			{Key: "addr:street", Value: "Carrer Major"},
+			{Key: "addr:postcode", Value: "AD500"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+		{ID: 11, Lat: 42.5565, Lon: 1.5335, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "12"},
+			{Key: "addr:street", Value: "Carrer Major"},
+			{Key: "addr:postcode", Value: "AD300"},
+			{Key: "addr:city", Value: "Ordino"},
+		}},
+		{ID: 12, Lat: 42.5566, Lon: 1.5336, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "3"},
+			{Key: "addr:street", Value: "Carrer Major"},
+			{Key: "addr:postcode", Value: "AD300"},
+			{Key: "addr:city", Value: "Ordino"},
+		}},
+	}
+	for i := range nodes {
+		b.ProcessNode(&nodes[i])
+	}
+	ways := []*osm.Way{
+		{ID: 1, Nodes: []osm.ID{1, 2}, Tags: osm.Tags{
+			{Key: "highway", Value: "residential"},
+			{Key: "name", Value: "Carrer Major"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+		{ID: 2, Nodes: []osm.ID{3, 4}, Tags: osm.Tags{
+			{Key: "highway", Value: "residential"},
+			{Key: "name", Value: "Carrer Major"},
+			{Key: "addr:city", Value: "Ordino"},
+		}},
+	}
+	for _, w := range ways {
+		b.ProcessWay(w)
+	}
+	return b.GetIndex()
+}
+
+func TestParseQuery(t *testing.T) {
+	idx := newSearchTestIndex(t)
+
+	tests := []struct {
+		query string
+		want  parsedQuery
+	}{
+		{"Carrer Major 12, Andorra la Vella", parsedQuery{street: "carrer major", houseNumber: "12", locality: "Andorra la Vella"}},
+		{"12 Carrer Major Ordino", parsedQuery{street: "carrer major", houseNumber: "12", locality: "Ordino"}},
+		{"Carrer Major 3, AD300", parsedQuery{street: "carrer major", houseNumber: "3", postcode: "ad300"}},
+		{"Ordno", parsedQuery{locality: "Ordino"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.parseQuery(tt.query); got != tt.want {
+				t.Errorf("parseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := newSearchTestIndex(t)
+
+	t.Run("Address with locality", func(t *testing.T) {
+		results := idx.Search("Carrer Major 12, Andorra la Vella", 5)
+		if len(results) < 2 {
+			t.Fatalf("Expected several candidates, got %d", len(results))
+		}
+		top := results[0]
+		if top.Layer != LayerAddress || top.City != "Andorra la Vella" || top.HouseNumber != "12" {
+			t.Errorf("Unexpected top result %+v", top)
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].Score > results[i-1].Score {
+				t.Errorf("Results not sorted by score at %d", i)
+			}
+		}
+		if results[1].Score >= top.Score {
+			t.Errorf("Expected the address in Ordino to rank lower, got %f >= %f", results[1].Score, top.Score)
+		}
+	})
+
+	t.Run("Misspelled street", func(t *testing.T) {
+		results := idx.Search("carer majr 3", 1)
+		if len(results) != 1 || results[0].HouseNumber != "3" {
+			t.Errorf("Expected Carrer Major 3, got %+v", results)
+		}
+	})
+
+	t.Run("Street without house number", func(t *testing.T) {
+		results := idx.Search("Carrer Major, Ordino", 1)
+		if len(results) != 1 || results[0].Layer != LayerStreet || results[0].City != "Ordino" {
+			t.Errorf("Expected the street in Ordino, got %+v", results)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		if results := idx.Search("Carrer Major", 1); len(results) != 1 {
+			t.Errorf("Expected 1 result, got %d", len(results))
+		}
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		if results := idx.Search("Rue de Rivoli 5", 5); len(results) != 0 {
+			t.Errorf("Expected no results, got %+v", results)
+		}
+	})
+}
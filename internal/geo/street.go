@@ -0,0 +1,245 @@
+// internal/geo/street.go
+package geo
+
+import (
+	"math"
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+// Street is a named road merged from all ways that share its name within one
+// locality
+type Street struct {
+	Name     string
+	Names    Names
+	City     string
+	Geometry MultiLineString
+}
+
+// Center returns the point halfway along the street's longest piece
+func (s *Street) Center() Coord {
+	var longest LineString
+	longestLength := -1.0
+	for _, l := range s.Geometry {
+		if length := l.Length(); length > longestLength {
+			longest, longestLength = l, length
+		}
+	}
+	return longest.Interpolate(0.5)
+}
+
+// streetWay is a named street way waiting to be assigned a locality
+type streetWay struct {
+	name  string
+	names Names
+	city  string
+	line  LineString
+}
+
+// makeStreetNameKey is the key for GeoIndex.Streets
+func makeStreetNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// geocodeStreet returns the center and extent of the longest street with the
+// given name, or nil if there is none
+func (idx *GeoIndex) geocodeStreet(name, city string) *GeocodeResult {
+	var best *Street
+	bestLength := -1.0
+	for _, s := range idx.FindStreets(name, city) {
+		if length := s.Geometry.Length(); length > bestLength {
+			best, bestLength = s, length
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	result := best.result()
+	return &result
+}
+
+// result describes the street as a geocoding result located at its center
+func (s *Street) result() GeocodeResult {
+	center := s.Center()
+	extent := s.Geometry.Bounds()
+	return GeocodeResult{
+		Address:    Address{Street: s.Name, City: s.City, Lat: center.Lat, Lon: center.Lon},
+		Name:       s.Name,
+		Names:      s.Names,
+		Layer:      LayerStreet,
+		Importance: streetImportance,
+		Extent:     &extent,
+	}
+}
+
+// FindStreets returns the streets with the given name, or with it as one of
+// their variants, restricted to a city when one is given
+func (idx *GeoIndex) FindStreets(name, city string) []*Street {
+	candidates, exists := idx.Streets[makeStreetNameKey(name)]
+	if !exists {
+		t := idx.textIndex()
+		if id, ok := t.names.Lookup(name); ok {
+			candidates = t.streets[id]
+		}
+	}
+
+	var streets []*Street
+	for _, s := range candidates {
+		if city == "" || strings.EqualFold(s.City, city) {
+			streets = append(streets, s)
+		}
+	}
+	return streets
+}
+
+// shareStreetNames gives addresses without street name variants those of the
+// street they are on, so that "Calle Mayor 12" finds an address tagged
+// "Carrer Major"
+func (idx *GeoIndex) shareStreetNames() {
+	for _, addr := range idx.Addresses {
+		if !addr.StreetNames.IsEmpty() {
+			continue
+		}
+		for _, s := range idx.Streets[makeStreetNameKey(addr.Street)] {
+			if strings.EqualFold(s.City, addr.City) && !s.Names.IsEmpty() {
+				// Shared rather than copied; names are not modified after building
+				addr.StreetNames = s.Names
+				break
+			}
+		}
+	}
+}
+
+// NearestStreet returns the street closest to the coordinate by distance to
+// its line segments, or nil if none is within maxDistanceKm
+func (idx *GeoIndex) NearestStreet(lat, lon, maxDistanceKm float64) (*Street, float64) {
+	if idx.StreetLines == nil {
+		return nil, math.Inf(1)
+	}
+	nearest := idx.StreetLines.Nearest(Coord{Lat: lat, Lon: lon}, 1, maxDistanceKm, nil)
+	if len(nearest) == 0 {
+		return nil, math.Inf(1)
+	}
+	return nearest[0].Data.(*Street), nearest[0].Distance
+}
+
+// processStreet queues a named street way; streets are merged per locality
+// once the administrative boundaries are known
+func (b *GeoBuilder) processStreet(way *osm.Way, name string) {
+	line := make(LineString, 0, len(way.Nodes))
+	for _, id := range way.Nodes {
+		if lat, lon, exists := b.nodes.Get(id); exists {
+			line = append(line, Coord{Lat: lat, Lon: lon})
+		}
+	}
+	if len(line) < 2 {
+		return
+	}
+	b.streets = append(b.streets, streetWay{
+		name:  name,
+		names: namesFromTags(way.Tags, "name"),
+		city:  way.Tags.Get("addr:city"),
+		line:  line,
+	})
+}
+
+// resolveStreets merges the queued street ways into the index
+func (b *GeoBuilder) resolveStreets() {
+	type group struct {
+		name, city string
+		names      Names
+		lines      []LineString
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, sw := range b.streets {
+		city := sw.city
+		if city == "" {
+			mid := sw.line.Interpolate(0.5)
+			city = b.index.cityAt(mid, b.index.AdminAreasAt(mid.Lat, mid.Lon))
+		}
+		key := makeStreetNameKey(sw.name) + ":" + strings.ToLower(city)
+		g, exists := groups[key]
+		if !exists {
+			g = &group{name: sw.name, city: city}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.names.merge(sw.names)
+		g.lines = append(g.lines, sw.line)
+	}
+	b.streets = nil
+
+	for _, key := range order {
+		g := groups[key]
+		nameKey := makeStreetNameKey(g.name)
+
+		var street *Street
+		for _, s := range b.index.Streets[nameKey] {
+			if strings.EqualFold(s.City, g.city) {
+				street = s
+				break
+			}
+		}
+		if street == nil {
+			street = &Street{Name: g.name, City: g.city}
+			b.index.Streets[nameKey] = append(b.index.Streets[nameKey], street)
+		}
+		street.Names.merge(g.names)
+
+		for _, line := range mergeLines(g.lines) {
+			street.Geometry = append(street.Geometry, line)
+			b.index.StreetLines.Insert(RTreeEntry{Geometry: line, Data: street})
+		}
+		b.index.changed()
+	}
+}
+
+// mergeLines joins lines that share an end point into longer lines
+func mergeLines(lines []LineString) []LineString {
+	used := make([]bool, len(lines))
+	var merged []LineString
+
+	for start := range lines {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		line := append(LineString(nil), lines[start]...)
+
+		for extended := true; extended; {
+			extended = false
+			for i, other := range lines {
+				if used[i] {
+					continue
+				}
+				first, last := line[0], line[len(line)-1]
+				switch {
+				case other[0] == last:
+					line = append(line, other[1:]...)
+				case other[len(other)-1] == last:
+					for j := len(other) - 2; j >= 0; j-- {
+						line = append(line, other[j])
+					}
+				case other[len(other)-1] == first:
+					line = append(append(LineString(nil), other[:len(other)-1]...), line...)
+				case other[0] == first:
+					prefix := make(LineString, 0, len(other)+len(line))
+					for j := len(other) - 1; j > 0; j-- {
+						prefix = append(prefix, other[j])
+					}
+					line = append(prefix, line...)
+				default:
+					continue
+				}
+				used[i] = true
+				extended = true
+			}
+		}
+		merged = append(merged, line)
+	}
+	return merged
+}
@@ -0,0 +1,86 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func TestStreets(t *testing.T) {
+	b := NewGeoBuilder()
+	nodes := []osm.Node{
+		// An L-shaped street split over two ways
+		{ID: 1, Lat: 42.0, Lon: 1.0},
+		{ID: 2, Lat: 42.0, Lon: 1.01},
+		{ID: 3, Lat: 42.01, Lon: 1.01},
+		// A street with the same name in another town
+		{ID: 4, Lat: 43.0, Lon: 2.0},
+		{ID: 5, Lat: 43.0, Lon: 2.01},
+	}
+	for i := range nodes {
+		b.ProcessNode(&nodes[i])
+	}
+
+	street := func(id osm.ID, city string, nodes ...osm.ID) *osm.Way {
+		tags := osm.Tags{{Key: "highway", Value: "residential"}, {Key: "name", Value: "Carrer Major"}}
+		if city != "" {
+			tags = append(tags, osm.Tag{Key: "addr:city", Value: city})
+		}
+		return &osm.Way{ID: id, Nodes: nodes, Tags: tags}
+	}
+	ways := []*osm.Way{
+		street(10, "Ordino", 1, 2),
+		street(11, "Ordino", 3, 2),
+		street(12, "Canillo", 4, 5),
+	}
+	for _, w := range ways {
+		if err := b.ProcessWay(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := b.GetIndex()
+
+	t.Run("Merged per locality", func(t *testing.T) {
+		if got := idx.FindStreets("carrer major", ""); len(got) != 2 {
+			t.Fatalf("Expected 2 streets, got %d", len(got))
+		}
+		ordino := idx.FindStreets("Carrer Major", "Ordino")
+		if len(ordino) != 1 {
+			t.Fatalf("Expected 1 street in Ordino, got %d", len(ordino))
+		}
+		if len(ordino[0].Geometry) != 1 || len(ordino[0].Geometry[0]) != 3 {
+			t.Errorf("Expected the two ways joined into one line, got %v", ordino[0].Geometry)
+		}
+	})
+
+	t.Run("Nearest by segment distance", func(t *testing.T) {
+		// Right next to the corner, far from the mean of the node coordinates
+		s, dist := idx.NearestStreet(42.0001, 1.0099, 1)
+		if s == nil || s.City != "Ordino" {
+			t.Fatalf("Expected the street in Ordino, got %+v", s)
+		}
+		if dist > 0.02 {
+			t.Errorf("Expected a distance of about 11 m, got %f km", dist)
+		}
+		if s, _ := idx.NearestStreet(0, 0, 1); s != nil {
+			t.Errorf("Expected no street near (0, 0), got %+v", s)
+		}
+	})
+
+	t.Run("Geocode without house number", func(t *testing.T) {
+		result, err := idx.Geocode("Carrer Major", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Extent == nil {
+			t.Fatal("Expected the street extent")
+		}
+		// The longer street in Ordino is chosen; halfway along it lies on the longer, northbound leg
+		if result.City != "Ordino" || result.Lat <= 42.0 || !almostEqual(result.Lon, 1.01, 1e-9) {
+			t.Errorf("Unexpected result %+v", result.Address)
+		}
+		if result.Extent.MaxLat != 42.01 || result.Extent.MinLon != 1.0 {
+			t.Errorf("Unexpected extent %+v", *result.Extent)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+// internal/geo/structured.go
+package geo
+
+import "strings"
+
+// stateMinLevel and stateMaxLevel bound the admin levels of states,
+// provinces and regions
+const (
+	stateMinLevel = 3
+	stateMaxLevel = 6
+)
+
+// StructuredQuery is a query split into its components, such as from an
+// address form. All fields are optional. The most specific component given
+// is what is searched for; the broader ones only restrict where, so that
+// Street "Carrer Major" with City "Ordino" finds the street in Ordino only.
+type StructuredQuery struct {
+	Name        string // Name of a POI or place, searched when no street is given
+	HouseNumber string
+	Street      string
+	PostCode    string
+	City        string
+	State       string // State, province or region
+	Country     string // Country name or ISO 3166-1 alpha-2 code
+
+	Bounds       *Bounds  // Only results inside this area
+	CountryCodes []string // Only results in these countries, by ISO 3166-1 alpha-2 code
+	Layers       []Layer  // Only results in these layers
+
+	Limit int    // Maximum number of results, all if zero
+	Focus *Coord // Results near this point rank higher
+}
+
+// GeocodeStructured returns the features matching a structured query,
+// ranked like SearchWithOptions
+func (idx *GeoIndex) GeocodeStructured(sq StructuredQuery) []GeocodeResult {
+	q := idx.structuredQuery(sq)
+	if q.street == "" && q.locality == "" && q.postcode == "" {
+		return nil
+	}
+
+	var results []GeocodeResult
+	results = append(results, idx.searchAddresses(q)...)
+	if len(results) == 0 {
+		results = append(results, idx.interpolatedAddresses(q)...)
+	}
+	results = append(results, idx.searchStreets(q)...)
+	results = append(results, idx.searchPOIs(q)...)
+	results = append(results, idx.searchPlaces(q)...)
+	results = append(results, idx.searchAreas(q)...)
+
+	kept := results[:0]
+	for _, r := range results {
+		if idx.matchesStructured(r, sq) {
+			kept = append(kept, r)
+		}
+	}
+	return rank(kept, Bias{Focus: sq.Focus}, sq.Limit)
+}
+
+// structuredQuery returns the text query for the most specific component of
+// a structured query. Without a street or name, the city, state or country
+// is searched for as a locality.
+func (idx *GeoIndex) structuredQuery(sq StructuredQuery) parsedQuery {
+	q := parsedQuery{
+		street:      strings.ToLower(strings.TrimSpace(sq.Street)),
+		houseNumber: strings.ToLower(strings.TrimSpace(sq.HouseNumber)),
+		postcode:    strings.ToLower(strings.TrimSpace(sq.PostCode)),
+	}
+	if q.street == "" && q.houseNumber == "" {
+		q.street = strings.ToLower(strings.TrimSpace(sq.Name))
+	}
+	// The state and country are only searched for themselves; as filters
+	// they do not count towards the score
+	localities := []string{sq.City}
+	if q.street == "" && q.houseNumber == "" && q.postcode == "" {
+		localities = append(localities, sq.State, sq.Country)
+	}
+	for _, locality := range localities {
+		if locality = strings.TrimSpace(locality); locality != "" {
+			if name, ok := matchLocality(locality, idx.localityNames()); ok {
+				locality = name
+			}
+			q.locality = locality
+			break
+		}
+	}
+	return q
+}
+
+// interpolatedAddresses returns the house number of the query interpolated
+// along an address range of its street
+func (idx *GeoIndex) interpolatedAddresses(q parsedQuery) []GeocodeResult {
+	if q.street == "" || q.houseNumber == "" {
+		return nil
+	}
+	addr := idx.interpolate(q.street, q.houseNumber, q.postcode)
+	if addr == nil {
+		return nil
+	}
+	result := addressResult(addr)
+	result.Score = componentScore(q, 1, 1, equalScore(q.locality, addr.City), equalScore(q.postcode, addr.PostCode))
+	return []GeocodeResult{result}
+}
+
+// matchesStructured reports whether a result passes the filters of a
+// structured query and lies within its broader components
+func (idx *GeoIndex) matchesStructured(r GeocodeResult, sq StructuredQuery) bool {
+	if len(sq.Layers) > 0 && !containsLayer(sq.Layers, r.Layer) {
+		return false
+	}
+	if sq.Bounds != nil && !sq.Bounds.ContainsCoord(r.Location()) {
+		return false
+	}
+
+	// Components only restrict results when something more specific is
+	// searched for
+	feature := sq.Street != "" || sq.HouseNumber != "" || sq.Name != ""
+	if feature && sq.PostCode != "" && r.PostCode != "" && normalizeString(r.PostCode) != normalizeString(sq.PostCode) {
+		return false
+	}
+	if feature && sq.City != "" && !idx.sameLocality(r.City, sq.City) {
+		return false
+	}
+	if sq.State == "" && sq.Country == "" && len(sq.CountryCodes) == 0 {
+		return true
+	}
+
+	areas := idx.AdminAreasAt(r.Lat, r.Lon)
+	if (feature || sq.City != "") && sq.State != "" && !inState(areas, sq.State) {
+		return false
+	}
+	if (feature || sq.City != "" || sq.State != "") && sq.Country != "" && !inCountry(r, areas, sq.Country) {
+		return false
+	}
+	if len(sq.CountryCodes) > 0 {
+		code := countryCode(r, areas)
+		for _, c := range sq.CountryCodes {
+			if strings.EqualFold(c, code) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsLayer(layers []Layer, layer Layer) bool {
+	for _, l := range layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// sameLocality reports whether city names the same locality as name, such
+// as in another language
+func (idx *GeoIndex) sameLocality(city, name string) bool {
+	if city == "" {
+		return false
+	}
+	if canonical, ok := matchLocality(name, idx.localityNames()); ok {
+		name = canonical
+	}
+	return normalizeString(city) == normalizeString(name)
+}
+
+// inState reports whether one of the areas is a state, province or region
+// named name
+func inState(areas []*AdminArea, name string) bool {
+	for _, area := range areas {
+		if area.Level >= stateMinLevel && area.Level <= stateMaxLevel && areaNamed(area, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inCountry reports whether a result lies in the country given by name or
+// code
+func inCountry(r GeocodeResult, areas []*AdminArea, country string) bool {
+	if strings.EqualFold(countryCode(r, areas), country) || normalizeString(r.Country) == normalizeString(country) {
+		return true
+	}
+	for _, area := range areas {
+		if area.Level == 2 && areaNamed(area, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// countryCode returns the ISO 3166-1 alpha-2 code of the country of a
+// result, from its address or the country boundary containing it
+func countryCode(r GeocodeResult, areas []*AdminArea) string {
+	if len(r.Country) == 2 {
+		return strings.ToUpper(r.Country)
+	}
+	for _, area := range areas {
+		if area.Level == 2 && area.CountryCode != "" {
+			return strings.ToUpper(area.CountryCode)
+		}
+	}
+	return ""
+}
+
+// areaNamed reports whether name is one of the names of an area
+func areaNamed(area *AdminArea, name string) bool {
+	normalized := normalizeString(name)
+	for _, v := range withVariants(area.Name, area.Names) {
+		if normalizeString(v) == normalized {
+			return true
+		}
+	}
+	return false
+}
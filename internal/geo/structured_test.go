@@ -0,0 +1,98 @@
+// internal/geo/structured_test.go
+package geo
+
+import (
+	"testing"
+)
+
+func TestGeocodeStructured(t *testing.T) {
+	idx := newSearchTestIndex(t)
+
+	t.Run("Street in city", func(t *testing.T) {
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", City: "Ordino"})
+		if len(results) == 0 {
+			t.Fatal("Expected results")
+		}
+		for _, r := range results {
+			if r.City != "Ordino" {
+				t.Errorf("Expected only results in Ordino, got %+v", r)
+			}
+		}
+		if results[0].Layer != LayerStreet {
+			t.Errorf("Expected the street first, got %+v", results[0])
+		}
+	})
+
+	t.Run("Address in city", func(t *testing.T) {
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", HouseNumber: "12", City: "andorra la vella"})
+		if len(results) == 0 || results[0].Layer != LayerAddress || results[0].PostCode != "AD500" {
+			t.Errorf("Expected Carrer Major 12 in Andorra la Vella, got %+v", results)
+		}
+	})
+
+	t.Run("Postcode", func(t *testing.T) {
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", HouseNumber: "12", PostCode: "AD300"})
+		if len(results) == 0 || results[0].City != "Ordino" {
+			t.Errorf("Expected the address in Ordino, got %+v", results)
+		}
+	})
+
+	t.Run("Layers", func(t *testing.T) {
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", Layers: []Layer{LayerAddress}, Limit: 10})
+		if len(results) != 0 {
+			t.Errorf("Expected no addresses without a house number, got %+v", results)
+		}
+		results = idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", Layers: []Layer{LayerStreet}})
+		if len(results) != 2 {
+			t.Errorf("Expected both streets, got %+v", results)
+		}
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		bounds := Bounds{MinLat: 42.55, MinLon: 1.53, MaxLat: 42.56, MaxLon: 1.54}
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", HouseNumber: "12", Bounds: &bounds})
+		if len(results) == 0 || results[0].Layer != LayerAddress {
+			t.Fatalf("Expected the address in the bounds first, got %+v", results)
+		}
+		for _, r := range results {
+			if r.City != "Ordino" {
+				t.Errorf("Expected only results in the bounds, got %+v", r)
+			}
+		}
+	})
+
+	t.Run("Country codes", func(t *testing.T) {
+		for _, addr := range idx.Addresses {
+			if addr.City == "Ordino" {
+				addr.Country = "AD"
+			}
+		}
+		results := idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", HouseNumber: "12", CountryCodes: []string{"fr", "ad"}})
+		if len(results) != 1 || results[0].City != "Ordino" {
+			t.Errorf("Expected only the address in Andorra, got %+v", results)
+		}
+		results = idx.GeocodeStructured(StructuredQuery{Street: "Carrer Major", HouseNumber: "12", Country: "ad"})
+		if len(results) != 1 || results[0].City != "Ordino" {
+			t.Errorf("Expected only the address in Andorra, got %+v", results)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if results := idx.GeocodeStructured(StructuredQuery{Layers: []Layer{LayerStreet}}); results != nil {
+			t.Errorf("Expected no results, got %+v", results)
+		}
+	})
+}
+
+func TestGeocodeStructured_Places(t *testing.T) {
+	idx := newPlaceTestIndex(t)
+
+	results := idx.GeocodeStructured(StructuredQuery{Name: "Santa Coloma", City: "Vila"})
+	if len(results) != 1 || results[0].Layer != LayerNeighbourhood {
+		t.Errorf("Expected only the suburb of Vila, got %+v", results)
+	}
+	results = idx.GeocodeStructured(StructuredQuery{City: "Villa"})
+	if len(results) == 0 || results[0].Name != "Vila" {
+		t.Errorf("Expected the town by its Spanish name, got %+v", results)
+	}
+}
@@ -1,22 +1,31 @@
 // internal/geo/types.go
 package geo
 
-import "strconv"
+import (
+	"sync"
+
+	"github.com/sebastiaanwouters/geodude/internal/address"
+)
 
 type Address struct {
 	HouseNumber string
 	Street      string
+	StreetNames Names // Variants of Street such as its name in other languages
 	City        string
 	PostCode    string
 	Country     string
 	Lat         float64
 	Lon         float64
+	Footprint   MultiPolygon // Building or area outline, nil for address nodes
 }
 
+// AddressRange is the part of an addr:interpolation way between two house
+// numbers
 type AddressRange struct {
 	StartNumber int
 	EndNumber   int
-	Step        int // Usually 2 for even/odd numbering
+	Step        int    // Usually 2 for even/odd numbering
+	Parity      string // "even" or "odd" if the numbers must have that parity
 	Street      string
 	City        string
 	PostCode    string
@@ -25,13 +34,17 @@ type AddressRange struct {
 	StartLon    float64
 	EndLat      float64
 	EndLon      float64
+	Line        LineString // Way from start to end, nil for a straight line
 }
 
+// Interpolate returns the address of a house number in the range. Numbers
+// with a suffix or unit, such as "12a", are placed at their base number.
 func (ar *AddressRange) Interpolate(houseNumber string) *Address {
-	num, err := strconv.Atoi(houseNumber)
-	if err != nil {
+	h, ok := address.ParseHouseNumber(houseNumber)
+	if !ok || h.None || h.To > 0 {
 		return nil
 	}
+	num := h.Number
 
 	// Check if number is within range and matches step pattern
 	if num < ar.StartNumber || num > ar.EndNumber {
@@ -40,13 +53,22 @@ func (ar *AddressRange) Interpolate(houseNumber string) *Address {
 	if ar.Step > 1 && (num-ar.StartNumber)%ar.Step != 0 {
 		return nil
 	}
+	if (ar.Parity == "even" && num%2 != 0) || (ar.Parity == "odd" && num%2 == 0) {
+		return nil
+	}
 
 	// Calculate position ratio
-	ratio := float64(num-ar.StartNumber) / float64(ar.EndNumber-ar.StartNumber)
+	ratio := 0.0
+	if ar.EndNumber > ar.StartNumber {
+		ratio = float64(num-ar.StartNumber) / float64(ar.EndNumber-ar.StartNumber)
+	}
 
-	// Interpolate coordinates
-	lat := ar.StartLat + (ar.EndLat-ar.StartLat)*ratio
-	lon := ar.StartLon + (ar.EndLon-ar.StartLon)*ratio
+	// Numbers are spread evenly along the way, not between its nodes
+	line := ar.Line
+	if len(line) < 2 {
+		line = LineString{{Lat: ar.StartLat, Lon: ar.StartLon}, {Lat: ar.EndLat, Lon: ar.EndLon}}
+	}
+	c := line.Interpolate(ratio)
 
 	return &Address{
 		HouseNumber: houseNumber,
@@ -54,13 +76,36 @@ func (ar *AddressRange) Interpolate(houseNumber string) *Address {
 		City:        ar.City,
 		PostCode:    ar.PostCode,
 		Country:     ar.Country,
-		Lat:         lat,
-		Lon:         lon,
+		Lat:         c.Lat,
+		Lon:         c.Lon,
 	}
 }
 
+// interpolate returns the house number interpolated along the first range of
+// the street containing it, or nil
+func (idx *GeoIndex) interpolate(street, houseNumber, postcode string) *Address {
+	for _, ar := range idx.AddressRanges[makeStreetKey(street, postcode)] {
+		if addr := ar.Interpolate(houseNumber); addr != nil {
+			return addr
+		}
+	}
+	return nil
+}
+
 type GeoIndex struct {
-	Addresses     map[string]*Address      // Key: "street:housenumber:postcode"
-	AddressRanges map[string]*AddressRange // Key: "street:postcode"
-	StreetIndex   *QuadTree                // For spatial queries
+	Addresses     map[string]*Address        // Key: "street:housenumber:postcode"
+	AddressRanges map[string][]*AddressRange // Key: "street:postcode", one entry per range
+	StreetIndex   *QuadTree                  // For spatial queries
+	AdminAreas    *RTree                     // Administrative boundaries (*AdminArea)
+	Buildings     *RTree                     // Footprints of addressed buildings (*Address)
+	Streets       map[string][]*Street       // Key: lowercase street name, one entry per locality
+	StreetLines   *RTree                     // Street geometries (*Street)
+	POIs          *RTree                     // Points of interest (*POI)
+	Places        *RTree                     // Cities, towns, villages and their parts (*Place)
+
+	textMu   sync.Mutex   // Guards text
+	prefixMu sync.Mutex   // Guards prefixes
+	version  uint64       // Bumped by every change to the features
+	text     *textIndex   // Built by GetIndex, or by a text query after a change
+	prefixes *prefixIndex // Built by GetIndex, or by an autocomplete query after a change
 }
@@ -5,3 +5,7 @@ import "math"
 func degreesToRadians(degrees float64) float64 {
 	return degrees * math.Pi / 180
 }
+
+func radiansToDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -8,24 +8,34 @@ import (
 
 // GraphBuilder builds a graph from OSM data using streaming
 type GraphBuilder struct {
+	locations osm.NodeStore
 	nodes     map[osm.ID]Node
 	edges     map[osm.ID][]Edge
 	nodeCount int
 	wayCount  int
+	skipped   int // Way segments with a node that has no location
 }
 
 func NewGraphBuilder() *GraphBuilder {
+	return NewGraphBuilderWithNodeStore(osm.NewSparseNodeStore())
+}
+
+// NewGraphBuilderWithNodeStore creates a builder keeping the locations of
+// all nodes in the given store until the ways are processed
+func NewGraphBuilderWithNodeStore(locations osm.NodeStore) *GraphBuilder {
 	return &GraphBuilder{
-		nodes: make(map[osm.ID]Node),
-		edges: make(map[osm.ID][]Edge),
+		locations: locations,
+		nodes:     make(map[osm.ID]Node),
+		edges:     make(map[osm.ID][]Edge),
 	}
 }
 
 // ProcessNode implements osm.Processor
 func (b *GraphBuilder) ProcessNode(node *osm.Node) error {
-	// Only store nodes that are part of ways
+	// Only nodes that are part of ways are added to the graph, so the
+	// location is kept until the ways are seen
 	b.nodeCount++
-	return nil
+	return b.locations.Set(node.ID, node.Lat, node.Lon)
 }
 
 // ProcessWay implements osm.Processor
@@ -34,21 +44,21 @@ func (b *GraphBuilder) ProcessWay(way *osm.Way) error {
 
 	// Process nodes in the way
 	for i := 0; i < len(way.Nodes)-1; i++ {
-		from := way.Nodes[i]
-		to := way.Nodes[i+1]
-
-		// Add nodes if they don't exist
-		if _, exists := b.nodes[from]; !exists {
-			b.nodes[from] = Node{ID: from}
-		}
-		if _, exists := b.nodes[to]; !exists {
-			b.nodes[to] = Node{ID: to}
+		fromNode, fromOK := locateNode(b.nodes, b.locations, way.Nodes[i])
+		toNode, toOK := locateNode(b.nodes, b.locations, way.Nodes[i+1])
+		if !fromOK || !toOK {
+			// Nodes outside the extract have no location to measure from
+			b.skipped++
+			continue
 		}
+		from, to := fromNode.ID, toNode.ID
+		b.nodes[from] = fromNode
+		b.nodes[to] = toNode
 
 		// Calculate distance and add edges
 		distance := geo.HaversineDistance(
-			geo.Coord{Lat: b.nodes[from].Lat, Lon: b.nodes[from].Lon},
-			geo.Coord{Lat: b.nodes[to].Lat, Lon: b.nodes[to].Lon},
+			geo.Coord{Lat: fromNode.Lat, Lon: fromNode.Lon},
+			geo.Coord{Lat: toNode.Lat, Lon: toNode.Lon},
 		)
 
 		b.edges[from] = append(b.edges[from], Edge{From: from, To: to, Weight: distance})
@@ -63,8 +73,9 @@ func (b *GraphBuilder) ProcessRelation(relation *osm.Relation) error {
 	return nil
 }
 
-// Build returns the final graph
+// Build returns the final graph, releasing the stored node locations
 func (b *GraphBuilder) Build() *Graph {
+	b.locations.Close()
 	return &Graph{
 		Nodes: b.nodes,
 		Edges: b.edges,
@@ -72,17 +83,19 @@ func (b *GraphBuilder) Build() *Graph {
 }
 
 type Statistics struct {
-	NodesProcessed int
-	WaysProcessed  int
-	NodesInGraph   int
-	EdgesInGraph   int
+	NodesProcessed  int
+	WaysProcessed   int
+	NodesInGraph    int
+	EdgesInGraph    int
+	SegmentsSkipped int // Way segments left out for a node without a location
 }
 
 func (b *GraphBuilder) GetStatistics() Statistics {
 	return Statistics{
-		NodesProcessed: b.nodeCount,
-		WaysProcessed:  b.wayCount,
-		NodesInGraph:   len(b.nodes),
-		EdgesInGraph:   len(b.edges),
+		NodesProcessed:  b.nodeCount,
+		WaysProcessed:   b.wayCount,
+		NodesInGraph:    len(b.nodes),
+		EdgesInGraph:    len(b.edges),
+		SegmentsSkipped: b.skipped,
 	}
 }
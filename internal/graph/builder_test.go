@@ -8,7 +8,7 @@ import (
 
 func TestStreamProcessing(t *testing.T) {
 	builder := NewGraphBuilder()
-	err := osm.ParsePBF("../../data/andorra-latest.osm.pbf", true, builder)
+	err := osm.ParsePBF("../../data/andorra-latest.osm.pbf", osm.RoutableFilter, builder)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,4 +20,76 @@ func TestStreamProcessing(t *testing.T) {
 	if stats.WaysProcessed == 0 {
 		t.Error("Expected to process some ways")
 	}
+
+	// Edges weigh the distance between the located nodes
+	graph := builder.Build()
+	for from, edges := range graph.Edges {
+		if node := graph.Nodes[from]; node.Lat == 0 && node.Lon == 0 {
+			t.Fatalf("Node %d has no location", from)
+		}
+		for _, edge := range edges {
+			if edge.Weight < 0 || edge.Weight > 50 {
+				t.Fatalf("Edge %d-%d weighs %f", edge.From, edge.To, edge.Weight)
+			}
+		}
+	}
+}
+
+func TestStreamProcessingTwoPass(t *testing.T) {
+	builder := NewGraphBuilder()
+	err := osm.ParsePBFTwoPass("../../data/andorra-latest.osm.pbf", osm.RoutableFilter, builder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	singlePass := NewGraphBuilder()
+	err = osm.ParsePBF("../../data/andorra-latest.osm.pbf", osm.RoutableFilter, singlePass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the nodes of routable ways and restrictions are delivered
+	stats, all := builder.GetStatistics(), singlePass.GetStatistics()
+	if stats.NodesProcessed < stats.NodesInGraph || stats.NodesProcessed >= all.NodesProcessed {
+		t.Errorf("Expected between %d and %d nodes, got %d", stats.NodesInGraph, all.NodesProcessed, stats.NodesProcessed)
+	}
+	if stats.WaysProcessed != all.WaysProcessed || stats.NodesInGraph != all.NodesInGraph {
+		t.Errorf("Expected the same %d ways and %d graph nodes as in one pass, got %d and %d",
+			all.WaysProcessed, all.NodesInGraph, stats.WaysProcessed, stats.NodesInGraph)
+	}
+	graph := builder.Build()
+	for id, node := range graph.Nodes {
+		if node.Lat == 0 && node.Lon == 0 {
+			t.Fatalf("Node %d has no location", id)
+		}
+	}
+}
+
+func TestGraphBuilder_MissingNode(t *testing.T) {
+	builder := NewGraphBuilder()
+	for _, node := range []osm.Node{
+		{ID: 1, Lat: 42.50, Lon: 1.52},
+		{ID: 2, Lat: 42.51, Lon: 1.52},
+		{ID: 3, Lat: 42.52, Lon: 1.52},
+	} {
+		if err := builder.ProcessNode(&node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Node 4 lies outside the extract
+	if err := builder.ProcessWay(&osm.Way{ID: 10, Nodes: []osm.ID{1, 2, 4, 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := builder.GetStatistics()
+	if stats.SegmentsSkipped != 2 {
+		t.Errorf("Expected 2 skipped segments, got %d", stats.SegmentsSkipped)
+	}
+	graph := builder.Build()
+	if len(graph.Nodes) != 2 || len(graph.Edges[1]) != 1 || len(graph.Edges[2]) != 1 {
+		t.Errorf("Expected only the edge between nodes 1 and 2, got %v", graph.Edges)
+	}
+	if _, exists := graph.Nodes[4]; exists {
+		t.Error("Expected no node without a location")
+	}
 }
@@ -44,40 +44,47 @@ func (g *Graph) AddEdge(from, to osm.ID, weight float64) {
 }
 
 // ConstructGraphFromOSMData constructs a graph from the given OSMData.
+// Segments of ways with a node that has no location, such as one outside the
+// extract, are left out.
 func ConstructGraphFromOSMData(data *osm.OSMData) *Graph {
 	graph := NewGraph()
 
-	// Add all nodes to the graph
-	for _, osmNode := range data.Nodes {
-		node := Node{
-			ID:  osmNode.ID,
-			Lat: osmNode.Lat,
-			Lon: osmNode.Lon,
-		}
-		graph.AddNode(node)
-	}
-
-	// Add edges based on ways
+	// Add edges based on ways, adding their nodes at their stored locations
 	for _, way := range data.Ways {
 		for i := 0; i < len(way.Nodes)-1; i++ {
-			from := way.Nodes[i]
-			to := way.Nodes[i+1]
-
-			fromNode := graph.Nodes[from]
-			toNode := graph.Nodes[to]
+			fromNode, fromOK := locateNode(graph.Nodes, data.Nodes, way.Nodes[i])
+			toNode, toOK := locateNode(graph.Nodes, data.Nodes, way.Nodes[i+1])
+			if !fromOK || !toOK {
+				continue
+			}
+			graph.AddNode(fromNode)
+			graph.AddNode(toNode)
 
 			// Calculate the distance between the two nodes using Haversine formula
 			distance := geo.HaversineDistance(geo.Coord{Lat: fromNode.Lat, Lon: fromNode.Lon}, geo.Coord{Lat: toNode.Lat, Lon: toNode.Lon})
 
 			// Add bidirectional edges (assuming the graph is undirected)
-			graph.AddEdge(from, to, distance)
-			graph.AddEdge(to, from, distance)
+			graph.AddEdge(fromNode.ID, toNode.ID, distance)
+			graph.AddEdge(toNode.ID, fromNode.ID, distance)
 		}
 	}
 
 	return graph
 }
 
+// locateNode returns a node already in the graph, or else the node at its
+// stored location, reporting false if it has none
+func locateNode(nodes map[osm.ID]Node, locations osm.NodeStore, id osm.ID) (Node, bool) {
+	if node, exists := nodes[id]; exists {
+		return node, true
+	}
+	lat, lon, ok := locations.Get(id)
+	if !ok {
+		return Node{}, false
+	}
+	return Node{ID: id, Lat: lat, Lon: lon}, true
+}
+
 func (g *Graph) AdjacentNodes(nodeID osm.ID) map[osm.ID]float64 {
 	adjacent := make(map[osm.ID]float64)
 
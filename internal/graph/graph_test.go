@@ -2,6 +2,8 @@ package graph
 
 import (
 	"testing"
+
+	"github.com/sebastiaanwouters/geodude/internal/osm"
 )
 
 func TestGraph(t *testing.T) {
@@ -84,3 +86,25 @@ func TestGraph(t *testing.T) {
 		}
 	})
 }
+
+func TestConstructGraphFromOSMData_MissingNode(t *testing.T) {
+	data := &osm.OSMData{
+		Nodes: osm.NewSparseNodeStore(),
+		// Node 4 lies outside the extract
+		Ways: []osm.Way{{ID: 10, Nodes: []osm.ID{1, 2, 4, 3}}},
+	}
+	defer data.Close()
+	for id, lat := range map[osm.ID]float64{1: 42.50, 2: 42.51, 3: 42.52} {
+		if err := data.Nodes.Set(id, lat, 1.52); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph := ConstructGraphFromOSMData(data)
+	if len(graph.Nodes) != 2 || len(graph.Edges[1]) != 1 || len(graph.Edges[2]) != 1 {
+		t.Errorf("Expected only the edge between nodes 1 and 2, got %v", graph.Edges)
+	}
+	if edge := graph.Edges[1][0]; edge.To != 2 || edge.Weight > 2 {
+		t.Errorf("Expected an edge of about 1 km to node 2, got %+v", edge)
+	}
+}
@@ -0,0 +1,568 @@
+// internal/openinghours/parser.go
+package openinghours
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError reports an invalid opening_hours value
+type SyntaxError struct {
+	Pos int // Byte offset of the error in the input
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("opening_hours: %s at position %d", e.Msg, e.Pos)
+}
+
+// ruleKind is how a rule combines with the rules before it
+type ruleKind int
+
+const (
+	normalRule     ruleKind = iota // After ";": replaces earlier rules on the days it matches
+	additionalRule                 // After ",": adds to earlier rules
+	fallbackRule                   // After "||": applies only to days no earlier rule matched
+)
+
+// State is whether a place is open
+type State int
+
+const (
+	Closed State = iota
+	Open
+	Unknown
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case Unknown:
+		return "unknown"
+	}
+	return "closed"
+}
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"mo", "tu", "we", "th", "fr", "sa", "su"}
+)
+
+// minutesPerDay is the length of a day; spans may extend past it into the
+// next day
+const minutesPerDay = 24 * 60
+
+type rule struct {
+	kind     ruleKind
+	months   []monthRange
+	weeks    []weekRange
+	weekdays []weekdayRange
+	holidays []holiday
+	spans    []span
+	state    State
+	modifier bool // The state was given explicitly
+	comment  string
+}
+
+// monthRange covers the dates from..to, each as month*100+day, wrapping
+// around the end of the year when from > to
+type monthRange struct {
+	from, to int
+}
+
+type weekRange struct {
+	from, to, step int
+}
+
+// weekdayRange covers weekdays from..to, Monday being 0. With nth set it
+// only covers the nth weekdays of the month, negative counting from the end.
+type weekdayRange struct {
+	from, to int
+	nth      []int
+}
+
+type holiday int
+
+const (
+	publicHoliday holiday = iota
+	schoolHoliday
+)
+
+// span is a time interval in minutes since midnight. End may exceed a day
+// for spans running past midnight.
+type span struct {
+	start, end int
+	openEnd    bool // Written as "18:00+", closing time unknown
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// Parse parses an opening_hours value such as "Mo-Fr 09:00-13:00,15:00-19:00;
+// Sa 10:00-14:00; PH off"
+func Parse(input string) (*Schedule, error) {
+	p := &parser{input: input}
+	var rules []rule
+	kind := normalRule
+	for {
+		p.skipSpace()
+		if p.eof() {
+			if len(rules) == 0 {
+				return nil, p.errorf("empty value")
+			}
+			break
+		}
+		r, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		r.kind = kind
+		rules = append(rules, r)
+
+		p.skipSpace()
+		switch {
+		case p.eof():
+		case p.accept("||"):
+			kind = fallbackRule
+		case p.accept(";"):
+			kind = normalRule
+		case p.accept(","):
+			kind = additionalRule
+		default:
+			return nil, p.errorf("unexpected %q", p.rest(1))
+		}
+	}
+	return &Schedule{rules: rules}, nil
+}
+
+func (p *parser) parseRule() (rule, error) {
+	start := p.pos
+	r := rule{state: Open}
+
+	if p.accept("24/7") {
+		r.spans = []span{{start: 0, end: minutesPerDay}}
+	} else {
+		if _, ok := p.peekName(monthNames); ok {
+			months, err := p.parseMonths()
+			if err != nil {
+				return r, err
+			}
+			r.months = months
+			p.skipSpace()
+		}
+		if strings.EqualFold(p.peekWord(), "week") {
+			weeks, err := p.parseWeeks()
+			if err != nil {
+				return r, err
+			}
+			r.weeks = weeks
+			p.skipSpace()
+		}
+		if p.atWeekday() {
+			if err := p.parseWeekdays(&r); err != nil {
+				return r, err
+			}
+			p.skipSpace()
+		}
+		if p.atDigit() {
+			spans, err := p.parseSpans()
+			if err != nil {
+				return r, err
+			}
+			r.spans = spans
+		}
+	}
+
+	p.skipSpace()
+	switch strings.ToLower(p.peekWord()) {
+	case "open":
+		r.state, r.modifier = Open, true
+	case "off", "closed":
+		r.state, r.modifier = Closed, true
+	case "unknown":
+		r.state, r.modifier = Unknown, true
+	case "":
+	default:
+		if p.pos == start {
+			return r, p.errorf("unexpected %q", p.peekWord())
+		}
+		return r, p.errorf("unknown selector or modifier %q", p.peekWord())
+	}
+	if r.modifier {
+		p.pos += len(p.peekWord())
+		p.skipSpace()
+	}
+
+	if p.peek() == '"' {
+		comment, err := p.parseComment()
+		if err != nil {
+			return r, err
+		}
+		r.comment = comment
+		// A rule consisting of a comment only gives no definite state
+		if !r.modifier && r.spans == nil {
+			r.state = Unknown
+		}
+	}
+
+	if p.pos == start {
+		return r, p.errorf("expected a rule")
+	}
+	return r, nil
+}
+
+// parseMonths parses month and date ranges: "Jan-Mar", "Dec 24-26", "Dec 24-Jan 02", "Jan,Jul"
+func (p *parser) parseMonths() ([]monthRange, error) {
+	var ranges []monthRange
+	for {
+		fromMonth, fromDay, err := p.parseDate()
+		if err != nil {
+			return nil, err
+		}
+		toMonth, toDay := fromMonth, fromDay
+		if p.accept("-") {
+			if m, ok := p.peekName(monthNames); ok {
+				p.pos += 3
+				toMonth, toDay = m+1, 0
+				if p.skipSpaceBeforeDay() {
+					if toDay, err = p.parseNumber(1, 31, "day"); err != nil {
+						return nil, err
+					}
+				}
+			} else if fromDay != 0 {
+				if toDay, err = p.parseNumber(1, 31, "day"); err != nil {
+					return nil, err
+				}
+			} else {
+				return nil, p.errorf("expected a month")
+			}
+		}
+		if fromDay == 0 {
+			fromDay = 1
+		}
+		if toDay == 0 {
+			toDay = 31
+		}
+		ranges = append(ranges, monthRange{from: fromMonth*100 + fromDay, to: toMonth*100 + toDay})
+
+		if !p.acceptListSeparator(func() bool { _, ok := p.peekName(monthNames); return ok }) {
+			return ranges, nil
+		}
+	}
+}
+
+// parseDate parses a month optionally followed by a day: "Dec", "Dec 24".
+// Months count from 1 and a missing day is 0.
+func (p *parser) parseDate() (month, day int, err error) {
+	month, ok := p.peekName(monthNames)
+	if !ok {
+		return 0, 0, p.errorf("expected a month")
+	}
+	p.pos += 3
+	if p.skipSpaceBeforeDay() {
+		if day, err = p.parseNumber(1, 31, "day"); err != nil {
+			return 0, 0, err
+		}
+	}
+	return month + 1, day, nil
+}
+
+// skipSpaceBeforeDay skips spaces if a day of the month follows, rather than
+// a time such as "10:00"
+func (p *parser) skipSpaceBeforeDay() bool {
+	i := p.pos
+	for i < len(p.input) && p.input[i] == ' ' {
+		i++
+	}
+	j := i
+	for j < len(p.input) && isDigit(p.input[j]) {
+		j++
+	}
+	if j == i || j-i > 2 || (j < len(p.input) && p.input[j] == ':') {
+		return false
+	}
+	p.pos = i
+	return true
+}
+
+// parseWeeks parses ISO week selectors: "week 01-10", "week 1,3", "week 02-52/2"
+func (p *parser) parseWeeks() ([]weekRange, error) {
+	p.pos += len("week")
+	p.skipSpace()
+	var ranges []weekRange
+	for {
+		from, err := p.parseNumber(1, 53, "week")
+		if err != nil {
+			return nil, err
+		}
+		r := weekRange{from: from, to: from, step: 1}
+		if p.accept("-") {
+			if r.to, err = p.parseNumber(1, 53, "week"); err != nil {
+				return nil, err
+			}
+			if p.accept("/") {
+				if r.step, err = p.parseNumber(1, 53, "week step"); err != nil {
+					return nil, err
+				}
+			}
+		}
+		ranges = append(ranges, r)
+		if !p.acceptListSeparator(p.atDigit) {
+			return ranges, nil
+		}
+	}
+}
+
+// parseWeekdays parses weekday ranges and holidays: "Mo-Fr", "Sa,Su,PH", "Su[1]", "Fr[-1]"
+func (p *parser) parseWeekdays(r *rule) error {
+	for {
+		switch word := strings.ToUpper(p.peekWord()); word {
+		case "PH":
+			p.pos += 2
+			r.holidays = append(r.holidays, publicHoliday)
+		case "SH":
+			p.pos += 2
+			r.holidays = append(r.holidays, schoolHoliday)
+		default:
+			from, ok := p.peekName(weekdayNames)
+			if !ok || len(word) != 2 {
+				return p.errorf("expected a weekday")
+			}
+			p.pos += 2
+			wr := weekdayRange{from: from, to: from}
+			if p.accept("-") {
+				to, ok := p.peekName(weekdayNames)
+				if !ok || len(p.peekWord()) != 2 {
+					return p.errorf("expected a weekday")
+				}
+				p.pos += 2
+				wr.to = to
+			}
+			if p.accept("[") {
+				nth, err := p.parseNth()
+				if err != nil {
+					return err
+				}
+				wr.nth = nth
+			}
+			r.weekdays = append(r.weekdays, wr)
+		}
+		if !p.acceptListSeparator(p.atWeekday) {
+			return nil
+		}
+	}
+}
+
+// parseNth parses the occurrences within the month after "[": "1]", "-1]", "1,3]", "1-2]"
+func (p *parser) parseNth() ([]int, error) {
+	var nth []int
+	for {
+		sign := 1
+		if p.accept("-") {
+			sign = -1
+		}
+		n, err := p.parseNumber(1, 5, "weekday occurrence")
+		if err != nil {
+			return nil, err
+		}
+		nth = append(nth, sign*n)
+		if sign > 0 && p.accept("-") {
+			to, err := p.parseNumber(n, 5, "weekday occurrence")
+			if err != nil {
+				return nil, err
+			}
+			for i := n + 1; i <= to; i++ {
+				nth = append(nth, i)
+			}
+		}
+		if p.accept("]") {
+			return nth, nil
+		}
+		if !p.accept(",") {
+			return nil, p.errorf("expected \"]\"")
+		}
+	}
+}
+
+// parseSpans parses time spans: "09:00-13:00,15:00-19:00", "22:00-02:00", "18:00+"
+func (p *parser) parseSpans() ([]span, error) {
+	var spans []span
+	for {
+		start, err := p.parseTime(minutesPerDay)
+		if err != nil {
+			return nil, err
+		}
+		s := span{start: start}
+		switch {
+		case p.accept("+"):
+			s.end, s.openEnd = minutesPerDay, true
+		case p.accept("-"):
+			if s.end, err = p.parseTime(2 * minutesPerDay); err != nil {
+				return nil, err
+			}
+			if s.end <= s.start {
+				s.end += minutesPerDay
+			}
+			// "10:00-18:00+": open until at least 18:00
+			p.accept("+")
+		default:
+			return nil, p.errorf("expected \"-\" after time")
+		}
+		spans = append(spans, s)
+		if !p.acceptListSeparator(p.atDigit) {
+			return spans, nil
+		}
+	}
+}
+
+// parseTime parses "hh:mm" up to latest minutes after midnight: 24:00 for
+// the start of a span, 48:00 for its end, which may be the next day
+func (p *parser) parseTime(latest int) (int, error) {
+	if word := p.peekWord(); word != "" {
+		return 0, p.errorf("unsupported time %q", word)
+	}
+	hours, err := p.parseNumber(0, latest/60, "hour")
+	if err != nil {
+		return 0, err
+	}
+	if !p.accept(":") {
+		return 0, p.errorf("expected \":\"")
+	}
+	start := p.pos
+	minutes, err := p.parseNumber(0, 59, "minute")
+	if err != nil {
+		return 0, err
+	}
+	if p.pos-start != 2 {
+		return 0, &SyntaxError{Pos: start, Msg: "expected two-digit minutes"}
+	}
+	if t := hours*60 + minutes; t <= latest {
+		return t, nil
+	}
+	return 0, &SyntaxError{Pos: start, Msg: "time out of range"}
+}
+
+func (p *parser) parseComment() (string, error) {
+	start := p.pos
+	p.pos++
+	end := strings.IndexByte(p.input[p.pos:], '"')
+	if end < 0 {
+		return "", &SyntaxError{Pos: start, Msg: "unterminated comment"}
+	}
+	comment := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+	return comment, nil
+}
+
+// parseNumber parses a decimal number between min and max
+func (p *parser) parseNumber(min, max int, what string) (int, error) {
+	start := p.pos
+	n := 0
+	for !p.eof() && isDigit(p.input[p.pos]) {
+		n = n*10 + int(p.input[p.pos]-'0')
+		p.pos++
+		if p.pos-start > 4 {
+			break
+		}
+	}
+	if p.pos == start {
+		return 0, p.errorf("expected %s", what)
+	}
+	if n < min || n > max {
+		return 0, &SyntaxError{Pos: start, Msg: fmt.Sprintf("%s %d out of range", what, n)}
+	}
+	return n, nil
+}
+
+// acceptListSeparator consumes a "," if next follows it, leaving commas that
+// separate rules for the caller
+func (p *parser) acceptListSeparator(next func() bool) bool {
+	if p.peek() != ',' {
+		return false
+	}
+	p.pos++
+	save := p.pos
+	p.skipSpace()
+	if next() {
+		return true
+	}
+	p.pos = save - 1
+	return false
+}
+
+func (p *parser) atWeekday() bool {
+	word := strings.ToUpper(p.peekWord())
+	if word == "PH" || word == "SH" {
+		return true
+	}
+	_, ok := p.peekName(weekdayNames)
+	return ok && len(word) == 2
+}
+
+func (p *parser) atDigit() bool {
+	return !p.eof() && isDigit(p.input[p.pos])
+}
+
+// peekName returns the index of the name the next word is, ignoring case
+func (p *parser) peekName(names []string) (int, bool) {
+	word := strings.ToLower(p.peekWord())
+	for i, name := range names {
+		if word == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// peekWord returns the run of letters at the current position
+func (p *parser) peekWord() string {
+	end := p.pos
+	for end < len(p.input) && unicode.IsLetter(rune(p.input[end])) && p.input[end] < 0x80 {
+		end++
+	}
+	return p.input[p.pos:end]
+}
+
+func (p *parser) accept(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) rest(n int) string {
+	if p.pos+n > len(p.input) {
+		return p.input[p.pos:]
+	}
+	return p.input[p.pos : p.pos+n]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,249 @@
+// internal/openinghours/schedule.go
+package openinghours
+
+import (
+	"sort"
+	"time"
+)
+
+// Schedule is a parsed opening_hours value
+type Schedule struct {
+	rules []rule
+
+	// Location is the time zone the opening hours are given in. If nil,
+	// times are evaluated in their own location.
+	Location *time.Location
+	// Holidays and SchoolHolidays report whether a date is a public or school
+	// holiday, matched by PH and SH. If nil, no date is.
+	Holidays       func(date time.Time) bool
+	SchoolHolidays func(date time.Time) bool
+}
+
+// maxLookahead is how many days NextChange searches, enough for every date
+// selector to come round once
+const maxLookahead = 367
+
+// interval is a span of a day with the state a rule gives it
+type interval struct {
+	span
+	state   State
+	comment string
+}
+
+// IsOpen reports whether the place is open at t. Unknown states, such as
+// "by appointment" or an open end, count as closed.
+func (s *Schedule) IsOpen(t time.Time) bool {
+	state, _ := s.StateAt(t)
+	return state == Open
+}
+
+// StateAt returns the state at t and the comment of the rule giving it
+func (s *Schedule) StateAt(t time.Time) (State, string) {
+	t = s.local(t)
+	date := midnight(t)
+	minute := t.Hour()*60 + t.Minute()
+	return s.stateAt(date, minute, newDayCache(s))
+}
+
+// NextChange returns the first time after t at which the state differs from
+// the state at t, and false if it stays the same for at least a year
+func (s *Schedule) NextChange(t time.Time) (time.Time, bool) {
+	t = s.local(t)
+	days := newDayCache(s)
+	today := midnight(t)
+	current, _ := s.stateAt(today, t.Hour()*60+t.Minute(), days)
+
+	for offset := 0; offset <= maxLookahead; offset++ {
+		date := today.AddDate(0, 0, offset)
+		for _, minute := range s.boundaries(date, days) {
+			change := time.Date(date.Year(), date.Month(), date.Day(), 0, minute, 0, 0, date.Location())
+			if !change.After(t) {
+				continue
+			}
+			if state, _ := s.stateAt(date, minute, days); state != current {
+				return change, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// stateAt returns the state at minute of date. Spans of the day take
+// precedence over spans running past midnight from the day before, and
+// later rules over earlier ones.
+func (s *Schedule) stateAt(date time.Time, minute int, days *dayCache) (State, string) {
+	today := days.get(date)
+	for i := len(today) - 1; i >= 0; i-- {
+		if iv := today[i]; iv.start <= minute && minute < iv.end {
+			return iv.state, iv.comment
+		}
+	}
+	yesterday := days.get(date.AddDate(0, 0, -1))
+	for i := len(yesterday) - 1; i >= 0; i-- {
+		if iv := yesterday[i]; iv.start <= minute+minutesPerDay && minute+minutesPerDay < iv.end {
+			return iv.state, iv.comment
+		}
+	}
+	return Closed, ""
+}
+
+// boundaries returns the minutes of date at which its state may change
+func (s *Schedule) boundaries(date time.Time, days *dayCache) []int {
+	set := map[int]bool{0: true}
+	for _, iv := range days.get(date) {
+		set[iv.start] = true
+		if iv.end < minutesPerDay {
+			set[iv.end] = true
+		}
+	}
+	for _, iv := range days.get(date.AddDate(0, 0, -1)) {
+		if end := iv.end - minutesPerDay; end > 0 && end < minutesPerDay {
+			set[end] = true
+		}
+	}
+	minutes := make([]int, 0, len(set))
+	for m := range set {
+		minutes = append(minutes, m)
+	}
+	sort.Ints(minutes)
+	return minutes
+}
+
+// day returns the intervals the rules give date, later ones taking precedence
+func (s *Schedule) day(date time.Time) []interval {
+	var intervals []interval
+	matched := false
+	for _, r := range s.rules {
+		if r.kind == fallbackRule && matched {
+			break
+		}
+		if !s.matches(r, date) {
+			continue
+		}
+		if r.kind != additionalRule {
+			intervals = intervals[:0]
+		}
+		matched = true
+
+		spans := r.spans
+		if spans == nil {
+			spans = []span{{start: 0, end: minutesPerDay}}
+		}
+		for _, sp := range spans {
+			state := r.state
+			if sp.openEnd && state == Open {
+				state = Unknown
+			}
+			intervals = append(intervals, interval{span: sp, state: state, comment: r.comment})
+		}
+	}
+	return intervals
+}
+
+// matches reports whether the date selectors of r cover date
+func (s *Schedule) matches(r rule, date time.Time) bool {
+	if len(r.months) > 0 && !matchesAny(len(r.months), func(i int) bool { return r.months[i].contains(date) }) {
+		return false
+	}
+	if len(r.weeks) > 0 && !matchesAny(len(r.weeks), func(i int) bool { return r.weeks[i].contains(date) }) {
+		return false
+	}
+	if len(r.weekdays) == 0 && len(r.holidays) == 0 {
+		return true
+	}
+	for _, wr := range r.weekdays {
+		if wr.contains(date) {
+			return true
+		}
+	}
+	for _, h := range r.holidays {
+		check := s.Holidays
+		if h == schoolHoliday {
+			check = s.SchoolHolidays
+		}
+		if check != nil && check(date) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(n int, match func(i int) bool) bool {
+	for i := 0; i < n; i++ {
+		if match(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r monthRange) contains(date time.Time) bool {
+	d := int(date.Month())*100 + date.Day()
+	if r.from <= r.to {
+		return r.from <= d && d <= r.to
+	}
+	return d >= r.from || d <= r.to
+}
+
+func (r weekRange) contains(date time.Time) bool {
+	_, week := date.ISOWeek()
+	if r.from <= r.to {
+		return r.from <= week && week <= r.to && (week-r.from)%r.step == 0
+	}
+	// Ranges such as "week 52-02" wrap around the end of the year
+	return (week >= r.from || week <= r.to) && (week-r.from+53)%53%r.step == 0
+}
+
+func (r weekdayRange) contains(date time.Time) bool {
+	// time.Weekday counts from Sunday, the parsed ranges from Monday
+	day := (int(date.Weekday()) + 6) % 7
+	if r.from <= r.to {
+		if day < r.from || day > r.to {
+			return false
+		}
+	} else if day < r.from && day > r.to {
+		return false
+	}
+	if len(r.nth) == 0 {
+		return true
+	}
+	fromStart := (date.Day()-1)/7 + 1
+	daysInMonth := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	fromEnd := -((daysInMonth-date.Day())/7 + 1)
+	for _, n := range r.nth {
+		if n == fromStart || n == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) local(t time.Time) time.Time {
+	if s.Location != nil {
+		return t.In(s.Location)
+	}
+	return t
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// dayCache remembers the intervals of days already evaluated
+type dayCache struct {
+	schedule *Schedule
+	days     map[time.Time][]interval
+}
+
+func newDayCache(s *Schedule) *dayCache {
+	return &dayCache{schedule: s, days: make(map[time.Time][]interval)}
+}
+
+func (c *dayCache) get(date time.Time) []interval {
+	if intervals, ok := c.days[date]; ok {
+		return intervals
+	}
+	intervals := c.schedule.day(date)
+	c.days[date] = intervals
+	return intervals
+}
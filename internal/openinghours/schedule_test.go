@@ -0,0 +1,173 @@
+// internal/openinghours/schedule_test.go
+package openinghours
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Monday 2024-06-03 is in ISO week 23
+func at(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestIsOpen(t *testing.T) {
+	tests := []struct {
+		hours string
+		time  time.Time
+		want  bool
+	}{
+		{"24/7", at(time.June, 9, 3, 0), true},
+		{"Mo-Fr 09:00-17:00", at(time.June, 3, 9, 0), true},
+		{"Mo-Fr 09:00-17:00", at(time.June, 3, 17, 0), false},
+		{"Mo-Fr 09:00-17:00", at(time.June, 8, 12, 0), false},
+		{"Mo-Fr 09:00-13:00,15:00-19:00", at(time.June, 4, 14, 0), false},
+		{"Mo-Fr 09:00-13:00,15:00-19:00", at(time.June, 4, 15, 30), true},
+		{"Mo-Fr 09:00-17:00; We off", at(time.June, 5, 10, 0), false},
+		{"Mo-Fr 09:00-17:00; We 12:00-14:00", at(time.June, 5, 10, 0), false},
+		{"Mo-Fr 09:00-12:00, We 14:00-18:00", at(time.June, 5, 15, 0), true},
+		{"Mo-Fr 09:00-12:00, We 14:00-18:00", at(time.June, 5, 10, 0), true},
+		{"Fr-Mo 10:00-12:00", at(time.June, 9, 11, 0), true},
+		{"Fr 22:00-02:00", at(time.June, 8, 1, 0), true},
+		{"Fr 22:00-02:00", at(time.June, 8, 3, 0), false},
+		{"Sa 20:00-26:00", at(time.June, 9, 1, 59), true},
+		{"Jun-Aug Mo-Su 09:00-21:00; Sep-May Mo-Sa 10:00-18:00", at(time.June, 9, 20, 0), true},
+		{"Jun-Aug Mo-Su 09:00-21:00; Sep-May Mo-Sa 10:00-18:00", at(time.December, 1, 12, 0), false},
+		{"Dec 24-Jan 02 off; Mo-Su 10:00-20:00", at(time.December, 31, 12, 0), true},
+		{"Mo-Su 10:00-20:00; Dec 24-Jan 02 off", at(time.December, 31, 12, 0), false},
+		{"Mo-Su 10:00-20:00; Dec 24-Jan 02 off", at(time.January, 1, 12, 0), false},
+		{"Mo-Su 10:00-20:00; Dec 25 off", at(time.December, 26, 12, 0), true},
+		{"week 22-24 Mo 10:00-12:00", at(time.June, 3, 11, 0), true},
+		{"week 01-53/2 Mo 10:00-12:00", at(time.June, 3, 11, 0), true},
+		{"week 02-52/2 Mo 10:00-12:00", at(time.June, 3, 11, 0), false},
+		{"Su[1] 10:00-14:00", at(time.June, 2, 11, 0), true},
+		{"Su[1] 10:00-14:00", at(time.June, 9, 11, 0), false},
+		{"Su[-1] 10:00-14:00", at(time.June, 30, 11, 0), true},
+		{"Mo-Fr 18:00+", at(time.June, 3, 19, 0), false},
+		{`Mo-Fr "by appointment"`, at(time.June, 3, 10, 0), false},
+		{`Mo-Fr 10:00-12:00 "ring the bell"`, at(time.June, 3, 10, 0), true},
+		{"Mo-Fr 09:00-17:00 || unknown", at(time.June, 3, 10, 0), true},
+		{"Mo-Fr 09:00-17:00; PH off", at(time.June, 3, 10, 0), true},
+		{"mo-fr 09:00-17:00", at(time.June, 3, 10, 0), true},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.hours)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.hours, err)
+			continue
+		}
+		if got := s.IsOpen(tt.time); got != tt.want {
+			t.Errorf("Parse(%q).IsOpen(%v) = %v, want %v", tt.hours, tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestStateAt(t *testing.T) {
+	s, _ := Parse(`Mo-Fr 09:00-17:00; Sa "by appointment" || off`)
+	if state, comment := s.StateAt(at(time.June, 8, 10, 0)); state != Unknown || comment != "by appointment" {
+		t.Errorf("StateAt(Saturday) = %v, %q", state, comment)
+	}
+	if state, _ := s.StateAt(at(time.June, 9, 10, 0)); state != Closed {
+		t.Errorf("Expected the fallback to close on Sunday, got %v", state)
+	}
+	if state, _ := s.StateAt(at(time.June, 3, 10, 0)); state != Open {
+		t.Errorf("Expected open on Monday, got %v", state)
+	}
+}
+
+func TestHolidays(t *testing.T) {
+	s, _ := Parse("Mo-Fr 09:00-17:00; PH off; Sa,PH 10:00-12:00")
+	christmas := func(date time.Time) bool { return date.Month() == time.December && date.Day() == 25 }
+	s.Holidays = christmas
+
+	if s.IsOpen(at(time.December, 25, 15, 0)) {
+		t.Error("Expected closed on the afternoon of a holiday")
+	}
+	if !s.IsOpen(at(time.December, 25, 11, 0)) {
+		t.Error("Expected open on the morning of a holiday")
+	}
+	if !s.IsOpen(at(time.December, 24, 15, 0)) {
+		t.Error("Expected open on a working day")
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	tests := []struct {
+		hours string
+		from  time.Time
+		want  time.Time
+	}{
+		{"Mo-Fr 09:00-17:00", at(time.June, 3, 10, 0), at(time.June, 3, 17, 0)},
+		{"Mo-Fr 09:00-17:00", at(time.June, 3, 17, 0), at(time.June, 4, 9, 0)},
+		{"Mo-Fr 09:00-17:00", at(time.June, 7, 18, 0), at(time.June, 10, 9, 0)},
+		{"Mo-Fr 09:00-13:00,15:00-19:00", at(time.June, 3, 12, 59), at(time.June, 3, 13, 0)},
+		{"Fr 22:00-02:00", at(time.June, 7, 23, 0), at(time.June, 8, 2, 0)},
+		{"Mo-Su 00:00-24:00; Dec 25 off", at(time.June, 1, 0, 0), at(time.December, 25, 0, 0)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.hours)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.hours, err)
+		}
+		if got, ok := s.NextChange(tt.from); !ok || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).NextChange(%v) = %v, %v, want %v", tt.hours, tt.from, got, ok, tt.want)
+		}
+	}
+
+	s, _ := Parse("24/7")
+	if next, ok := s.NextChange(at(time.June, 3, 10, 0)); ok {
+		t.Errorf("Expected 24/7 never to change, got %v", next)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	andorra, err := time.LoadLocation("Europe/Andorra")
+	if err != nil {
+		t.Skip("Time zone database unavailable")
+	}
+	s, _ := Parse("Mo-Fr 09:00-17:00")
+	s.Location = andorra
+
+	// 07:30 UTC is 09:30 in Andorra in summer
+	if !s.IsOpen(at(time.June, 3, 7, 30)) {
+		t.Error("Expected the hours to be evaluated in the schedule's time zone")
+	}
+	next, _ := s.NextChange(at(time.June, 3, 7, 30))
+	if want := at(time.June, 3, 15, 0); !next.Equal(want) {
+		t.Errorf("NextChange() = %v, want %v", next, want)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		hours string
+		pos   int
+	}{
+		{"", 0},
+		{"Mo-Fx 09:00-17:00", 3},
+		{"Mo-Fr 09:00-17:60", 15},
+		{"Mo-Fr 9-17", 7},
+		{"Mo-Fr 09:00-17:00 Sa 10:00-12:00", 18},
+		{"Mo-Fr 09:00-17:00;; Sa 10:00-12:00", 18},
+		{`Mo-Fr "open`, 6},
+		{"Jun 32", 4},
+		{"sunrise-sunset", 0},
+		{"Mo[0] 10:00-12:00", 3},
+		// Only the end of a span may be past midnight
+		{"Mo 25:00-26:00", 3},
+		{"Mo 24:30-26:00", 6},
+		{"Mo 20:00-48:30", 12},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.hours)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) = %v, want a syntax error", tt.hours, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("Parse(%q) error at %d, want %d: %v", tt.hours, syntaxErr.Pos, tt.pos, err)
+		}
+	}
+}
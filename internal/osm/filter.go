@@ -0,0 +1,176 @@
+// internal/osm/filter.go
+package osm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// elementKind is a set of OSM element types
+type elementKind uint8
+
+const (
+	nodeKind elementKind = 1 << iota
+	wayKind
+	relationKind
+
+	allKinds = nodeKind | wayKind | relationKind
+)
+
+// Filter selects the elements delivered by StreamProcess by their tags,
+// written as expressions in the syntax of osmium tags-filter:
+//
+//	n/amenity                         nodes with an amenity tag
+//	w/highway!=proposed,construction  ways with a highway tag of another value
+//	r/type=restriction                turn restriction relations
+//	addr:*                            elements with any addr: tag
+//	name=*straat                      elements whose name ends in "straat"
+//
+// The optional prefix lists the element types an expression applies to, by
+// default all of them; "*" in keys and values matches any text. An element
+// passes if any expression for its type matches. Element types that no
+// expression applies to are not filtered, so a filter of ways still delivers
+// every node. A nil Filter passes everything.
+type Filter struct {
+	rules []filterRule
+	kinds elementKind // Types with at least one rule
+}
+
+type filterRule struct {
+	kinds  elementKind
+	key    string
+	values []string // Values to match, nil for any value
+	negate bool     // Match values other than values
+}
+
+// RoutableFilter keeps the ways and relations needed for routing
+var RoutableFilter = MustParseFilter("w/highway", "w/junction", "r/type=restriction,route")
+
+// ParseFilter parses filter expressions, each of the form
+// [TYPES/]KEY[=VALUES] or [TYPES/]KEY!=VALUES, where TYPES is any of the
+// letters n, w and r and VALUES is a comma-separated list
+func ParseFilter(expressions ...string) (*Filter, error) {
+	f := &Filter{}
+	for _, expression := range expressions {
+		rule, err := parseFilterRule(expression)
+		if err != nil {
+			return nil, err
+		}
+		f.rules = append(f.rules, rule)
+		f.kinds |= rule.kinds
+	}
+	return f, nil
+}
+
+// MustParseFilter is like ParseFilter but panics if an expression is invalid
+func MustParseFilter(expressions ...string) *Filter {
+	f, err := ParseFilter(expressions...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func parseFilterRule(expression string) (filterRule, error) {
+	rule := filterRule{kinds: allKinds}
+	s := strings.TrimSpace(expression)
+	if types, rest, found := strings.Cut(s, "/"); found && types != "" && !strings.ContainsAny(types, "=!*") {
+		rule.kinds = 0
+		for _, t := range types {
+			switch t {
+			case 'n':
+				rule.kinds |= nodeKind
+			case 'w':
+				rule.kinds |= wayKind
+			case 'r':
+				rule.kinds |= relationKind
+			default:
+				return filterRule{}, fmt.Errorf("invalid filter %q: unknown element type %q", expression, t)
+			}
+		}
+		s = rest
+	}
+
+	key, values, hasValues := strings.Cut(s, "=")
+	if hasValues && strings.HasSuffix(key, "!") {
+		key, rule.negate = strings.TrimSuffix(key, "!"), true
+	}
+	rule.key = strings.TrimSpace(key)
+	if rule.key == "" {
+		return filterRule{}, fmt.Errorf("invalid filter %q: missing key", expression)
+	}
+	if hasValues {
+		for _, v := range strings.Split(values, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return filterRule{}, fmt.Errorf("invalid filter %q: empty value", expression)
+			}
+			rule.values = append(rule.values, v)
+		}
+	}
+	return rule, nil
+}
+
+// filters reports whether elements of the kind are filtered at all
+func (f *Filter) filters(kind elementKind) bool {
+	return f != nil && f.kinds&kind != 0
+}
+
+// match reports whether an element of the kind with the tags passes
+func (f *Filter) match(kind elementKind, tags osm.Tags) bool {
+	if !f.filters(kind) {
+		return true
+	}
+	for _, rule := range f.rules {
+		if rule.kinds&kind != 0 && rule.match(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r filterRule) match(tags osm.Tags) bool {
+	for _, tag := range tags {
+		if !wildcardMatch(r.key, tag.Key) {
+			continue
+		}
+		if r.values == nil {
+			return true
+		}
+		matched := false
+		for _, v := range r.values {
+			if wildcardMatch(v, tag.Value) {
+				matched = true
+				break
+			}
+		}
+		if matched != r.negate {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch reports whether s matches the pattern, in which "*" stands
+// for any text
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
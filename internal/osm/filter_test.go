@@ -0,0 +1,108 @@
+// internal/osm/filter_test.go
+package osm
+
+import (
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestFilter_Match(t *testing.T) {
+	tags := func(kv ...string) osm.Tags {
+		var t osm.Tags
+		for i := 0; i < len(kv); i += 2 {
+			t = append(t, osm.Tag{Key: kv[i], Value: kv[i+1]})
+		}
+		return t
+	}
+
+	tests := []struct {
+		expressions []string
+		kind        elementKind
+		tags        osm.Tags
+		want        bool
+	}{
+		{[]string{"n/amenity"}, nodeKind, tags("amenity", "cafe"), true},
+		{[]string{"n/amenity"}, nodeKind, tags("shop", "bakery"), false},
+		// Types without expressions are not filtered
+		{[]string{"n/amenity"}, wayKind, tags("shop", "bakery"), true},
+		{[]string{"amenity"}, wayKind, tags("shop", "bakery"), false},
+		{[]string{"nw/amenity"}, relationKind, nil, true},
+
+		{[]string{"w/highway=primary,secondary"}, wayKind, tags("highway", "secondary"), true},
+		{[]string{"w/highway=primary,secondary"}, wayKind, tags("highway", "track"), false},
+		{[]string{"w/highway!=proposed,construction"}, wayKind, tags("highway", "residential"), true},
+		{[]string{"w/highway!=proposed,construction"}, wayKind, tags("highway", "construction"), false},
+		{[]string{"w/highway!=proposed,construction"}, wayKind, tags("name", "Carrer Major"), false},
+		{[]string{"r/type=restriction"}, relationKind, tags("type", "restriction"), true},
+		{[]string{"r/type=restriction"}, relationKind, tags("type", "route"), false},
+
+		{[]string{"addr:*"}, nodeKind, tags("addr:housenumber", "12"), true},
+		{[]string{"addr:*"}, nodeKind, tags("address", "12"), false},
+		{[]string{"*"}, nodeKind, tags("note", "x"), true},
+		{[]string{"*"}, nodeKind, nil, false},
+		{[]string{"name=*straat"}, wayKind, tags("name", "Kerkstraat"), true},
+		{[]string{"name=*straat"}, wayKind, tags("name", "Straatweg"), false},
+		{[]string{"name=*major*"}, wayKind, tags("name", "Carrer majors"), true},
+		{[]string{"highway!=*_link"}, wayKind, tags("highway", "primary_link"), false},
+
+		{[]string{"w/highway", "n/amenity"}, wayKind, tags("amenity", "cafe"), false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expressions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.match(tt.kind, tt.tags); got != tt.want {
+			t.Errorf("%v matching %d %v = %v, want %v", tt.expressions, tt.kind, tt.tags, got, tt.want)
+		}
+	}
+
+	if !RoutableFilter.match(wayKind, tags("junction", "roundabout")) || RoutableFilter.match(wayKind, tags("building", "yes")) {
+		t.Error("Expected the routable filter to pass highways and junctions only")
+	}
+
+	var none *Filter
+	if !none.match(wayKind, nil) {
+		t.Error("Expected a nil filter to pass everything")
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "x/highway", "w/", "=primary", "highway=", "highway=primary,", "!=primary"} {
+		if _, err := ParseFilter(expression); err == nil {
+			t.Errorf("Expected an error for %q", expression)
+		}
+	}
+}
+
+func TestParsePBF_Filter(t *testing.T) {
+	filter := MustParseFilter("n/amenity=restaurant", "w/building", "r/boundary=administrative")
+	data, err := LoadPBF("./../../data/andorra-latest.osm.pbf", filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	if len(data.TaggedNodes) == 0 || len(data.Ways) == 0 || len(data.Relations) == 0 {
+		t.Fatalf("Expected nodes, ways and relations, got %d, %d and %d", len(data.TaggedNodes), len(data.Ways), len(data.Relations))
+	}
+	for _, node := range data.TaggedNodes {
+		if node.Tags.Get("amenity") != "restaurant" {
+			t.Fatalf("Node %d is not a restaurant: %v", node.ID, node.Tags)
+		}
+	}
+	if data.Nodes.Len() != len(data.TaggedNodes) {
+		t.Errorf("Expected only the %d restaurants, got %d nodes", len(data.TaggedNodes), data.Nodes.Len())
+	}
+	for _, way := range data.Ways {
+		if way.Tags.Get("building") == "" {
+			t.Fatalf("Way %d is not a building: %v", way.ID, way.Tags)
+		}
+	}
+	for _, relation := range data.Relations {
+		if relation.Tags.Get("boundary") != "administrative" {
+			t.Fatalf("Relation %d is not a boundary: %v", relation.ID, relation.Tags)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// internal/osm/mmap_other.go
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package osm
+
+import (
+	"errors"
+	"os"
+)
+
+// errNoMmap makes file-backed stores fall back to reading and writing the
+// file directly
+var errNoMmap = errors.New("memory-mapped files are not supported on this platform")
+
+func mapFile(f *os.File, size int64) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+// internal/osm/mmap_unix.go
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package osm
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of a file into memory for reading and
+// writing
+func mapFile(f *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
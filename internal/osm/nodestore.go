@@ -0,0 +1,166 @@
+// internal/osm/nodestore.go
+package osm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NodeStore keeps the locations of nodes so that way geometries can be built
+// after the nodes have streamed past. Stores are not safe for concurrent use.
+type NodeStore interface {
+	// Set stores the location of a node, replacing any earlier one
+	Set(id ID, lat, lon float64) error
+	// Get returns the location of a node and whether it is known
+	Get(id ID) (lat, lon float64, ok bool)
+	// Len returns the number of nodes stored
+	Len() int
+	// Close releases the memory and files held by the store
+	Close() error
+}
+
+// Input sizes in bytes of PBF files up to which each store is chosen by
+// NewNodeStore. PBF files hold roughly one node per 8 bytes.
+const (
+	sparseStoreMaxInput  = 1 << 30  // Up to about 130 million nodes, 16 bytes each in memory
+	chunkedStoreMaxInput = 16 << 30 // Up to about 2 billion nodes, 16 bytes each on disk
+)
+
+// NewNodeStore returns the store suited to a PBF input of the given size in
+// bytes: a sparse array in memory for extracts, sorted chunks on disk for
+// large countries and continents, and a dense file indexed by ID for the
+// planet, where nearly every ID is used. Files are created in dir, or in the
+// default directory for temporary files if dir is empty. An unknown size of
+// 0 or less selects the in-memory store.
+func NewNodeStore(inputSize int64, dir string) (NodeStore, error) {
+	switch {
+	case inputSize < sparseStoreMaxInput:
+		return NewSparseNodeStore(), nil
+	case inputSize < chunkedStoreMaxInput:
+		return NewChunkedNodeStore(dir)
+	default:
+		return NewDenseNodeStore(dir)
+	}
+}
+
+// Locations are stored as fixed-point numbers with seven decimals, the
+// precision of OSM coordinates
+const coordScale = 1e7
+
+func toFixed(degrees float64) int32 {
+	return int32(math.Round(degrees * coordScale))
+}
+
+func fromFixed(v int32) float64 {
+	return float64(v) / coordScale
+}
+
+func checkLocation(id ID, lat, lon float64) error {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("node %d: invalid location %f, %f", id, lat, lon)
+	}
+	return nil
+}
+
+// negativeNodes keeps the locations of nodes with negative IDs, which
+// editors such as JOSM give to new objects. They are rare, so a map is kept
+// next to the arrays and files indexed by positive IDs.
+type negativeNodes struct {
+	nodes map[ID][2]int32
+}
+
+func (n *negativeNodes) set(id ID, lat, lon float64) {
+	if n.nodes == nil {
+		n.nodes = make(map[ID][2]int32)
+	}
+	n.nodes[id] = [2]int32{toFixed(lat), toFixed(lon)}
+}
+
+func (n *negativeNodes) get(id ID) (float64, float64, bool) {
+	location, ok := n.nodes[id]
+	return fromFixed(location[0]), fromFixed(location[1]), ok
+}
+
+type sparseEntry struct {
+	id       ID
+	lat, lon int32
+}
+
+// SparseNodeStore keeps locations in memory in an array sorted by ID, 16
+// bytes per node. PBF files list nodes by ascending ID, so the array is
+// usually sorted as it is filled.
+type SparseNodeStore struct {
+	entries  []sparseEntry
+	sorted   bool
+	negative negativeNodes
+}
+
+func NewSparseNodeStore() *SparseNodeStore {
+	return &SparseNodeStore{sorted: true}
+}
+
+func (s *SparseNodeStore) Set(id ID, lat, lon float64) error {
+	if err := checkLocation(id, lat, lon); err != nil {
+		return err
+	}
+	if id < 0 {
+		s.negative.set(id, lat, lon)
+		return nil
+	}
+	entry := sparseEntry{id: id, lat: toFixed(lat), lon: toFixed(lon)}
+	if n := len(s.entries); n > 0 && s.sorted {
+		switch last := s.entries[n-1].id; {
+		case id == last:
+			s.entries[n-1] = entry
+			return nil
+		case id < last:
+			s.sorted = false
+		}
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *SparseNodeStore) Get(id ID) (float64, float64, bool) {
+	if id < 0 {
+		return s.negative.get(id)
+	}
+	s.sort()
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].id >= id })
+	if i == len(s.entries) || s.entries[i].id != id {
+		return 0, 0, false
+	}
+	return fromFixed(s.entries[i].lat), fromFixed(s.entries[i].lon), true
+}
+
+func (s *SparseNodeStore) Len() int {
+	s.sort()
+	return len(s.entries) + len(s.negative.nodes)
+}
+
+func (s *SparseNodeStore) Close() error {
+	s.entries = nil
+	s.sorted = true
+	s.negative = negativeNodes{}
+	return nil
+}
+
+// sort orders the entries after out-of-order additions, keeping the last
+// location set for each ID
+func (s *SparseNodeStore) sort() {
+	if s.sorted {
+		return
+	}
+	sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].id < s.entries[j].id })
+	kept := s.entries[:0]
+	for _, e := range s.entries {
+		if n := len(kept); n > 0 && kept[n-1].id == e.id {
+			kept[n-1] = e
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	s.entries = kept
+	s.sorted = true
+}
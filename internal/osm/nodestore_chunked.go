@@ -0,0 +1,204 @@
+// internal/osm/nodestore_chunked.go
+package osm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const (
+	// chunkEntrySize is the size in bytes of an ID and location on disk
+	chunkEntrySize = 16
+
+	// chunkSize is the number of entries buffered in memory before they are
+	// sorted and written as a chunk
+	chunkSize = 1 << 20
+
+	// chunkBlockSize is the number of entries read at once; the first ID of
+	// every block is kept in memory to find the block holding a node
+	chunkBlockSize = 512
+)
+
+// nodeChunk is a run of entries sorted by ID in the file of a chunked store
+type nodeChunk struct {
+	offset int64 // Position of the first entry in the file, in bytes
+	length int   // Number of entries
+	first  ID
+	last   ID
+	blocks []ID // First ID of every block
+}
+
+// ChunkedNodeStore buffers locations in memory and writes them to a file in
+// chunks sorted by ID, keeping only a small index of each chunk in memory.
+// It suits inputs too large to hold in memory whose IDs are too sparse for a
+// dense store. Each ID is expected to be set once.
+type ChunkedNodeStore struct {
+	file    *os.File
+	size    int64         // Bytes written to the file
+	buffer  []sparseEntry // Entries not yet written
+	sorted  bool          // Whether the buffer is sorted by ID
+	chunks  []nodeChunk
+	ordered bool // Whether the chunks follow each other without overlapping
+
+	negative negativeNodes
+
+	// The block read last, since nodes of a way are often close in ID
+	cached      []byte
+	cachedChunk int
+	cachedBlock int
+}
+
+// NewChunkedNodeStore creates a chunked store in a temporary file in dir,
+// which is removed on Close
+func NewChunkedNodeStore(dir string) (*ChunkedNodeStore, error) {
+	file, err := os.CreateTemp(dir, "nodes-chunked-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node store: %w", err)
+	}
+	return &ChunkedNodeStore{file: file, sorted: true, ordered: true, cachedChunk: -1}, nil
+}
+
+func (s *ChunkedNodeStore) Set(id ID, lat, lon float64) error {
+	if err := checkLocation(id, lat, lon); err != nil {
+		return err
+	}
+	if id < 0 {
+		s.negative.set(id, lat, lon)
+		return nil
+	}
+	if n := len(s.buffer); n > 0 && s.buffer[n-1].id > id {
+		s.sorted = false
+	}
+	s.buffer = append(s.buffer, sparseEntry{id: id, lat: toFixed(lat), lon: toFixed(lon)})
+	if len(s.buffer) == chunkSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *ChunkedNodeStore) Get(id ID) (float64, float64, bool) {
+	if id < 0 {
+		return s.negative.get(id)
+	}
+	// The unwritten buffer holds the most recent locations
+	s.sortBuffer()
+	if i := sort.Search(len(s.buffer), func(i int) bool { return s.buffer[i].id > id }) - 1; i >= 0 && s.buffer[i].id == id {
+		return fromFixed(s.buffer[i].lat), fromFixed(s.buffer[i].lon), true
+	}
+
+	if s.ordered {
+		i := sort.Search(len(s.chunks), func(i int) bool { return s.chunks[i].last >= id })
+		if i < len(s.chunks) && s.chunks[i].first <= id {
+			return s.find(i, id)
+		}
+		return 0, 0, false
+	}
+	// Later chunks replace earlier ones
+	for i := len(s.chunks) - 1; i >= 0; i-- {
+		if s.chunks[i].first <= id && id <= s.chunks[i].last {
+			if lat, lon, ok := s.find(i, id); ok {
+				return lat, lon, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func (s *ChunkedNodeStore) Len() int {
+	n := len(s.buffer) + len(s.negative.nodes)
+	for _, c := range s.chunks {
+		n += c.length
+	}
+	return n
+}
+
+func (s *ChunkedNodeStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if removeErr := os.Remove(s.file.Name()); err == nil {
+		err = removeErr
+	}
+	s.file = nil
+	s.buffer, s.chunks, s.cached = nil, nil, nil
+	s.negative = negativeNodes{}
+	return err
+}
+
+// flush sorts the buffer and writes it to the file as a chunk
+func (s *ChunkedNodeStore) flush() error {
+	if len(s.buffer) == 0 {
+		return nil
+	}
+	s.sortBuffer()
+
+	chunk := nodeChunk{
+		offset: s.size,
+		length: len(s.buffer),
+		first:  s.buffer[0].id,
+		last:   s.buffer[len(s.buffer)-1].id,
+	}
+	data := make([]byte, len(s.buffer)*chunkEntrySize)
+	for i, e := range s.buffer {
+		if i%chunkBlockSize == 0 {
+			chunk.blocks = append(chunk.blocks, e.id)
+		}
+		entry := data[i*chunkEntrySize:]
+		binary.LittleEndian.PutUint64(entry, uint64(e.id))
+		binary.LittleEndian.PutUint32(entry[8:], uint32(e.lat))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(e.lon))
+	}
+	if _, err := s.file.WriteAt(data, s.size); err != nil {
+		return fmt.Errorf("failed to write node chunk: %w", err)
+	}
+
+	if n := len(s.chunks); n > 0 && s.chunks[n-1].last >= chunk.first {
+		s.ordered = false
+	}
+	s.chunks = append(s.chunks, chunk)
+	s.size += int64(len(data))
+	s.buffer = s.buffer[:0]
+	s.sorted = true
+	return nil
+}
+
+// sortBuffer orders the buffer by ID, keeping entries with equal IDs in the
+// order they were set
+func (s *ChunkedNodeStore) sortBuffer() {
+	if !s.sorted {
+		sort.SliceStable(s.buffer, func(i, j int) bool { return s.buffer[i].id < s.buffer[j].id })
+		s.sorted = true
+	}
+}
+
+// find looks a node up in a chunk, reading the block that may hold it
+func (s *ChunkedNodeStore) find(chunkIndex int, id ID) (float64, float64, bool) {
+	chunk := &s.chunks[chunkIndex]
+	block := sort.Search(len(chunk.blocks), func(i int) bool { return chunk.blocks[i] > id }) - 1
+	if block < 0 {
+		return 0, 0, false
+	}
+	if s.cachedChunk != chunkIndex || s.cachedBlock != block {
+		length := min(chunkBlockSize, chunk.length-block*chunkBlockSize)
+		data := make([]byte, length*chunkEntrySize)
+		if _, err := s.file.ReadAt(data, chunk.offset+int64(block*chunkBlockSize*chunkEntrySize)); err != nil {
+			return 0, 0, false
+		}
+		s.cached, s.cachedChunk, s.cachedBlock = data, chunkIndex, block
+	}
+
+	n := len(s.cached) / chunkEntrySize
+	entryID := func(i int) ID { return ID(binary.LittleEndian.Uint64(s.cached[i*chunkEntrySize:])) }
+	// The last of equal IDs was set last
+	i := sort.Search(n, func(i int) bool { return entryID(i) > id }) - 1
+	if i < 0 || entryID(i) != id {
+		return 0, 0, false
+	}
+	entry := s.cached[i*chunkEntrySize:]
+	lat := int32(binary.LittleEndian.Uint32(entry[8:]))
+	lon := int32(binary.LittleEndian.Uint32(entry[12:]))
+	return fromFixed(lat), fromFixed(lon), true
+}
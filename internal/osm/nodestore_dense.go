@@ -0,0 +1,158 @@
+// internal/osm/nodestore_dense.go
+package osm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+const (
+	// denseSlotSize is the size in bytes of a location in a dense store
+	denseSlotSize = 8
+
+	// minDenseCapacity is the number of IDs a dense store first makes room for
+	minDenseCapacity = 1 << 20
+
+	// Coordinates are stored offset to be positive, so that the zeros of
+	// unwritten parts of the file mark unknown nodes
+	latOffset = 90*coordScale + 1
+	lonOffset = 180*coordScale + 1
+)
+
+// DenseNodeStore keeps locations in a file with an 8-byte slot for every ID
+// up to the largest one stored, mapped into memory where the platform allows.
+// It suits the planet, whose IDs are nearly all in use; the file is sparse,
+// so unused ranges of IDs take no disk space on most file systems.
+type DenseNodeStore struct {
+	file     *os.File
+	data     []byte // The mapped file, nil if it is read and written directly
+	capacity int64  // Number of slots in the file
+	count    int
+	negative negativeNodes
+}
+
+// NewDenseNodeStore creates a dense store in a temporary file in dir, which
+// is removed on Close
+func NewDenseNodeStore(dir string) (*DenseNodeStore, error) {
+	file, err := os.CreateTemp(dir, "nodes-dense-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node store: %w", err)
+	}
+	return &DenseNodeStore{file: file}, nil
+}
+
+func (s *DenseNodeStore) Set(id ID, lat, lon float64) error {
+	if err := checkLocation(id, lat, lon); err != nil {
+		return err
+	}
+	if id < 0 {
+		s.negative.set(id, lat, lon)
+		return nil
+	}
+	if int64(id) >= s.capacity {
+		if err := s.grow(int64(id) + 1); err != nil {
+			return err
+		}
+	}
+
+	var slot [denseSlotSize]byte
+	if err := s.read(id, slot[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(slot[:4]) == 0 {
+		s.count++
+	}
+	binary.LittleEndian.PutUint32(slot[:4], uint32(int64(toFixed(lat))+latOffset))
+	binary.LittleEndian.PutUint32(slot[4:], uint32(int64(toFixed(lon))+lonOffset))
+	return s.write(id, slot[:])
+}
+
+func (s *DenseNodeStore) Get(id ID) (float64, float64, bool) {
+	if id < 0 {
+		return s.negative.get(id)
+	}
+	if int64(id) >= s.capacity {
+		return 0, 0, false
+	}
+	var slot [denseSlotSize]byte
+	if err := s.read(id, slot[:]); err != nil {
+		return 0, 0, false
+	}
+	lat := binary.LittleEndian.Uint32(slot[:4])
+	if lat == 0 {
+		return 0, 0, false
+	}
+	lon := binary.LittleEndian.Uint32(slot[4:])
+	return fromFixed(int32(int64(lat) - latOffset)), fromFixed(int32(int64(lon) - lonOffset)), true
+}
+
+func (s *DenseNodeStore) Len() int {
+	return s.count + len(s.negative.nodes)
+}
+
+func (s *DenseNodeStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	var err error
+	if s.data != nil {
+		err = unmapFile(s.data)
+		s.data = nil
+	}
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(s.file.Name()); err == nil {
+		err = removeErr
+	}
+	s.file = nil
+	s.capacity, s.count = 0, 0
+	s.negative = negativeNodes{}
+	return err
+}
+
+// grow enlarges the file to hold at least n slots, doubling it to keep the
+// number of remappings low
+func (s *DenseNodeStore) grow(n int64) error {
+	capacity := max(n, 2*s.capacity, minDenseCapacity)
+	if s.data != nil {
+		if err := unmapFile(s.data); err != nil {
+			return fmt.Errorf("failed to grow node store: %w", err)
+		}
+		s.data = nil
+	}
+	if err := s.file.Truncate(capacity * denseSlotSize); err != nil {
+		return fmt.Errorf("failed to grow node store: %w", err)
+	}
+	s.capacity = capacity
+	// Without a mapping the file is read and written directly
+	if data, err := mapFile(s.file, capacity*denseSlotSize); err == nil {
+		s.data = data
+	}
+	return nil
+}
+
+func (s *DenseNodeStore) read(id ID, slot []byte) error {
+	offset := int64(id) * denseSlotSize
+	if s.data != nil {
+		copy(slot, s.data[offset:offset+denseSlotSize])
+		return nil
+	}
+	if _, err := s.file.ReadAt(slot, offset); err != nil {
+		return fmt.Errorf("failed to read node %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *DenseNodeStore) write(id ID, slot []byte) error {
+	offset := int64(id) * denseSlotSize
+	if s.data != nil {
+		copy(s.data[offset:offset+denseSlotSize], slot)
+		return nil
+	}
+	if _, err := s.file.WriteAt(slot, offset); err != nil {
+		return fmt.Errorf("failed to write node %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// internal/osm/nodestore_test.go
+package osm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNodeStores(t *testing.T) {
+	stores := map[string]func() (NodeStore, error){
+		"sparse":  func() (NodeStore, error) { return NewSparseNodeStore(), nil },
+		"dense":   func() (NodeStore, error) { return NewDenseNodeStore(t.TempDir()) },
+		"chunked": func() (NodeStore, error) { return NewChunkedNodeStore(t.TempDir()) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s, err := newStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			locations := []struct {
+				id       ID
+				lat, lon float64
+			}{
+				{1, 42.5063, 1.5218},
+				{3854300084, 42.4913026, 1.5963506},
+				{2, -90, -180},
+				{5, 90, 180},
+				{4, 0, 0},
+				// Negative IDs of objects created in an editor
+				{-3, 42.51, 1.53},
+			}
+			for _, l := range locations {
+				if err := s.Set(l.id, l.lat, l.lon); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Replaced by a later location
+			if err := s.Set(1, 42.5, 1.5); err != nil {
+				t.Fatal(err)
+			}
+			locations[0].lat, locations[0].lon = 42.5, 1.5
+
+			for _, l := range locations {
+				lat, lon, ok := s.Get(l.id)
+				if !ok || math.Abs(lat-l.lat) > 1e-7 || math.Abs(lon-l.lon) > 1e-7 {
+					t.Errorf("Get(%d) = %f, %f, %v, want %f, %f", l.id, lat, lon, ok, l.lat, l.lon)
+				}
+			}
+			for _, id := range []ID{0, 3, 6, 1 << 40, -1} {
+				if _, _, ok := s.Get(id); ok {
+					t.Errorf("Get(%d) found a node that was not set", id)
+				}
+			}
+			if name != "chunked" && s.Len() != len(locations) {
+				t.Errorf("Len() = %d, want %d", s.Len(), len(locations))
+			}
+
+			if err := s.Set(7, 91, 0); err == nil {
+				t.Error("Expected an error for an invalid latitude")
+			}
+		})
+	}
+}
+
+func TestChunkedNodeStore_Chunks(t *testing.T) {
+	s, err := NewChunkedNodeStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Two full chunks and a buffered remainder, the second chunk overlapping
+	// the first
+	n := 2*chunkSize + 100
+	for i := 0; i < n; i++ {
+		id := ID(2 * i)
+		if i >= chunkSize && i < 2*chunkSize {
+			id = ID(2*(i-chunkSize) + 1)
+		}
+		if err := s.Set(id, float64(i%90), float64(i%180)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.chunks) != 2 || s.ordered {
+		t.Fatalf("Expected 2 overlapping chunks, got %d, ordered %v", len(s.chunks), s.ordered)
+	}
+	if s.Len() != n {
+		t.Errorf("Len() = %d, want %d", s.Len(), n)
+	}
+
+	tests := []struct {
+		id ID
+		i  int
+	}{
+		{0, 0},
+		{2 * 1000, 1000},
+		{2*1000 + 1, chunkSize + 1000},
+		{2 * (2*chunkSize + 50), 2*chunkSize + 50},
+	}
+	for _, tt := range tests {
+		lat, lon, ok := s.Get(tt.id)
+		if !ok || lat != float64(tt.i%90) || lon != float64(tt.i%180) {
+			t.Errorf("Get(%d) = %f, %f, %v, want entry %d", tt.id, lat, lon, ok, tt.i)
+		}
+	}
+	if _, _, ok := s.Get(ID(2*n + 1)); ok {
+		t.Error("Found a node that was not set")
+	}
+}
+
+func TestNewNodeStore(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "sparse"},
+		{3 << 20, "sparse"},
+		{2 << 30, "chunked"},
+		{80 << 30, "dense"},
+	}
+	for _, tt := range tests {
+		s, err := NewNodeStore(tt.size, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		switch s.(type) {
+		case *SparseNodeStore:
+			got = "sparse"
+		case *ChunkedNodeStore:
+			got = "chunked"
+		case *DenseNodeStore:
+			got = "dense"
+		}
+		if got != tt.want {
+			t.Errorf("NewNodeStore(%d) = %s store, want %s", tt.size, got, tt.want)
+		}
+		s.Close()
+	}
+}
@@ -21,42 +21,118 @@ func isURL(filePath string) (*url.URL, bool) {
 	return nil, false
 }
 
-func ParsePBF(filePath string, onlyRoutable bool, processor Processor) error {
-	if !strings.HasSuffix(strings.ToLower(filePath), ".osm.pbf") {
-		return fmt.Errorf("invalid file extension: file must end with .osm.pbf")
+// LoadPBF reads a PBF file or URL into memory, keeping node locations in
+// the store suited to its size. Close the returned data to release the
+// store's files.
+func LoadPBF(filePath string, filter *Filter) (*OSMData, error) {
+	reader, size, err := openPBF(filePath)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
 
-	var reader io.ReadCloser
-	var err error
-
-	if parsedURL, isURL := isURL(filePath); isURL {
-		reader, err = getURLReader(parsedURL.String())
-	} else {
-		reader, err = getFileReader(filePath)
+	nodes, err := NewNodeStore(size, "")
+	if err != nil {
+		return nil, err
 	}
+	data := &OSMData{Nodes: nodes}
+	if err := StreamProcess(reader, data, filter); err != nil {
+		data.Close()
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParsePBF streams the elements of a PBF file or URL that pass the filter
+// through a processor
+func ParsePBF(filePath string, filter *Filter, processor Processor) error {
+	reader, _, err := openPBF(filePath)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
-	return StreamProcess(reader, processor, onlyRoutable)
+	return StreamProcess(reader, processor, filter)
+}
+
+// LoadPBFTwoPass reads a PBF file or URL into memory like LoadPBF, but in
+// two passes, keeping only the nodes referenced by the ways and relations
+// that pass the filter. URLs are downloaded twice.
+func LoadPBFTwoPass(filePath string, filter *Filter) (*OSMData, error) {
+	reader, size, err := openPBF(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := NewNodeStore(size, "")
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	data := &OSMData{Nodes: nodes}
+	if err := processTwoPass(reopenPBF(filePath, reader), data, filter); err != nil {
+		data.Close()
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParsePBFTwoPass streams a PBF file or URL through a processor in two
+// passes, delivering only the nodes referenced by the ways and relations
+// that pass the filter
+func ParsePBFTwoPass(filePath string, filter *Filter, processor Processor) error {
+	return processTwoPass(reopenPBF(filePath, nil), processor, filter)
+}
+
+// reopenPBF returns a function opening a PBF file or URL for each pass,
+// starting with reader if it is already open
+func reopenPBF(filePath string, reader io.ReadCloser) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if reader != nil {
+			r := reader
+			reader = nil
+			return r, nil
+		}
+		r, _, err := openPBF(filePath)
+		return r, err
+	}
+}
+
+// openPBF opens a PBF file or URL and returns its size in bytes, or 0 if
+// unknown
+func openPBF(filePath string) (io.ReadCloser, int64, error) {
+	if !strings.HasSuffix(strings.ToLower(filePath), ".osm.pbf") {
+		return nil, 0, fmt.Errorf("invalid file extension: file must end with .osm.pbf")
+	}
+	if parsedURL, isURL := isURL(filePath); isURL {
+		return getURLReader(parsedURL.String())
+	}
+	return getFileReader(filePath)
 }
 
-func getURLReader(url string) (io.ReadCloser, error) {
+func getURLReader(url string) (io.ReadCloser, int64, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
+		return nil, 0, fmt.Errorf("failed to download file: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
-		return nil, fmt.Errorf("failed to download file: HTTP status %d", resp.StatusCode)
+		return nil, 0, fmt.Errorf("failed to download file: HTTP status %d", resp.StatusCode)
 	}
-	return resp.Body, nil
+	return resp.Body, max(resp.ContentLength, 0), nil
 }
 
-func getFileReader(filePath string) (io.ReadCloser, error) {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("file does not exist: %s", filePath)
+func getFileReader(filePath string) (io.ReadCloser, int64, error) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, 0, fmt.Errorf("file does not exist: %s", filePath)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
 	}
-	return os.Open(filePath)
+	return file, info.Size(), nil
 }
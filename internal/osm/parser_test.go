@@ -1,26 +1,32 @@
 package osm
 
 import (
+	"math"
+	"os"
 	"testing"
 )
 
 func TestParsePBF(t *testing.T) {
-	data, err := ParsePBF("./../../data/andorra-latest.osm.pbf", false)
+	data, err := LoadPBF("./../../data/andorra-latest.osm.pbf", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	defer data.Close()
+
 	// Test nodes
-	if len(data.Nodes) != 471006 {
-		t.Fatalf("Expected 471006 nodes, got %d", len(data.Nodes))
+	if data.Nodes.Len() != 471006 {
+		t.Fatalf("Expected 471006 nodes, got %d", data.Nodes.Len())
 	}
 
-	if data.Nodes[3854300084].Lon != 1.5963506 {
-		t.Fatalf("Expected Longitude 1.5963506, got %f", data.Nodes[3854300084].Lon)
+	// Locations are stored with the seven decimals of OSM coordinates
+	lat, lon, ok := data.Nodes.Get(3854300084)
+	if !ok || math.Abs(lon-1.5963506) > 1e-9 {
+		t.Fatalf("Expected Longitude 1.5963506, got %f", lon)
 	}
 
-	if data.Nodes[3854300084].Lat != 42.491302600000004 {
-		t.Fatalf("Expected Latitude 42.491302600000004, got %f", data.Nodes[3854300084].Lat)
+	if math.Abs(lat-42.4913026) > 1e-9 {
+		t.Fatalf("Expected Latitude 42.4913026, got %f", lat)
 	}
 
 	// Test ways
@@ -99,14 +105,16 @@ func TestParsePBF(t *testing.T) {
 }
 
 func TestParsePBFOnlyRoutable(t *testing.T) {
-	data, err := ParsePBF("./../../data/andorra-latest.osm.pbf", true)
+	data, err := LoadPBF("./../../data/andorra-latest.osm.pbf", RoutableFilter)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	defer data.Close()
+
 	// Test nodes
-	if len(data.Nodes) != 471006 {
-		t.Fatalf("Expected 471006 nodes, got %d", len(data.Nodes))
+	if data.Nodes.Len() != 471006 {
+		t.Fatalf("Expected 471006 nodes, got %d", data.Nodes.Len())
 	}
 
 	// Test ways
@@ -115,6 +123,65 @@ func TestParsePBFOnlyRoutable(t *testing.T) {
 	}
 }
 
+func TestParsePBFTwoPass(t *testing.T) {
+	data, err := LoadPBFTwoPass("./../../data/andorra-latest.osm.pbf", RoutableFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	// The same ways, with only the nodes they reference
+	if len(data.Ways) != 7763 {
+		t.Fatalf("Expected 7763 ways, got %d", len(data.Ways))
+	}
+	if data.Nodes.Len() == 0 || data.Nodes.Len() >= 471006 {
+		t.Fatalf("Expected fewer than 471006 nodes, got %d", data.Nodes.Len())
+	}
+	refs := make(map[ID]bool)
+	for _, way := range data.Ways {
+		for _, id := range way.Nodes {
+			refs[id] = true
+		}
+	}
+	for _, relation := range data.Relations {
+		for _, member := range relation.Members {
+			if member.Type == "node" {
+				refs[member.Ref] = true
+			}
+		}
+	}
+	for id := range refs {
+		if _, _, ok := data.Nodes.Get(id); !ok {
+			t.Fatalf("Node %d of a way is missing", id)
+		}
+	}
+	if data.Nodes.Len() > len(refs) {
+		t.Errorf("Expected at most %d nodes, got %d", len(refs), data.Nodes.Len())
+	}
+}
+
+func TestStreamProcessTwoPass(t *testing.T) {
+	file, err := os.Open("./../../data/andorra-latest.osm.pbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	data := &OSMData{Nodes: NewSparseNodeStore()}
+	defer data.Close()
+	if err := StreamProcessTwoPass(file, data, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Nodes that are not part of a way or relation, such as most POIs, are
+	// left out
+	if data.Nodes.Len() == 0 || data.Nodes.Len() >= 471006 {
+		t.Errorf("Expected fewer than 471006 nodes, got %d", data.Nodes.Len())
+	}
+	if len(data.Ways) == 0 || len(data.Relations) == 0 {
+		t.Errorf("Expected ways and relations, got %d and %d", len(data.Ways), len(data.Relations))
+	}
+}
+
 // Helper function to find a way by ID
 func findWayByID(ways []Way, id ID) (Way, bool) {
 	for _, way := range ways {
@@ -164,13 +231,13 @@ func compareTags(tags1, tags2 Tags) bool {
 func TestParsePBFFromURL(t *testing.T) {
 	// Replace with a valid URL pointing to an OSM PBF file
 	url := "https://download.geofabrik.de/europe/andorra-latest.osm.pbf"
-	data, err := ParsePBF(url, false)
+	data, err := LoadPBF(url, nil)
 	if err != nil {
 		t.Fatalf("ParsePBFFromURL() error: %v", err)
 	}
 
 	// Verify the parsed data
-	if len(data.Nodes) == 0 {
+	if data.Nodes.Len() == 0 {
 		t.Error("Expected nodes, got none")
 	}
 	if len(data.Ways) == 0 {
@@ -183,21 +250,21 @@ func TestParsePBFFromURL(t *testing.T) {
 
 func TestParsePBFFromInvalidURL(t *testing.T) {
 	url := "https://example.com/invalid-file.txt"
-	_, err := ParsePBF(url, false)
+	_, err := LoadPBF(url, nil)
 	if err == nil {
 		t.Error("Expected error for invalid URL, got nil")
 	}
 }
 
 func TestParsePBF_InvalidExtension(t *testing.T) {
-	_, err := ParsePBF("test.txt", false)
+	_, err := LoadPBF("test.txt", nil)
 	if err == nil {
 		t.Error("Expected error for invalid file extension")
 	}
 }
 
 func TestParsePBF_NonexistentFile(t *testing.T) {
-	_, err := ParsePBF("nonexistent.osm.pbf", false)
+	_, err := LoadPBF("nonexistent.osm.pbf", nil)
 	if err == nil {
 		t.Error("Expected error for nonexistent file")
 	}
@@ -3,7 +3,9 @@ package osm
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sort"
 
 	"github.com/paulmach/osm"
 	"github.com/paulmach/osm/osmpbf"
@@ -16,10 +18,105 @@ type Processor interface {
 	ProcessRelation(relation *Relation) error
 }
 
-// StreamProcess processes OSM data in a streaming fashion
-func StreamProcess(reader io.Reader, processor Processor, onlyRoutable bool) error {
-	scanner := osmpbf.New(context.Background(), reader, 3)
+// RelationWayProcessor is a Processor that needs the node lists of the ways
+// that relations refer to. Relations follow ways in PBF files, so without
+// knowing those ways in advance it would have to keep every way. Two-pass
+// processing calls SetRelationWays before delivering any element.
+type RelationWayProcessor interface {
+	Processor
+	SetRelationWays(isMember func(id ID) bool)
+}
+
+// StreamProcess processes OSM data in a streaming fashion, delivering the
+// elements that pass the filter, or all of them if it is nil
+func StreamProcess(reader io.Reader, processor Processor, filter *Filter) error {
+	return stream(reader, processor, filter, nil)
+}
+
+// StreamProcessTwoPass processes OSM data in two passes over the reader. PBF
+// files list nodes before ways, so the first pass collects the nodes
+// referenced by the ways and relations that pass the filter, and the second
+// delivers only those nodes and the nodes the filter selects itself, followed
+// by the ways and relations. Processors then need not keep the location of
+// every node in the input, nor, if they implement RelationWayProcessor, the
+// nodes of every way.
+func StreamProcessTwoPass(reader io.ReadSeeker, processor Processor, filter *Filter) error {
+	open := func() (io.ReadCloser, error) {
+		if _, err := reader.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind input: %w", err)
+		}
+		return io.NopCloser(reader), nil
+	}
+	return processTwoPass(open, processor, filter)
+}
+
+// processTwoPass runs both passes, opening the input for each
+func processTwoPass(open func() (io.ReadCloser, error), processor Processor, filter *Filter) error {
+	reader, err := open()
+	if err != nil {
+		return err
+	}
+	refs, ways, err := collectRefs(reader, filter)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if p, ok := processor.(RelationWayProcessor); ok {
+		p.SetRelationWays(ways.Contains)
+	}
+
+	reader, err = open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return stream(reader, processor, filter, refs)
+}
+
+// collectRefs returns the nodes of the ways and the node members of the
+// relations that pass the filter, and the way members of those relations
+func collectRefs(reader io.Reader, filter *Filter) (nodes, ways *idSet, err error) {
+	scanner := newScanner(reader, filter)
 	defer scanner.Close()
+	scanner.SkipNodes = true
+
+	refs, ways := &idSet{}, &idSet{}
+	for scanner.Scan() {
+		switch v := scanner.Object().(type) {
+		case *osm.Way:
+			for _, node := range v.Nodes {
+				refs.Add(ID(node.ID))
+			}
+		case *osm.Relation:
+			for _, member := range v.Members {
+				switch member.Type {
+				case osm.TypeNode:
+					refs.Add(ID(member.Ref))
+				case osm.TypeWay:
+					ways.Add(ID(member.Ref))
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	refs.sort()
+	ways.sort()
+	return refs, ways, nil
+}
+
+// stream delivers the elements of the input that pass the filter. Unless
+// refs is nil, nodes are only delivered if they are in refs or the filter
+// selects them.
+func stream(reader io.Reader, processor Processor, filter *Filter, refs *idSet) error {
+	scanner := newScanner(reader, filter)
+	defer scanner.Close()
+	if refs != nil {
+		scanner.FilterNode = func(node *osm.Node) bool {
+			return refs.Contains(ID(node.ID)) || (filter.filters(nodeKind) && filter.match(nodeKind, node.Tags))
+		}
+	}
 
 	for scanner.Scan() {
 		obj := scanner.Object()
@@ -35,23 +132,32 @@ func StreamProcess(reader io.Reader, processor Processor, onlyRoutable bool) err
 				return err
 			}
 		case *osm.Way:
-			if !onlyRoutable || isRoutable(v) {
-				way := &Way{
-					ID:    ID(v.ID),
-					Nodes: make([]ID, len(v.Nodes)),
-					Tags:  CreateTags(v.Tags),
-				}
-				for i, node := range v.Nodes {
-					way.Nodes[i] = ID(node.ID)
-				}
-				if err := processor.ProcessWay(way); err != nil {
-					return err
-				}
+			way := &Way{
+				ID:    ID(v.ID),
+				Nodes: make([]ID, len(v.Nodes)),
+				Tags:  CreateTags(v.Tags),
+			}
+			for i, node := range v.Nodes {
+				way.Nodes[i] = ID(node.ID)
+			}
+			if err := processor.ProcessWay(way); err != nil {
+				return err
 			}
 		case *osm.Relation:
-			if shouldProcessRelation(v) {
-				//TODO: convert relation to our type
-				//TODO: process relation
+			relation := &Relation{
+				ID:      ID(v.ID),
+				Tags:    CreateTags(v.Tags),
+				Members: make([]Member, len(v.Members)),
+			}
+			for i, member := range v.Members {
+				relation.Members[i] = Member{
+					Type: string(member.Type),
+					Ref:  ID(member.Ref),
+					Role: member.Role,
+				}
+			}
+			if err := processor.ProcessRelation(relation); err != nil {
+				return err
 			}
 		}
 	}
@@ -59,11 +165,45 @@ func StreamProcess(reader io.Reader, processor Processor, onlyRoutable bool) err
 	return scanner.Err()
 }
 
-func isRoutable(way *osm.Way) bool {
-	return way.Tags.HasTag("highway") || way.Tags.HasTag("junction")
+// newScanner returns a scanner of the input, skipping elements that do not
+// pass the filter while decoding
+func newScanner(reader io.Reader, filter *Filter) *osmpbf.Scanner {
+	scanner := osmpbf.New(context.Background(), reader, 3)
+	if filter.filters(nodeKind) {
+		scanner.FilterNode = func(node *osm.Node) bool { return filter.match(nodeKind, node.Tags) }
+	}
+	if filter.filters(wayKind) {
+		scanner.FilterWay = func(way *osm.Way) bool { return filter.match(wayKind, way.Tags) }
+	}
+	if filter.filters(relationKind) {
+		scanner.FilterRelation = func(relation *osm.Relation) bool { return filter.match(relationKind, relation.Tags) }
+	}
+	return scanner
+}
+
+// idSet is a set of IDs kept as a sorted array. IDs are added in any order
+// and sorted once before lookups, after which it is safe for concurrent reads.
+type idSet struct {
+	ids []ID
+}
+
+func (s *idSet) Add(id ID) {
+	s.ids = append(s.ids, id)
+}
+
+// sort orders the IDs and drops duplicates
+func (s *idSet) sort() {
+	sort.Slice(s.ids, func(i, j int) bool { return s.ids[i] < s.ids[j] })
+	kept := s.ids[:0]
+	for _, id := range s.ids {
+		if n := len(kept); n == 0 || kept[n-1] != id {
+			kept = append(kept, id)
+		}
+	}
+	s.ids = kept
 }
 
-func shouldProcessRelation(relation *osm.Relation) bool {
-	//TODO: add logic to determine if relation should be processed
-	return false
+func (s *idSet) Contains(id ID) bool {
+	i := sort.Search(len(s.ids), func(i int) bool { return s.ids[i] >= id })
+	return i < len(s.ids) && s.ids[i] == id
 }
@@ -34,11 +34,40 @@ type Member struct {
 	Role string // Role of the member in the relation
 }
 
-// OSMData holds the parsed OSM data using custom types.
+// OSMData holds the parsed OSM data using custom types. Nodes holds the
+// locations of the nodes delivered: all of them in one pass, but in two-pass
+// mode only those referenced by the ways and relations that pass the filter
+// and those the filter selects. Tagged nodes are also kept whole.
 type OSMData struct {
-	Nodes     map[ID]Node
-	Ways      []Way
-	Relations []Relation
+	Nodes       NodeStore // Locations of the nodes delivered
+	TaggedNodes []Node    // Nodes with tags, such as addresses and POIs
+	Ways        []Way
+	Relations   []Relation
+}
+
+// ProcessNode implements Processor
+func (d *OSMData) ProcessNode(node *Node) error {
+	if len(node.Tags) > 0 {
+		d.TaggedNodes = append(d.TaggedNodes, *node)
+	}
+	return d.Nodes.Set(node.ID, node.Lat, node.Lon)
+}
+
+// ProcessWay implements Processor
+func (d *OSMData) ProcessWay(way *Way) error {
+	d.Ways = append(d.Ways, *way)
+	return nil
+}
+
+// ProcessRelation implements Processor
+func (d *OSMData) ProcessRelation(relation *Relation) error {
+	d.Relations = append(d.Relations, *relation)
+	return nil
+}
+
+// Close releases the node store
+func (d *OSMData) Close() error {
+	return d.Nodes.Close()
 }
 
 type Tag struct {
@@ -71,7 +100,7 @@ func CreateTag(key string, value string) Tag {
 }
 
 func CreateTags(osmTags osm.Tags) Tags {
-	tags := make(Tags, len(osmTags))
+	tags := make(Tags, 0, len(osmTags))
 	for _, tag := range osmTags {
 		tags = append(tags, CreateTag(tag.Key, tag.Value))
 	}
@@ -0,0 +1,21 @@
+// internal/osm/types_test.go
+package osm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestCreateTags(t *testing.T) {
+	tags := CreateTags(osm.Tags{{Key: "highway", Value: "residential"}, {Key: "name", Value: "Carrer Major"}})
+	want := Tags{{Key: "highway", Value: "residential"}, {Key: "name", Value: "Carrer Major"}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("CreateTags() = %v, want %v", tags, want)
+	}
+
+	if tags := CreateTags(nil); len(tags) != 0 {
+		t.Errorf("Expected no tags, got %v", tags)
+	}
+}
@@ -0,0 +1,369 @@
+// internal/server/server.go
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+)
+
+// Server exposes a geocoding index over HTTP
+type Server struct {
+	index *geo.GeoIndex
+	mux   *http.ServeMux
+}
+
+// Result is the JSON form of a geocoding result
+type Result struct {
+	Name         string       `json:"name,omitempty"`
+	Layer        geo.Layer    `json:"layer"`
+	Category     geo.Category `json:"category,omitempty"`
+	HouseNumber  string       `json:"housenumber,omitempty"`
+	Street       string       `json:"street,omitempty"`
+	City         string       `json:"city,omitempty"`
+	PostCode     string       `json:"postcode,omitempty"`
+	Country      string       `json:"country,omitempty"`
+	Lat          float64      `json:"lat"`
+	Lon          float64      `json:"lon"`
+	Score        float64      `json:"score,omitempty"`
+	Confidence   float64      `json:"confidence,omitempty"`
+	Distance     float64      `json:"distance,omitempty"`
+	Extent       *geo.Bounds  `json:"extent,omitempty"`
+	OpeningHours string       `json:"opening_hours,omitempty"`
+}
+
+type response struct {
+	Results []Result `json:"results"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(index *geo.GeoIndex) *Server {
+	s := &Server{index: index, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /autocomplete", s.handleAutocomplete)
+	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /reverse", s.handleReverse)
+	s.mux.HandleFunc("GET /pois", s.handlePOIs)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleAutocomplete serves /autocomplete?q=<prefix>[&limit=n][&lat=..&lon=..][&viewport=..][&lang=..]
+func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("q") == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing q parameter"))
+		return
+	}
+	limit, err := intParam(query.Get("limit"), 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	focus, err := coordParam(query.Get("lat"), query.Get("lon"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	viewport, err := boundsParam(query.Get("viewport"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results := s.index.Autocomplete(query.Get("q"), geo.AutocompleteOptions{
+		Limit:    limit,
+		Focus:    focus,
+		Viewport: viewport,
+		Language: language(r),
+	})
+	writeResults(w, results)
+}
+
+// structuredParams are the query parameters of a structured search
+var structuredParams = []string{"name", "housenumber", "street", "postcode", "city", "state", "country"}
+
+// handleSearch serves /search?q=<query>[&limit=n][&lat=..&lon=..][&viewport=..][&lang=..],
+// or a structured search given by the parameters in structuredParams instead
+// of q, optionally restricted by [&bbox=..][&countrycodes=ad,fr][&layers=address,street]
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("q") == "" {
+		for _, param := range structuredParams {
+			if query.Get(param) != "" {
+				s.handleStructuredSearch(w, r)
+				return
+			}
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing q parameter"))
+		return
+	}
+	limit, err := intParam(query.Get("limit"), 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	focus, err := coordParam(query.Get("lat"), query.Get("lon"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	viewport, err := boundsParam(query.Get("viewport"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results := s.index.SearchWithOptions(query.Get("q"), geo.SearchOptions{
+		Limit:    limit,
+		Focus:    focus,
+		Viewport: viewport,
+	})
+	writeResults(w, s.localize(results, language(r)))
+}
+
+func (s *Server) handleStructuredSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := intParam(query.Get("limit"), 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	focus, err := coordParam(query.Get("lat"), query.Get("lon"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	bounds, err := boundsParam(query.Get("bbox"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var layers []geo.Layer
+	for _, layer := range listParam(query.Get("layers")) {
+		layers = append(layers, geo.Layer(layer))
+	}
+
+	results := s.index.GeocodeStructured(geo.StructuredQuery{
+		Name:         query.Get("name"),
+		HouseNumber:  query.Get("housenumber"),
+		Street:       query.Get("street"),
+		PostCode:     query.Get("postcode"),
+		City:         query.Get("city"),
+		State:        query.Get("state"),
+		Country:      query.Get("country"),
+		Bounds:       bounds,
+		CountryCodes: listParam(query.Get("countrycodes")),
+		Layers:       layers,
+		Limit:        limit,
+		Focus:        focus,
+	})
+	writeResults(w, s.localize(results, language(r)))
+}
+
+// handleReverse serves /reverse?lat=..&lon=..[&lang=..]
+func (s *Server) handleReverse(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	location, err := coordParam(query.Get("lat"), query.Get("lon"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if location == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing lat and lon parameters"))
+		return
+	}
+
+	result, err := s.index.ReverseGeocode(location.Lat, location.Lon)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if result == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no address near %f, %f", location.Lat, location.Lon))
+		return
+	}
+	writeResults(w, s.localize([]geo.GeocodeResult{*result}, language(r)))
+}
+
+// handlePOIs serves /pois[?category=..][&q=<name>][&lat=..&lon=..][&radius=<km>][&open=now|<RFC 3339 time>][&limit=n][&lang=..]
+func (s *Server) handlePOIs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := intParam(query.Get("limit"), 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	near, err := coordParam(query.Get("lat"), query.Get("lon"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var radius float64
+	if value := query.Get("radius"); value != "" {
+		if radius, err = strconv.ParseFloat(value, 64); err != nil || radius <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid radius %q", value))
+			return
+		}
+	}
+	var openAt *time.Time
+	switch value := query.Get("open"); value {
+	case "":
+	case "now":
+		now := time.Now()
+		openAt = &now
+	default:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid open %q", value))
+			return
+		}
+		openAt = &t
+	}
+	// Categories may be given by path, such as "health.pharmacy", or by name
+	category := geo.Category(query.Get("category"))
+	if named, ok := geo.CategoryFor(string(category)); ok {
+		category = named
+	}
+
+	results := s.index.FindPOIs(geo.POIQuery{
+		Category: category,
+		Name:     query.Get("q"),
+		Near:     near,
+		RadiusKm: radius,
+		OpenAt:   openAt,
+		Limit:    limit,
+	})
+	writeResults(w, s.localize(results, language(r)))
+}
+
+// localize translates result names into lang where known
+func (s *Server) localize(results []geo.GeocodeResult, lang string) []geo.GeocodeResult {
+	for i := range results {
+		results[i] = s.index.Localize(results[i], lang)
+	}
+	return results
+}
+
+// language returns the language requested by the lang parameter, or else the
+// first language of the Accept-Language header
+func language(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return lang
+	}
+	first, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	first, _, _ = strings.Cut(first, ";")
+	if first = strings.TrimSpace(first); first == "*" {
+		return ""
+	}
+	return first
+}
+
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid limit %q", value)
+	}
+	return n, nil
+}
+
+// coordParam parses an optional coordinate; both or neither must be given
+func coordParam(lat, lon string) (*geo.Coord, error) {
+	if lat == "" && lon == "" {
+		return nil, nil
+	}
+	latValue, err := strconv.ParseFloat(lat, 64)
+	if err != nil || latValue < -90 || latValue > 90 {
+		return nil, fmt.Errorf("invalid lat %q", lat)
+	}
+	lonValue, err := strconv.ParseFloat(lon, 64)
+	if err != nil || lonValue < -180 || lonValue > 180 {
+		return nil, fmt.Errorf("invalid lon %q", lon)
+	}
+	return &geo.Coord{Lat: latValue, Lon: lonValue}, nil
+}
+
+// boundsParam parses an optional box given as minLon,minLat,maxLon,maxLat
+func boundsParam(value string) (*geo.Bounds, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bounds %q", value)
+	}
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bounds %q", value)
+		}
+		v[i] = f
+	}
+	b := geo.Bounds{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon || b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return nil, fmt.Errorf("invalid bounds %q", value)
+	}
+	return &b, nil
+}
+
+// listParam splits an optional comma-separated list
+func listParam(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func writeResults(w http.ResponseWriter, results []geo.GeocodeResult) {
+	resp := response{Results: make([]Result, 0, len(results))}
+	for _, r := range results {
+		result := Result{
+			Name:        r.Name,
+			Layer:       r.Layer,
+			HouseNumber: r.HouseNumber,
+			Street:      r.Street,
+			City:        r.City,
+			PostCode:    r.PostCode,
+			Country:     r.Country,
+			Lat:         r.Lat,
+			Lon:         r.Lon,
+			Score:       r.Score,
+			Confidence:  r.Confidence,
+			Distance:    r.Distance,
+			Extent:      r.Extent,
+		}
+		if r.POI != nil {
+			result.Category = r.POI.Category
+			result.OpeningHours = r.POI.OpeningHours
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,172 @@
+// internal/server/server_test.go
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+	"github.com/sebastiaanwouters/geodude/internal/osm"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	b := geo.NewGeoBuilder()
+	andorra, err := time.LoadLocation("Europe/Andorra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetTimeZone(andorra)
+	nodes := []osm.Node{
+		{ID: 1, Lat: 42.507, Lon: 1.521, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "12"},
+			{Key: "addr:street", Value: "Carrer Major"},
+			{Key: "addr:city", Value: "Andorra la Vella"},
+		}},
+		{ID: 2, Lat: 42.556, Lon: 1.533, Tags: osm.Tags{
+			{Key: "addr:housenumber", Value: "14"},
+			{Key: "addr:street", Value: "Carrer Major"},
+			{Key: "addr:city", Value: "Ordino"},
+		}},
+		{ID: 3, Lat: 42.5071, Lon: 1.5211, Tags: osm.Tags{
+			{Key: "amenity", Value: "pharmacy"},
+			{Key: "name", Value: "Farmàcia Central"},
+			{Key: "opening_hours", Value: "Mo-Fr 09:00-20:00"},
+		}},
+	}
+	for i := range nodes {
+		if err := b.ProcessNode(&nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(b.GetIndex())
+}
+
+func get(t *testing.T, s *Server, url string) (int, response) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	var resp response
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Invalid response body: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestAutocomplete(t *testing.T) {
+	s := newTestServer(t)
+
+	code, resp := get(t, s, "/autocomplete?q=carrer+major+1&lat=42.556&lon=1.533")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", resp.Results)
+	}
+	if resp.Results[0].City != "Ordino" || resp.Results[0].Layer != geo.LayerAddress {
+		t.Errorf("Expected the address near the focus point first, got %+v", resp.Results[0])
+	}
+
+	if _, resp := get(t, s, "/autocomplete?q=carrer&limit=1"); len(resp.Results) != 1 {
+		t.Errorf("Expected the limit to apply, got %d results", len(resp.Results))
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, url := range []string{
+		"/autocomplete",
+		"/autocomplete?q=carrer&limit=0",
+		"/autocomplete?q=carrer&lat=42.5",
+		"/autocomplete?q=carrer&lat=95&lon=1",
+		"/search",
+		"/search?q=carrer&viewport=1,42,1.5",
+		"/search?street=carrer&bbox=1,42",
+		"/search?q=carrer&viewport=1.5,42,1,43",
+		"/reverse?lat=42.5",
+		"/pois?lat=42.5&lon=1.5&radius=-1",
+		"/pois?open=tomorrow",
+	} {
+		if code, _ := get(t, s, url); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, code)
+		}
+	}
+}
+
+func TestSearchAndReverse(t *testing.T) {
+	s := newTestServer(t)
+
+	if _, resp := get(t, s, "/search?q=Carrer+Major+12"); len(resp.Results) == 0 || resp.Results[0].HouseNumber != "12" {
+		t.Errorf("Unexpected search results %+v", resp.Results)
+	} else if c := resp.Results[0].Confidence; c <= 0 || c > 1 {
+		t.Errorf("Expected a confidence between 0 and 1, got %f", c)
+	}
+	if _, resp := get(t, s, "/reverse?lat=42.5561&lon=1.5331"); len(resp.Results) != 1 || resp.Results[0].HouseNumber != "14" {
+		t.Errorf("Unexpected reverse results %+v", resp.Results)
+	}
+}
+
+func TestStructuredSearch(t *testing.T) {
+	s := newTestServer(t)
+
+	_, resp := get(t, s, "/search?street=Carrer+Major&housenumber=14&city=Ordino")
+	if len(resp.Results) != 1 || resp.Results[0].HouseNumber != "14" {
+		t.Errorf("Expected only the address in Ordino, got %+v", resp.Results)
+	}
+	_, resp = get(t, s, "/search?street=Carrer+Major&layers=address,poi")
+	if len(resp.Results) != 0 {
+		t.Errorf("Expected no addresses or POIs without a house number, got %+v", resp.Results)
+	}
+	_, resp = get(t, s, "/search?street=Carrer+Major&housenumber=12&bbox=1.5,42.5,1.53,42.52")
+	if len(resp.Results) != 1 || resp.Results[0].City != "Andorra la Vella" {
+		t.Errorf("Expected only the address in the box, got %+v", resp.Results)
+	}
+}
+
+func TestReverseFar(t *testing.T) {
+	s := newTestServer(t)
+	code, resp := get(t, s, "/reverse?lat=-45&lon=170")
+	if code != http.StatusOK || len(resp.Results) != 1 || resp.Results[0].Distance < 1000 {
+		t.Errorf("Expected the closest address far away, got %d %+v", code, resp.Results)
+	}
+
+	empty := New(geo.NewGeoBuilder().GetIndex())
+	if code, _ := get(t, empty, "/reverse?lat=-45&lon=170"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 without any address, got %d", code)
+	}
+}
+
+func TestPOIs(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, url := range []string{
+		"/pois?category=pharmacy&lat=42.507&lon=1.521&radius=2",
+		"/pois?category=health.pharmacy",
+		"/pois?q=farmacia+central",
+	} {
+		code, resp := get(t, s, url)
+		if code != http.StatusOK || len(resp.Results) != 1 {
+			t.Fatalf("%s: expected 1 result, got %d %+v", url, code, resp.Results)
+		}
+		if r := resp.Results[0]; r.Category != "health.pharmacy" || r.OpeningHours != "Mo-Fr 09:00-20:00" {
+			t.Errorf("%s: unexpected result %+v", url, r)
+		}
+	}
+	if _, resp := get(t, s, "/pois?category=pharmacy&lat=42.556&lon=1.533&radius=1"); len(resp.Results) != 0 {
+		t.Errorf("Expected no pharmacy within 1 km of Ordino, got %+v", resp.Results)
+	}
+	// Monday 2024-06-03
+	if _, resp := get(t, s, "/pois?category=pharmacy&open=2024-06-03T10:00:00%2B02:00"); len(resp.Results) != 1 {
+		t.Errorf("Expected the pharmacy to be open, got %+v", resp.Results)
+	}
+	if _, resp := get(t, s, "/pois?category=pharmacy&open=2024-06-02T10:00:00%2B02:00"); len(resp.Results) != 0 {
+		t.Errorf("Expected the pharmacy to be closed on Sunday, got %+v", resp.Results)
+	}
+}
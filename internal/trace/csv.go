@@ -0,0 +1,137 @@
+// internal/trace/csv.go
+package trace
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+)
+
+// csvColumns maps recognised header names to a column role
+var csvColumns = map[string]string{
+	"lat":       "lat",
+	"latitude":  "lat",
+	"y":         "lat",
+	"lon":       "lon",
+	"lng":       "lon",
+	"long":      "lon",
+	"longitude": "lon",
+	"x":         "lon",
+	"time":      "time",
+	"timestamp": "time",
+	"datetime":  "time",
+	"ele":       "ele",
+	"elevation": "ele",
+	"alt":       "ele",
+	"altitude":  "ele",
+}
+
+// ReadCSV parses a trace from CSV. Without a header row the columns are
+// expected as lat,lon[,timestamp[,elevation]]; with a header the columns may
+// appear in any order. Comma, semicolon and tab delimiters are accepted.
+func ReadCSV(r io.Reader) (Trace, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Trace{}, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.Comma = detectDelimiter(string(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return Trace{}, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	cols := map[string]int{"lat": 0, "lon": 1, "time": 2, "ele": 3}
+	if len(records) > 0 && isHeader(records[0]) {
+		cols = map[string]int{}
+		for i, name := range records[0] {
+			if role, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+				if _, seen := cols[role]; !seen {
+					cols[role] = i
+				}
+			}
+		}
+		if _, ok := cols["lat"]; !ok {
+			return Trace{}, fmt.Errorf("CSV header has no latitude column")
+		}
+		if _, ok := cols["lon"]; !ok {
+			return Trace{}, fmt.Errorf("CSV header has no longitude column")
+		}
+		records = records[1:]
+	}
+
+	var trace Trace
+	for i, record := range records {
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		p, err := parseCSVRecord(record, cols)
+		if err != nil {
+			return Trace{}, fmt.Errorf("CSV record %d: %w", i+1, err)
+		}
+		trace.Points = append(trace.Points, p)
+	}
+	return trace, nil
+}
+
+func parseCSVRecord(record []string, cols map[string]int) (Point, error) {
+	field := func(role string) string {
+		i, ok := cols[role]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	lat, err := strconv.ParseFloat(field("lat"), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid latitude %q", field("lat"))
+	}
+	lon, err := strconv.ParseFloat(field("lon"), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid longitude %q", field("lon"))
+	}
+
+	p := Point{Coord: geo.Coord{Lat: lat, Lon: lon}}
+	// As in GPX, a point with an unreadable time is kept without one
+	if t, ok := parseTime(field("time")); ok {
+		p.Time = t
+	}
+	if ele, err := strconv.ParseFloat(field("ele"), 64); err == nil {
+		p.Elevation = &ele
+	}
+	return p, nil
+}
+
+// isHeader reports whether the first record is a header rather than data
+func isHeader(record []string) bool {
+	if len(record) == 0 {
+		return false
+	}
+	_, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+	return err != nil
+}
+
+// detectDelimiter picks the delimiter that occurs most often on the first line
+func detectDelimiter(data string) rune {
+	line := data
+	if i := strings.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, bestCount := ',', strings.Count(line, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(line, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,194 @@
+// internal/trace/geojson.go
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+)
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Features    []geoJSONObject `json:"features"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Properties  map[string]any  `json:"properties"`
+}
+
+// ReadGeoJSON parses traces from a GeoJSON document. LineStrings and each
+// part of a MultiLineString become a trace; Point and MultiPoint features are
+// collected into a single trace named "waypoints". Timestamps are taken from
+// the "coordTimes" or "times" property for lines and from "time" or
+// "timestamp" for points.
+func ReadGeoJSON(r io.Reader) ([]Trace, error) {
+	var root geoJSONObject
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+
+	var traces []Trace
+	waypoints := Trace{Name: "waypoints"}
+	if err := collectGeoJSON(&root, nil, &traces, &waypoints); err != nil {
+		return nil, err
+	}
+	if len(waypoints.Points) > 0 {
+		traces = append(traces, waypoints)
+	}
+	return traces, nil
+}
+
+func collectGeoJSON(obj *geoJSONObject, props map[string]any, traces *[]Trace, waypoints *Trace) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for i := range obj.Features {
+			if err := collectGeoJSON(&obj.Features[i], nil, traces, waypoints); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil
+		}
+		return collectGeoJSON(obj.Geometry, obj.Properties, traces, waypoints)
+	case "GeometryCollection":
+		for i := range obj.Geometries {
+			if err := collectGeoJSON(&obj.Geometries[i], props, traces, waypoints); err != nil {
+				return err
+			}
+		}
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(obj.Coordinates, &pos); err != nil {
+			return fmt.Errorf("invalid Point coordinates: %w", err)
+		}
+		p, err := positionToPoint(pos)
+		if err != nil {
+			return err
+		}
+		p.Time = propertyTime(props, "time", "timestamp")
+		waypoints.Points = append(waypoints.Points, p)
+	case "MultiPoint":
+		var positions [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &positions); err != nil {
+			return fmt.Errorf("invalid MultiPoint coordinates: %w", err)
+		}
+		points, err := positionsToPoints(positions, propertyTimes(props))
+		if err != nil {
+			return err
+		}
+		waypoints.Points = append(waypoints.Points, points...)
+	case "LineString":
+		var positions [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &positions); err != nil {
+			return fmt.Errorf("invalid LineString coordinates: %w", err)
+		}
+		points, err := positionsToPoints(positions, propertyTimes(props))
+		if err != nil {
+			return err
+		}
+		*traces = append(*traces, Trace{Name: propertyString(props, "name"), Points: points})
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &lines); err != nil {
+			return fmt.Errorf("invalid MultiLineString coordinates: %w", err)
+		}
+		// coordTimes for a MultiLineString is nested per line
+		var times [][]any
+		if props != nil {
+			for _, key := range []string{"coordTimes", "times"} {
+				if v, ok := props[key].([]any); ok {
+					for _, line := range v {
+						lt, _ := line.([]any)
+						times = append(times, lt)
+					}
+					break
+				}
+			}
+		}
+		for i, line := range lines {
+			var lineTimes []any
+			if i < len(times) {
+				lineTimes = times[i]
+			}
+			points, err := positionsToPoints(line, lineTimes)
+			if err != nil {
+				return err
+			}
+			*traces = append(*traces, Trace{Name: propertyString(props, "name"), Points: points})
+		}
+	default:
+		// Polygons and unknown types carry no trace information
+	}
+	return nil
+}
+
+func positionToPoint(pos []float64) (Point, error) {
+	if len(pos) < 2 {
+		return Point{}, fmt.Errorf("GeoJSON position needs at least 2 values, got %d", len(pos))
+	}
+	// GeoJSON positions are [lon, lat, elevation]
+	p := Point{Coord: geo.Coord{Lat: pos[1], Lon: pos[0]}}
+	if len(pos) > 2 {
+		ele := pos[2]
+		p.Elevation = &ele
+	}
+	return p, nil
+}
+
+func positionsToPoints(positions [][]float64, times []any) ([]Point, error) {
+	points := make([]Point, 0, len(positions))
+	for i, pos := range positions {
+		p, err := positionToPoint(pos)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(times) {
+			p.Time = anyToTime(times[i])
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func propertyTimes(props map[string]any) []any {
+	for _, key := range []string{"coordTimes", "times"} {
+		if v, ok := props[key].([]any); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func propertyTime(props map[string]any, keys ...string) time.Time {
+	for _, key := range keys {
+		if v, ok := props[key]; ok {
+			return anyToTime(v)
+		}
+	}
+	return time.Time{}
+}
+
+func propertyString(props map[string]any, key string) string {
+	s, _ := props[key].(string)
+	return s
+}
+
+// anyToTime converts a JSON string or number to a time, returning the zero
+// time if it cannot be interpreted
+func anyToTime(v any) time.Time {
+	switch t := v.(type) {
+	case string:
+		if parsed, ok := parseTime(t); ok {
+			return parsed
+		}
+	case float64:
+		if parsed, ok := unixTime(t); ok {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
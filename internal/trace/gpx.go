@@ -0,0 +1,141 @@
+// internal/trace/gpx.go
+package trace
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+)
+
+type gpxFile struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Xmlns     string     `xml:"xmlns,attr,omitempty"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name,omitempty"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxPoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Ele  string `xml:"ele,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+// ReadGPX parses tracks, routes and waypoints from a GPX document. Every
+// track segment and route becomes its own trace; waypoints are collected
+// into a single trace named "waypoints".
+func ReadGPX(r io.Reader) ([]Trace, error) {
+	var doc gpxFile
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode GPX: %w", err)
+	}
+
+	var traces []Trace
+	for _, trk := range doc.Tracks {
+		for _, seg := range trk.Segments {
+			points, err := convertGPXPoints(seg.Points)
+			if err != nil {
+				return nil, err
+			}
+			traces = append(traces, Trace{Name: trk.Name, Points: points})
+		}
+	}
+	for _, rte := range doc.Routes {
+		points, err := convertGPXPoints(rte.Points)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, Trace{Name: rte.Name, Points: points})
+	}
+	if len(doc.Waypoints) > 0 {
+		points, err := convertGPXPoints(doc.Waypoints)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, Trace{Name: "waypoints", Points: points})
+	}
+	return traces, nil
+}
+
+func convertGPXPoints(in []gpxPoint) ([]Point, error) {
+	points := make([]Point, 0, len(in))
+	for _, gp := range in {
+		lat, err := strconv.ParseFloat(strings.TrimSpace(gp.Lat), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPX latitude %q: %w", gp.Lat, err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(gp.Lon), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPX longitude %q: %w", gp.Lon, err)
+		}
+		p := Point{Coord: geo.Coord{Lat: lat, Lon: lon}}
+		// Malformed optional fields are dropped rather than failing the trace
+		if ele, err := strconv.ParseFloat(strings.TrimSpace(gp.Ele), 64); err == nil {
+			p.Elevation = &ele
+		}
+		if t, ok := parseTime(strings.TrimSpace(gp.Time)); ok {
+			p.Time = t
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// WriteGPX writes the traces as GPX 1.1 tracks with one segment each
+func WriteGPX(w io.Writer, traces ...Trace) error {
+	doc := gpxFile{
+		Version: "1.1",
+		Creator: "geodude",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+	}
+	for _, t := range traces {
+		seg := gpxSegment{Points: make([]gpxPoint, len(t.Points))}
+		for i, p := range t.Points {
+			gp := gpxPoint{
+				Lat: strconv.FormatFloat(p.Lat, 'f', -1, 64),
+				Lon: strconv.FormatFloat(p.Lon, 'f', -1, 64),
+			}
+			if p.Elevation != nil {
+				gp.Ele = strconv.FormatFloat(*p.Elevation, 'f', -1, 64)
+			}
+			if p.HasTime() {
+				gp.Time = p.Time.UTC().Format(time.RFC3339Nano)
+			}
+			seg.Points[i] = gp
+		}
+		doc.Tracks = append(doc.Tracks, gpxTrack{Name: t.Name, Segments: []gpxSegment{seg}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GPX: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,34 @@
+// internal/trace/reader.go
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFile reads traces from a file, choosing the format by its extension
+// (.gpx, .csv, .geojson or .json)
+func ReadFile(filePath string) ([]Trace, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".gpx":
+		return ReadGPX(f)
+	case ".csv":
+		t, err := ReadCSV(f)
+		if err != nil {
+			return nil, err
+		}
+		return []Trace{t}, nil
+	case ".geojson", ".json":
+		return ReadGeoJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported trace format: %s", filePath)
+	}
+}
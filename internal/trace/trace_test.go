@@ -0,0 +1,196 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="42.5" lon="1.5"><name>Start</name></wpt>
+  <trk>
+    <name>Morning ride</name>
+    <trkseg>
+      <trkpt lat="42.5063" lon="1.5218"><ele>1023.5</ele><time>2024-05-01T08:00:00Z</time></trkpt>
+      <trkpt lat="42.5070" lon="1.5225"><time>2024-05-01T08:00:05Z</time></trkpt>
+      <trkpt lat="42.5081" lon="1.5230"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestReadGPX(t *testing.T) {
+	traces, err := ReadGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("Expected 2 traces, got %d", len(traces))
+	}
+
+	track := traces[0]
+	if track.Name != "Morning ride" || len(track.Points) != 3 {
+		t.Fatalf("Unexpected track %q with %d points", track.Name, len(track.Points))
+	}
+	if track.Points[0].Elevation == nil || *track.Points[0].Elevation != 1023.5 {
+		t.Errorf("Expected elevation 1023.5, got %v", track.Points[0].Elevation)
+	}
+	if track.Points[1].Elevation != nil {
+		t.Errorf("Expected missing elevation, got %v", *track.Points[1].Elevation)
+	}
+	want := time.Date(2024, 5, 1, 8, 0, 5, 0, time.UTC)
+	if !track.Points[1].Time.Equal(want) {
+		t.Errorf("Expected time %v, got %v", want, track.Points[1].Time)
+	}
+	if track.Points[2].HasTime() {
+		t.Errorf("Expected missing time, got %v", track.Points[2].Time)
+	}
+
+	if traces[1].Name != "waypoints" || len(traces[1].Points) != 1 {
+		t.Errorf("Expected a single waypoint, got %+v", traces[1])
+	}
+}
+
+func TestWriteGPXRoundTrip(t *testing.T) {
+	ele := 850.0
+	in := Trace{
+		Name: "Route",
+		Points: []Point{
+			{Time: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), Elevation: &ele},
+			{},
+		},
+	}
+	in.Points[0].Lat, in.Points[0].Lon = 42.5063, 1.5218
+	in.Points[1].Lat, in.Points[1].Lon = 42.51, 1.53
+
+	var buf bytes.Buffer
+	if err := WriteGPX(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadGPX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || len(out[0].Points) != 2 {
+		t.Fatalf("Unexpected round trip result %+v", out)
+	}
+	got := out[0].Points
+	if got[0].Coord != in.Points[0].Coord || got[1].Coord != in.Points[1].Coord {
+		t.Errorf("Coordinates changed: %+v", got)
+	}
+	if !got[0].Time.Equal(in.Points[0].Time) || got[0].Elevation == nil || *got[0].Elevation != ele {
+		t.Errorf("Time or elevation changed: %+v", got[0])
+	}
+	if got[1].HasTime() || got[1].Elevation != nil {
+		t.Errorf("Expected no time or elevation, got %+v", got[1])
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     int
+		wantTime bool
+		wantErr  bool
+	}{
+		{
+			name:     "No header",
+			input:    "42.5,1.5,2024-05-01T08:00:00Z\n42.6,1.6,1714550405\n",
+			want:     2,
+			wantTime: true,
+		},
+		{
+			name:     "Header in any order",
+			input:    "timestamp;lon;lat\n2024-05-01 08:00:00;1.5;42.5\n",
+			want:     1,
+			wantTime: true,
+		},
+		{
+			name:  "Missing timestamps",
+			input: "lat,lon\n42.5,1.5\n42.6,1.6\n",
+			want:  2,
+		},
+		{
+			name:  "Invalid timestamp",
+			input: "lat,lon,time\n42.5,1.5,yesterday\n42.6,1.6,2024-05-01T08:00:05Z\n",
+			want:  2,
+		},
+		{
+			name:    "No latitude column",
+			input:   "foo,bar\n1,2\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid coordinate",
+			input:   "42.5,abc\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := ReadCSV(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(trace.Points) != tt.want {
+				t.Fatalf("Expected %d points, got %d", tt.want, len(trace.Points))
+			}
+			if trace.Points[0].Lat != 42.5 || trace.Points[0].Lon != 1.5 {
+				t.Errorf("Unexpected first point %+v", trace.Points[0])
+			}
+			if trace.Points[0].HasTime() != tt.wantTime {
+				t.Errorf("HasTime() = %v, want %v", trace.Points[0].HasTime(), tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestReadGeoJSON(t *testing.T) {
+	input := `{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"properties": {"name": "Track", "coordTimes": ["2024-05-01T08:00:00Z", "2024-05-01T08:01:00Z"]},
+				"geometry": {"type": "LineString", "coordinates": [[1.5, 42.5, 1000], [1.6, 42.6]]}
+			},
+			{
+				"type": "Feature",
+				"properties": {"time": "2024-05-01T09:00:00Z"},
+				"geometry": {"type": "Point", "coordinates": [1.7, 42.7]}
+			}
+		]
+	}`
+
+	traces, err := ReadGeoJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("Expected 2 traces, got %d", len(traces))
+	}
+
+	line := traces[0]
+	if line.Name != "Track" || len(line.Points) != 2 {
+		t.Fatalf("Unexpected line %+v", line)
+	}
+	if line.Points[0].Lat != 42.5 || line.Points[0].Lon != 1.5 {
+		t.Errorf("Expected lon/lat order to be swapped, got %+v", line.Points[0].Coord)
+	}
+	if line.Points[0].Elevation == nil || line.Points[1].Elevation != nil {
+		t.Errorf("Unexpected elevations %v, %v", line.Points[0].Elevation, line.Points[1].Elevation)
+	}
+	if !line.Points[1].HasTime() {
+		t.Error("Expected second point to have a time")
+	}
+
+	if traces[1].Name != "waypoints" || !traces[1].Points[0].HasTime() {
+		t.Errorf("Unexpected waypoints %+v", traces[1])
+	}
+}
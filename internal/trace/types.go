@@ -0,0 +1,84 @@
+// internal/trace/types.go
+package trace
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sebastiaanwouters/geodude/internal/geo"
+)
+
+// Point is a single trace or waypoint coordinate. Time is the zero value and
+// Elevation is nil when the source did not provide them.
+type Point struct {
+	geo.Coord
+	Time      time.Time
+	Elevation *float64
+}
+
+// HasTime reports whether the point carries a timestamp
+func (p Point) HasTime() bool {
+	return !p.Time.IsZero()
+}
+
+// Trace is an ordered list of points, e.g. a GPX track or a GeoJSON LineString
+type Trace struct {
+	Name   string
+	Points []Point
+}
+
+// Coords returns the coordinates of the trace without time or elevation
+func (t Trace) Coords() []geo.Coord {
+	coords := make([]geo.Coord, len(t.Points))
+	for i, p := range t.Points {
+		coords[i] = p.Coord
+	}
+	return coords
+}
+
+// timeLayouts are tried in order when a timestamp is not RFC 3339
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02",
+}
+
+// parseTime parses a timestamp in one of the common layouts or as Unix
+// seconds/milliseconds. An empty string yields the zero time.
+func parseTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	if unix, ok := parseUnix(s); ok {
+		return unix, true
+	}
+	return time.Time{}, false
+}
+
+func parseUnix(s string) (time.Time, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return unixTime(v)
+}
+
+func unixTime(v float64) (time.Time, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, false
+	}
+	// Values this large can only be milliseconds since the epoch
+	if math.Abs(v) > 1e11 {
+		v /= 1000
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+}